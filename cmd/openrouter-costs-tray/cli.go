@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"openrouter-costs-tray/internal/autostart"
+	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/cli"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/instance"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/redact"
+)
+
+// runCommand executes a CLI subcommand instead of starting the tray.
+// Logs go to stderr so they never mix with command output.
+func runCommand(args []string) int {
+	redactor := redact.New()
+	logger := slog.New(redactor.Handler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	cfgPath, err := config.DefaultConfigPath()
+	if err != nil {
+		cfgPath = "config.json"
+	}
+	cfg, err := config.LoadFromPath(cfgPath)
+	if err != nil {
+		logger.Warn("config load failed", "error", err, "path", cfgPath)
+	}
+	redactor.SetSecrets(redact.ConfigSecrets(cfg)...)
+
+	cachePath, err := cache.DefaultCachePath()
+	if err != nil {
+		cachePath = "costs_cache.json"
+	}
+
+	var starter autostart.Manager
+	if exe, err := autostart.Executable(); err != nil {
+		logger.Warn("executable path unavailable", "error", err)
+	} else {
+		starter = autostart.New(exe)
+	}
+
+	client := openrouter.NewClient("", nil, logger.With("component", "client"))
+	// Commands that only read local files still work with broken settings.
+	_ = applyConnection(client, cfg.Connection, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cli.Run(ctx, cli.Env{
+		Config:    cfg,
+		Client:    client,
+		History:   history.NewStore(filepath.Dir(cachePath)),
+		Cache:     cache.NewStore(cachePath),
+		Socket:    instance.SocketPath(filepath.Dir(cfgPath)),
+		Autostart: starter,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		Logger:    logger,
+	}, args)
+}
@@ -3,28 +3,54 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/url"
+	"os"
 	"path/filepath"
 	"time"
 
 	"fyne.io/fyne/v2/app"
 
+	"openrouter-costs-tray/internal/autostart"
+	"openrouter-costs-tray/internal/budget"
 	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/cli"
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/connectivity"
+	"openrouter-costs-tray/internal/dashboard"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/export"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/instance"
 	"openrouter-costs-tray/internal/logging"
+	"openrouter-costs-tray/internal/mqtt"
 	"openrouter-costs-tray/internal/notify"
 	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/redact"
 	"openrouter-costs-tray/internal/refresh"
+	"openrouter-costs-tray/internal/report"
 	"openrouter-costs-tray/internal/scheduler"
+	"openrouter-costs-tray/internal/staleness"
 	"openrouter-costs-tray/internal/state"
 	"openrouter-costs-tray/internal/summary"
+	"openrouter-costs-tray/internal/telemetry"
+	"openrouter-costs-tray/internal/transport"
+	"openrouter-costs-tray/internal/ui/generation"
+	"openrouter-costs-tray/internal/ui/logs"
 	"openrouter-costs-tray/internal/ui/settings"
+	"openrouter-costs-tray/internal/ui/spend"
+	"openrouter-costs-tray/internal/ui/spendhistory"
 	"openrouter-costs-tray/internal/ui/tray"
 )
 
 const appID = "openrouter-costs-tray"
 
 func main() {
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	cfgPath, cfgErr := config.DefaultConfigPath()
 	if cfgErr != nil {
 		cfgPath = "config.json"
@@ -39,12 +65,14 @@ func main() {
 		cfg = config.DefaultConfig()
 	}
 
-	logger, levelVar, logOutput := logging.NewLogger(cfg.Logging.Level)
-	logger = logger.With("app", appID)
+	logger, logOutput := logging.NewLogger(cfg.Logging)
+	// Every record passes the redactor so API keys never reach stdout or the log file.
+	redactor := redact.New(redact.ConfigSecrets(cfg)...)
+	logger = slog.New(redactor.Handler(logger.Handler())).With("app", appID)
 
 	logPath := filepath.Join(filepath.Dir(cfgPath), config.LogFileName)
 	if cfg.Logging.ToFile {
-		if err := logOutput.EnableFile(logPath); err != nil {
+		if err := logOutput.EnableFile(logPath, logging.RotationFromConfig(cfg.Logging)); err != nil {
 			logger.Warn("log file unavailable", "error", err, "path", logPath)
 		} else {
 			logger.Info("logging to file enabled", "path", logPath)
@@ -60,6 +88,24 @@ func main() {
 		logger.Info("config loaded", "path", cfgPath)
 	}
 
+	// A second launch hands over to the running tray instead of starting another one.
+	socketPath := instance.SocketPath(filepath.Dir(cfgPath))
+	inst, instErr := instance.Listen(socketPath, logger.With("component", "instance"))
+	if errors.Is(instErr, instance.ErrRunning) {
+		logger.Info("already running, opening settings of the running instance", "socket", socketPath)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := instance.Send(ctx, socketPath, instance.CommandShowSettings)
+		cancel()
+		if err != nil {
+			logger.Error("running instance unreachable", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if instErr != nil {
+		logger.Warn("single-instance socket unavailable", "error", instErr, "path", socketPath)
+	}
+
 	if cacheErr != nil {
 		logger.Warn("cache dir unavailable", "error", cacheErr, "path", cachePath)
 	}
@@ -70,41 +116,101 @@ func main() {
 	stateStore := state.New()
 	if cached, err := cacheStore.Load(); err == nil && cached != nil {
 		logger.Info("cache loaded", "path", cachePath, "last_success_at", cached.LastSuccessAt)
-		stateStore.SetSuccess(openrouter.Usage{
-			Total:   cached.TotalUsage,
-			Daily:   cached.DailyUsage,
-			Weekly:  cached.WeeklyUsage,
-			Monthly: cached.MonthlyUsage,
-			KeyID:   cached.KeyID,
-		}, cached.LastSuccessAt)
+		snap := refresh.SnapshotFromCache(cached)
+		stateStore.SetSuccess(snap.Usage, snap.LastSuccessAt)
+		stateStore.SetActivity(snap.Activity)
+		stateStore.SetKeys(snap.Keys)
+		stateStore.SetPrices(snap.Prices)
 	} else if err != nil {
 		logger.Warn("failed to load cache", "error", err)
 	}
 
-	if cfg.Connection.Token == "" {
+	if !cfg.Connection.Configured() {
 		stateStore.SetNotConfigured()
 	}
 
 	fyneApp := app.NewWithID(appID)
 	fyneApp.SetIcon(tray.IconResource())
 
+	bus := events.NewBus(logger.With("component", "events"))
 	client := openrouter.NewClient("", nil, logger.With("component", "client"))
+	showConnectionError(stateStore, cfg, applyConnection(client, cfg.Connection, logger))
+	client.SetDriftHandler(func(drift openrouter.Drift) {
+		stateStore.SetDrift(drift.Endpoint, drift.Issues)
+		bus.Publish(events.SchemaDrift{Endpoint: drift.Endpoint, Issues: drift.Issues})
+	})
 	notifier := notify.New(fyneApp, cfg.Notifications, logger.With("component", "notifier"))
+	bus.Listen(events.DefaultBuffer, notifier.HandleEvent)
+	mqttPublisher := mqtt.NewPublisher(logger.With("component", "mqtt"))
+	bus.Listen(events.DefaultBuffer, mqttPublisher.HandleEvent)
+	exporter := export.NewExporter(cfg.Export, nil, logger.With("component", "export"))
+	bus.Listen(events.DefaultBuffer, exporter.HandleEvent)
+	telemetryExporter := telemetry.NewExporter(cfg.Telemetry, nil, redactor, logger.With("component", "telemetry"))
+	bus.Listen(events.DefaultBuffer, telemetryExporter.HandleEvent)
 
-	refresher := refresh.New(client, cacheStore, cfgStore, notifier, stateStore, logger.With("component", "refresher"))
+	historyStore := history.NewStore(filepath.Dir(cachePath))
+	historyStore.SetSampleRetention(history.DefaultSampleRetention)
+	budgets := budget.NewEnforcer(cfgStore, stateStore, historyStore, logger.With("component", "budget"))
+	stateStore.SetProjects(budgets.ProjectTotals())
+	events.On(bus, events.DefaultBuffer, func(ev events.RefreshSucceeded) {
+		if err := historyStore.AppendSamples(report.SamplesFromRefresh(ev)); err != nil {
+			logger.Warn("spend samples save failed", "error", err)
+		}
+	})
+	proxies := &proxyRunner{
+		history: historyStore,
+		budget:  budgets,
+		bus:     bus,
+		client:  client,
+		logger:  logger.With("component", "proxy"),
+	}
+	events.On(bus, events.DefaultBuffer, func(ev events.ConfigChanged) {
+		redactor.SetSecrets(redact.ConfigSecrets(ev.Config)...)
+		showConnectionError(stateStore, ev.Config, applyConnection(client, ev.Config.Connection, logger))
+		proxies.Apply(ev.Config.Proxy, ev.Config.Connection)
+		if period, ok := config.ParsePeriod(ev.Config.Updates.Period); ok {
+			stateStore.SetPeriod(period)
+		}
+	})
+
+	refresher := refresh.New(client, cacheStore, cfgStore, bus, stateStore, logger.With("component", "refresher"))
+	refresher.SetTracer(telemetryExporter)
 
 	interval, ok := config.ParsePeriod(cfg.Updates.Period)
 	if !ok {
 		interval = 30 * time.Minute
 	}
+	stateStore.SetPeriod(interval)
+	netMonitor := connectivity.New(connectivity.RequestProbe(client.Ping), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		_ = refresher.Refresh(ctx)
+	}, logger.With("component", "connectivity"))
+	bus.Listen(events.DefaultBuffer, netMonitor.HandleEvent)
 	sched := scheduler.New(interval, func(ctx context.Context) error {
-		if err := refresher.Refresh(ctx); err != nil && !errors.Is(err, refresh.ErrNotConfigured) {
+		// The monitor refreshes as soon as the network is back.
+		if netMonitor.Offline() {
+			logger.Debug("scheduled refresh skipped: offline")
+			return nil
+		}
+		err := refresher.Refresh(ctx)
+		if err != nil && !errors.Is(err, refresh.ErrNotConfigured) && !openrouter.IsNetworkError(err) {
 			return err
 		}
 		return nil
 	}, logger.With("component", "scheduler"))
 
-	var trayUI *tray.Tray
+	staleWatcher := staleness.New(stateStore, bus, logger.With("component", "staleness"))
+
+	var starter autostart.Manager
+	if exe, err := autostart.Executable(); err != nil {
+		logger.Warn("executable path unavailable, start at login disabled", "error", err)
+	} else {
+		starter = autostart.New(exe)
+	}
+
+	// The dashboard drives trayActions, so it is created after them.
+	var dash *dashboard.Server
 	trayActions := tray.Actions{
 		Refresh: func() {
 			go func() {
@@ -118,16 +224,13 @@ func main() {
 		OpenSettings: func() {
 			settings.Show(fyneApp, settings.Deps{
 				ConfigStore: cfgStore,
+				Autostart:   starter,
 				Refresher:   refresher,
 				Scheduler:   sched,
-				Notifier:    notifier,
-				LevelVar:    levelVar,
+				Bus:         bus,
 				LogOutput:   logOutput,
 				LogPath:     logPath,
 				Logger:      logger.With("component", "settings"),
-				OnConfigApplied: func(cfg config.Config) {
-					trayUI.Update()
-				},
 			})
 		},
 		OpenWeb: func() {
@@ -142,20 +245,96 @@ func main() {
 				logger.Warn("open url failed", "error", err)
 			}
 		},
+		LookupGeneration: func() {
+			generation.Show(fyneApp, generation.Deps{
+				Lookup: func(ctx context.Context, id string) (openrouter.Generation, error) {
+					return client.FetchGeneration(ctx, cfgStore.Get().Connection.Token, id)
+				},
+				Logger: logger.With("component", "generation"),
+			})
+		},
+		ShowHistory: func() {
+			spendhistory.Show(fyneApp, spendhistory.Deps{
+				History: historyStore,
+				Logger:  logger.With("component", "history"),
+			})
+		},
+		ExportSpend: func() {
+			spend.Show(fyneApp, spend.Deps{
+				History: historyStore,
+				Logger:  logger.With("component", "export"),
+			})
+		},
+		ViewLogs: func() {
+			logs.Show(fyneApp, logs.Deps{
+				ConfigStore: cfgStore,
+				LogPath:     logPath,
+				Logger:      logger.With("component", "logs"),
+			})
+		},
 		Exit: func() {
 			sched.Stop()
+			staleWatcher.Stop()
+			netMonitor.Stop()
+			if inst != nil {
+				_ = inst.Close()
+			}
+			proxies.Stop()
+			mqttPublisher.Stop()
+			dash.Stop()
+			telemetryExporter.Close()
 			fyneApp.Quit()
 		},
 	}
 
-	trayUI = tray.New(fyneApp, stateStore, cfgStore, logger.With("component", "tray"), trayActions)
+	dash = dashboard.New(dashboard.Deps{
+		State:        stateStore,
+		Config:       cfgStore,
+		History:      historyStore,
+		Refresh:      refresher.Refresh,
+		OpenSettings: trayActions.OpenSettings,
+		Logger:       logger.With("component", "dashboard"),
+	})
+	bus.Listen(events.DefaultBuffer, dash.HandleEvent)
+
+	trayUI := tray.New(fyneApp, stateStore, cfgStore, logger.With("component", "tray"), trayActions)
 
-	refresher.SetUpdateCallback(func() {
+	bus.Listen(events.DefaultBuffer, func(events.Event) {
 		trayUI.Update()
 	})
+	// Totals are also read on refresh so they roll over at midnight UTC.
+	bus.Listen(events.DefaultBuffer, func(ev events.Event) {
+		switch ev.(type) {
+		case events.ProjectCostRecorded, events.RefreshSucceeded:
+			stateStore.SetProjects(budgets.ProjectTotals())
+			trayUI.Update()
+		}
+	})
+
+	if inst != nil {
+		inst.Serve(func(ctx context.Context, command string) (string, error) {
+			switch command {
+			case instance.CommandShowSettings:
+				trayActions.OpenSettings()
+				return "", nil
+			case instance.CommandRefresh:
+				if err := refresher.Refresh(ctx); err != nil {
+					return "", err
+				}
+				return summary.Tooltip(cfgStore.Get(), stateStore.Snapshot()), nil
+			case instance.CommandStatus:
+				return summary.Tooltip(cfgStore.Get(), stateStore.Snapshot()), nil
+			}
+			return "", fmt.Errorf("unknown command %q", command)
+		})
+	}
 
 	trayUI.Update()
 	sched.Start()
+	staleWatcher.Start(staleness.CheckInterval, trayUI.Update)
+	proxies.Apply(cfg.Proxy, cfg.Connection)
+	mqttPublisher.Apply(cfg.MQTT)
+	dash.Apply(cfg.Dashboard)
 
 	sendStartSummary := func() {
 		if notifier == nil {
@@ -187,3 +366,26 @@ func activityURL(keyID string) string {
 	}
 	return "https://openrouter.ai/activity?api_key_id=" + url.QueryEscape(keyID)
 }
+
+// applyConnection points client at the configured base URL, proxy and CA
+// bundle. Invalid settings disable the client rather than bypass the proxy or
+// CA, and the error is returned for the user to see.
+func applyConnection(client *openrouter.Client, conn config.ConnectionConfig, logger *slog.Logger) error {
+	httpClient, err := transport.New(conn)
+	if err != nil {
+		err = fmt.Errorf("connection settings invalid: %w", err)
+		logger.Error("requests disabled", "error", err)
+		client.Disable(err)
+		return err
+	}
+	client.Reconfigure(conn.BaseURL, httpClient)
+	return nil
+}
+
+// showConnectionError puts an applyConnection error in the tray state.
+func showConnectionError(stateStore *state.State, cfg config.Config, err error) {
+	if err == nil || !cfg.Connection.Configured() {
+		return
+	}
+	stateStore.SetError(errors.New(redact.String(err.Error(), redact.ConfigSecrets(cfg)...)))
+}
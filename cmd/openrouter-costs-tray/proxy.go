@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/budget"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/proxy"
+	"openrouter-costs-tray/internal/transport"
+)
+
+// proxyRunner starts, stops and restarts the metering proxy as its config changes.
+type proxyRunner struct {
+	history *history.Store
+	budget  *budget.Enforcer
+	bus     *events.Bus
+	client  *openrouter.Client
+	logger  *slog.Logger
+
+	mu          sync.Mutex
+	current     *proxy.Proxy
+	applied     config.ProxyConfig
+	appliedConn config.ConnectionConfig
+}
+
+// Apply (re)starts the proxy when its own settings or the upstream connection
+// settings changed. Credentials are per request and do not count.
+func (r *proxyRunner) Apply(cfg config.ProxyConfig, conn config.ConnectionConfig) {
+	conn.Token, conn.ProvisioningKey = "", ""
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil && cfg == r.applied && conn == r.appliedConn {
+		return
+	}
+	r.stopLocked()
+	r.applied = cfg
+	r.appliedConn = conn
+	if !cfg.Enabled {
+		return
+	}
+	httpClient, err := transport.New(conn)
+	if err != nil {
+		r.logger.Error("proxy upstream connection invalid", "error", err)
+		return
+	}
+	p, err := proxy.New(proxy.Options{
+		Upstream: conn.BaseURL,
+		// Completions may stream for minutes; keep the transport but not
+		// the API client's overall timeout.
+		Transport:      httpClient.Transport,
+		ProjectHeader:  cfg.ProjectHeader,
+		DefaultProject: cfg.DefaultProject,
+		History:        r.history,
+		Budget:         r.budget,
+		Bus:            r.bus,
+		Client:         r.client,
+		Logger:         r.logger,
+	})
+	if err != nil {
+		r.logger.Error("proxy init failed", "error", err)
+		return
+	}
+	if err := p.Start(cfg.Listen); err != nil {
+		r.logger.Error("proxy start failed", "error", err, "addr", cfg.Listen)
+		return
+	}
+	r.current = p
+}
+
+func (r *proxyRunner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopLocked()
+}
+
+func (r *proxyRunner) stopLocked() {
+	if r.current == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.current.Stop(ctx); err != nil {
+		r.logger.Warn("proxy stop failed", "error", err)
+	}
+	r.current = nil
+}
@@ -0,0 +1,39 @@
+// Package autostart installs the tray to start at login. Each platform has its
+// own Manager: an XDG autostart entry, a LaunchAgent plist or a Run key value.
+package autostart
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Name identifies the autostart entry on every platform.
+const Name = "openrouter-costs-tray"
+
+// Manager turns starting the tray at login on and off.
+type Manager interface {
+	Enabled() (bool, error)
+	Enable() error
+	Disable() error
+}
+
+// New returns the manager of the current platform that starts exe.
+func New(exe string) Manager {
+	return newManager(exe)
+}
+
+// Executable returns the path autostart should launch. AppImages report their
+// mount point as the executable, so the image itself is used instead.
+func Executable() (string, error) {
+	if image := os.Getenv("APPIMAGE"); image != "" {
+		return image, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return exe, nil
+}
@@ -0,0 +1,83 @@
+package autostart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"openrouter-costs-tray/internal/util"
+)
+
+// LaunchAgent writes a per-user launchd plist that runs the tray at login.
+type LaunchAgent struct {
+	Exe string
+	// Dir overrides ~/Library/LaunchAgents.
+	Dir string
+}
+
+func (l LaunchAgent) path() (string, error) {
+	dir := l.Dir
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, "Library", "LaunchAgents")
+	}
+	return filepath.Join(dir, Name+".plist"), nil
+}
+
+func (l LaunchAgent) Enabled() (bool, error) {
+	path, err := l.path()
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (l LaunchAgent) Enable() error {
+	if l.Exe == "" {
+		return errors.New("executable path is unknown")
+	}
+	path, err := l.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, launchAgentPlist(l.Exe), 0o644)
+}
+
+func (l LaunchAgent) Disable() error {
+	path, err := l.path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func launchAgentPlist(exe string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buf.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	buf.WriteString("  <key>Label</key>\n  <string>" + Name + "</string>\n")
+	buf.WriteString("  <key>ProgramArguments</key>\n  <array>\n    <string>")
+	_ = xml.EscapeText(&buf, []byte(exe))
+	buf.WriteString("</string>\n  </array>\n")
+	buf.WriteString("  <key>RunAtLoad</key>\n  <true/>\n")
+	buf.WriteString("</dict>\n</plist>\n")
+	return buf.Bytes()
+}
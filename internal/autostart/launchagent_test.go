@@ -0,0 +1,33 @@
+package autostart
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLaunchAgentEnableDisable(t *testing.T) {
+	dir := t.TempDir()
+	l := LaunchAgent{Exe: "/Applications/Tray & Co.app/Contents/MacOS/tray", Dir: dir}
+	if err := l.Enable(); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, Name+".plist"))
+	if err != nil {
+		t.Fatalf("read plist: %v", err)
+	}
+	plist := string(data)
+	if !strings.Contains(plist, "<string>/Applications/Tray &amp; Co.app/Contents/MacOS/tray</string>") || !strings.Contains(plist, "<key>RunAtLoad</key>\n  <true/>") {
+		t.Fatalf("unexpected plist:\n%s", plist)
+	}
+	if enabled, err := l.Enabled(); err != nil || !enabled {
+		t.Fatalf("expected enabled, got %v %v", enabled, err)
+	}
+	if err := l.Disable(); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if enabled, err := l.Enabled(); err != nil || enabled {
+		t.Fatalf("expected disabled, got %v %v", enabled, err)
+	}
+}
@@ -0,0 +1,5 @@
+package autostart
+
+func newManager(exe string) Manager {
+	return LaunchAgent{Exe: exe}
+}
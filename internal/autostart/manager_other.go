@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package autostart
+
+func newManager(exe string) Manager {
+	return XDG{Exe: exe}
+}
@@ -0,0 +1,63 @@
+package autostart
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const runKeyPath = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+// RunKey stores the tray command in the current user's Run registry key.
+type RunKey struct {
+	Exe string
+}
+
+func newManager(exe string) Manager {
+	return RunKey{Exe: exe}
+}
+
+func (r RunKey) Enabled() (bool, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer key.Close()
+	if _, _, err := key.GetStringValue(Name); err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r RunKey) Enable() error {
+	if r.Exe == "" {
+		return errors.New("executable path is unknown")
+	}
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.SetStringValue(Name, `"`+r.Exe+`"`)
+}
+
+func (r RunKey) Disable() error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer key.Close()
+	if err := key.DeleteValue(Name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return err
+	}
+	return nil
+}
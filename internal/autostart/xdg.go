@@ -0,0 +1,101 @@
+package autostart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"openrouter-costs-tray/internal/util"
+)
+
+// XDG writes a .desktop entry to the XDG autostart directory.
+type XDG struct {
+	Exe string
+	// Dir overrides the autostart directory; by default it is
+	// $XDG_CONFIG_HOME/autostart or ~/.config/autostart.
+	Dir string
+}
+
+// Path returns the location of the .desktop entry.
+func (x XDG) Path() (string, error) {
+	dir := x.Dir
+	if dir == "" {
+		base := os.Getenv("XDG_CONFIG_HOME")
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			base = filepath.Join(home, ".config")
+		}
+		dir = filepath.Join(base, "autostart")
+	}
+	return filepath.Join(dir, Name+".desktop"), nil
+}
+
+func (x XDG) Enabled() (bool, error) {
+	path, err := x.Path()
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (x XDG) Enable() error {
+	if x.Exe == "" {
+		return errors.New("executable path is unknown")
+	}
+	path, err := x.Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, []byte(desktopEntry(x.Exe)), 0o644)
+}
+
+func (x XDG) Disable() error {
+	path, err := x.Path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func desktopEntry(exe string) string {
+	return "[Desktop Entry]\n" +
+		"Type=Application\n" +
+		"Name=OpenRouter Costs Tray\n" +
+		"Comment=OpenRouter spend in the system tray\n" +
+		"Exec=" + quoteExec(exe) + "\n" +
+		"Terminal=false\n" +
+		"X-GNOME-Autostart-enabled=true\n"
+}
+
+// quoteExec quotes a path for the Exec key as the desktop entry spec requires.
+func quoteExec(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		if r == '"' || r == '`' || r == '$' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
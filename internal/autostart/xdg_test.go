@@ -0,0 +1,74 @@
+package autostart
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXDGEnableDisable(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", base)
+	x := XDG{Exe: "/opt/OpenRouter Costs/openrouter-costs-tray"}
+
+	path, err := x.Path()
+	if err != nil || path != filepath.Join(base, "autostart", Name+".desktop") {
+		t.Fatalf("unexpected path %q %v", path, err)
+	}
+	if enabled, err := x.Enabled(); err != nil || enabled {
+		t.Fatalf("expected disabled before enable, got %v %v", enabled, err)
+	}
+
+	if err := x.Enable(); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if enabled, err := x.Enabled(); err != nil || !enabled {
+		t.Fatalf("expected enabled, got %v %v", enabled, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read entry: %v", err)
+	}
+	entry := string(data)
+	if !strings.HasPrefix(entry, "[Desktop Entry]\n") || !strings.Contains(entry, "\nType=Application\n") {
+		t.Fatalf("unexpected entry:\n%s", entry)
+	}
+	if !strings.Contains(entry, "\nExec=\"/opt/OpenRouter Costs/openrouter-costs-tray\"\n") {
+		t.Fatalf("expected quoted Exec line:\n%s", entry)
+	}
+
+	// Enabling twice rewrites the entry instead of failing.
+	if err := x.Enable(); err != nil {
+		t.Fatalf("second enable: %v", err)
+	}
+	if err := x.Disable(); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected entry removed, got %v", err)
+	}
+	if err := x.Disable(); err != nil {
+		t.Fatalf("disable when absent: %v", err)
+	}
+}
+
+func TestXDGEnableWithoutExecutable(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := (XDG{}).Enable(); err == nil {
+		t.Fatalf("expected error without executable")
+	}
+}
+
+func TestQuoteExec(t *testing.T) {
+	cases := map[string]string{
+		"/usr/bin/openrouter-costs-tray": "/usr/bin/openrouter-costs-tray",
+		"/home/a b/tray":                 `"/home/a b/tray"`,
+		`/tmp/$x"y`:                      `"/tmp/\$x\"y"`,
+	}
+	for in, want := range cases {
+		if got := quoteExec(in); got != want {
+			t.Fatalf("quoteExec(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bar
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+
+	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/refresh"
+	"openrouter-costs-tray/internal/summary"
+	"openrouter-costs-tray/internal/util"
+)
+
+var Formats = []string{"waybar", "polybar", "i3blocks", "plain"}
+
+// Classes double as waybar CSS classes.
+const (
+	ClassNormal        = "normal"
+	ClassWarning       = "warning"
+	ClassCritical      = "critical"
+	ClassNoData        = "no-data"
+	ClassNotConfigured = "not-configured"
+)
+
+var classColors = map[string]string{
+	ClassWarning:       "#e5c07b",
+	ClassCritical:      "#e06c75",
+	ClassNoData:        "#888888",
+	ClassNotConfigured: "#888888",
+}
+
+// Thresholds classify today's spend in USD. Zero disables a level.
+type Thresholds struct {
+	Warn     float64
+	Critical float64
+}
+
+// DefaultThresholds follow the budget of the configured key.
+func DefaultThresholds(cfg config.Config) Thresholds {
+	return Thresholds{Warn: cfg.Budgets.Key.SoftCap, Critical: cfg.Budgets.Key.DailyCap}
+}
+
+// Status is what a bar shows. Percentage is today's spend relative to the
+// critical threshold and is nil without one.
+type Status struct {
+	Text       string
+	Tooltip    string
+	Class      string
+	Percentage *int
+}
+
+// Build derives the status from the cache written by the tray app's refreshes.
+func Build(cfg config.Config, cached *cache.CostsCache, th Thresholds) Status {
+	if !cfg.Connection.Configured() {
+		return Status{Text: "n/a", Tooltip: "Set token in Settings", Class: ClassNotConfigured}
+	}
+	if cached == nil {
+		return Status{Text: "n/a", Tooltip: "No data yet: start the tray app to refresh usage", Class: ClassNoData}
+	}
+	snap := refresh.SnapshotFromCache(cached)
+	status := Status{Tooltip: summary.Tooltip(cfg, snap), Class: ClassNormal}
+	spent := snap.Usage.Total
+	if snap.Usage.Daily != nil {
+		spent = *snap.Usage.Daily
+	}
+	status.Text = util.FormatUSD(spent)
+	if snap.Usage.Daily == nil {
+		return status
+	}
+	switch {
+	case th.Critical > 0 && spent >= th.Critical:
+		status.Class = ClassCritical
+	case th.Warn > 0 && spent >= th.Warn:
+		status.Class = ClassWarning
+	}
+	if th.Critical > 0 {
+		percentage := min(int(spent/th.Critical*100), 100)
+		status.Percentage = &percentage
+	}
+	return status
+}
+
+// Render formats one output line for the given bar.
+func Render(format string, status Status) (string, error) {
+	color := classColors[status.Class]
+	switch format {
+	case "waybar":
+		data, err := json.Marshal(struct {
+			Text       string `json:"text"`
+			Tooltip    string `json:"tooltip"`
+			Class      string `json:"class"`
+			Percentage *int   `json:"percentage,omitempty"`
+		}{status.Text, status.Tooltip, status.Class, status.Percentage})
+		return string(data), err
+	case "polybar":
+		if color == "" {
+			return status.Text, nil
+		}
+		return "%{F" + color + "}" + status.Text + "%{F-}", nil
+	case "i3blocks":
+		// Pango markup, so it works with markup=pango in both one-shot and persist mode.
+		text := html.EscapeString(status.Text)
+		if color == "" {
+			return text, nil
+		}
+		return `<span foreground="` + color + `">` + text + "</span>", nil
+	case "plain":
+		return status.Text, nil
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,68 @@
+package bar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/config"
+)
+
+func configured() config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	return cfg
+}
+
+func TestBuild(t *testing.T) {
+	daily := 4.0
+	cached := &cache.CostsCache{LastSuccessAt: time.Now(), TotalUsage: 20, DailyUsage: &daily}
+
+	status := Build(configured(), cached, Thresholds{Warn: 3, Critical: 5})
+	if status.Text != "$4.000" || status.Class != ClassWarning || status.Percentage == nil || *status.Percentage != 80 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if !strings.Contains(status.Tooltip, "Daily: $4.000") {
+		t.Fatalf("expected summary tooltip, got %q", status.Tooltip)
+	}
+
+	if status := Build(configured(), cached, Thresholds{Critical: 2}); status.Class != ClassCritical || *status.Percentage != 100 {
+		t.Fatalf("expected capped critical status, got %+v", status)
+	}
+	if status := Build(configured(), cached, Thresholds{}); status.Class != ClassNormal || status.Percentage != nil {
+		t.Fatalf("expected normal status without thresholds, got %+v", status)
+	}
+	if status := Build(configured(), &cache.CostsCache{TotalUsage: 20}, Thresholds{Warn: 1}); status.Text != "$20.00" || status.Class != ClassNormal {
+		t.Fatalf("expected total without daily usage, got %+v", status)
+	}
+	if status := Build(configured(), nil, Thresholds{}); status.Class != ClassNoData {
+		t.Fatalf("expected no data status, got %+v", status)
+	}
+	if status := Build(config.DefaultConfig(), cached, Thresholds{}); status.Class != ClassNotConfigured {
+		t.Fatalf("expected not configured status, got %+v", status)
+	}
+}
+
+func TestRender(t *testing.T) {
+	percentage := 80
+	status := Status{Text: "$4.000", Tooltip: "Daily: $4.000\nTotal: $20.00", Class: ClassWarning, Percentage: &percentage}
+	cases := map[string]string{
+		"waybar":   `{"text":"$4.000","tooltip":"Daily: $4.000\nTotal: $20.00","class":"warning","percentage":80}`,
+		"polybar":  "%{F#e5c07b}$4.000%{F-}",
+		"i3blocks": `<span foreground="#e5c07b">$4.000</span>`,
+		"plain":    "$4.000",
+	}
+	for format, want := range cases {
+		got, err := Render(format, status)
+		if err != nil || got != want {
+			t.Fatalf("%s: got %q %v, want %q", format, got, err, want)
+		}
+	}
+	if got, _ := Render("polybar", Status{Text: "$1.000", Class: ClassNormal}); got != "$1.000" {
+		t.Fatalf("expected uncoloured normal status, got %q", got)
+	}
+	if _, err := Render("tmux", status); err == nil {
+		t.Fatalf("expected unknown format error")
+	}
+}
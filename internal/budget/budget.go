@@ -0,0 +1,209 @@
+package budget
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/state"
+	"openrouter-costs-tray/internal/util"
+)
+
+type Action string
+
+const (
+	Allow     Action = "allow"
+	Downgrade Action = "downgrade"
+	Block     Action = "block"
+)
+
+// Decision is the outcome of checking a request against the configured budgets.
+type Decision struct {
+	Action Action
+	// Model is the fallback model for Downgrade.
+	Model string
+	// Scope names the budget that triggered the decision, e.g. "project api".
+	Scope string
+	Spent float64
+	Cap   float64
+	// Checked are the per-budget results behind the decision, for logging.
+	Checked []Decision
+}
+
+// Reason describes a non-allow decision for logs and error responses.
+func (d Decision) Reason() string {
+	switch d.Action {
+	case Block:
+		return fmt.Sprintf("daily budget exceeded for %s: spent %s of %s", d.Scope, util.FormatUSD(d.Spent), util.FormatUSD(d.Cap))
+	case Downgrade:
+		return fmt.Sprintf("soft budget exceeded for %s: spent %s of %s, using %s", d.Scope, util.FormatUSD(d.Spent), util.FormatUSD(d.Cap), d.Model)
+	default:
+		if len(d.Checked) == 0 {
+			return "no budget applies"
+		}
+		parts := make([]string, 0, len(d.Checked))
+		for _, c := range d.Checked {
+			limit := "no cap"
+			if c.Cap > 0 {
+				limit = "of " + util.FormatUSD(c.Cap)
+			}
+			parts = append(parts, fmt.Sprintf("%s spent %s %s", c.Scope, util.FormatUSD(c.Spent), limit))
+		}
+		return "within budget: " + strings.Join(parts, ", ")
+	}
+}
+
+// Evaluate checks spent against b. Blocking wins over downgrading, and a
+// request already using the fallback model is not downgraded.
+func Evaluate(b config.Budget, scope string, spent float64, model string) Decision {
+	if b.DailyCap > 0 && spent >= b.DailyCap {
+		return Decision{Action: Block, Scope: scope, Spent: spent, Cap: b.DailyCap}
+	}
+	if b.SoftCap > 0 && b.FallbackModel != "" && spent >= b.SoftCap && model != b.FallbackModel {
+		return Decision{Action: Downgrade, Model: b.FallbackModel, Scope: scope, Spent: spent, Cap: b.SoftCap}
+	}
+	return Decision{Action: Allow, Scope: scope, Spent: spent, Cap: b.DailyCap}
+}
+
+// merge returns the stricter of two decisions, preferring the later one on a tie.
+func merge(a, b Decision) Decision {
+	if rank(b.Action) >= rank(a.Action) {
+		return b
+	}
+	return a
+}
+
+func rank(action Action) int {
+	switch action {
+	case Block:
+		return 2
+	case Downgrade:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type metered struct {
+	at   time.Time
+	cost float64
+}
+
+// Enforcer tracks today's (UTC) spend per project and for the configured key.
+// Project spend comes from the proxy history; key spend is the daily usage
+// from the last refresh plus what the proxy metered since then.
+type Enforcer struct {
+	config  *config.Store
+	state   *state.State
+	history *history.Store
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu       sync.Mutex
+	day      string
+	projects map[string]float64
+	keyCosts []metered
+}
+
+func NewEnforcer(cfgStore *config.Store, stateStore *state.State, historyStore *history.Store, logger *slog.Logger) *Enforcer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Enforcer{
+		config:  cfgStore,
+		state:   stateStore,
+		history: historyStore,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Check decides what to do with a request for project made with token and model.
+func (e *Enforcer) Check(project, token, model string) Decision {
+	cfg := e.config.Get()
+	budgets := cfg.Budgets
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now().UTC()
+	e.rollover(now)
+
+	decision := Decision{Action: Allow}
+	var checked []Decision
+	if b, ok := budgets.Projects[project]; ok {
+		d := Evaluate(b, "project "+project, e.projects[project], model)
+		checked = append(checked, d)
+		decision = merge(decision, d)
+	}
+	if token != "" && token == cfg.Connection.Token {
+		d := Evaluate(budgets.Key, "API key", e.keySpend(), model)
+		checked = append(checked, d)
+		decision = merge(decision, d)
+	}
+	decision.Checked = checked
+	return decision
+}
+
+// Record adds a metered cost so checks see it before the next refresh.
+func (e *Enforcer) Record(project, token string, cost float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now().UTC()
+	e.rollover(now)
+	e.projects[project] += cost
+	if token != "" && token == e.config.Get().Connection.Token {
+		e.keyCosts = append(e.keyCosts, metered{at: now, cost: cost})
+	}
+}
+
+// ProjectTotals returns today's (UTC) spend per project, the same totals the
+// project budgets are checked against.
+func (e *Enforcer) ProjectTotals() map[string]float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rollover(e.now().UTC())
+	return maps.Clone(e.projects)
+}
+
+// rollover reloads project totals from history when the UTC day changes.
+func (e *Enforcer) rollover(now time.Time) {
+	day := now.Format("2006-01-02")
+	if day == e.day {
+		return
+	}
+	e.day = day
+	e.projects = map[string]float64{}
+	e.keyCosts = nil
+	if e.history == nil {
+		return
+	}
+	records, err := e.history.ProjectCosts(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		e.logger.Warn("project costs load failed", "error", err)
+		return
+	}
+	e.projects = history.ProjectTotals(records)
+}
+
+func (e *Enforcer) keySpend() float64 {
+	snap := e.state.Snapshot()
+	var spent float64
+	refreshedToday := snap.LastSuccessAt.UTC().Format("2006-01-02") == e.day
+	if refreshedToday && snap.Usage.Daily != nil {
+		spent = *snap.Usage.Daily
+	}
+	kept := e.keyCosts[:0]
+	for _, cost := range e.keyCosts {
+		if refreshedToday && !cost.at.After(snap.LastSuccessAt) {
+			continue
+		}
+		kept = append(kept, cost)
+		spent += cost.cost
+	}
+	e.keyCosts = kept
+	return spent
+}
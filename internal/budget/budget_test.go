@@ -0,0 +1,116 @@
+package budget
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/state"
+)
+
+func TestEvaluate(t *testing.T) {
+	b := config.Budget{DailyCap: 10, SoftCap: 5, FallbackModel: "openai/gpt-4o-mini"}
+	if d := Evaluate(b, "project api", 4, "openai/gpt-4o"); d.Action != Allow {
+		t.Fatalf("expected allow below soft cap, got %s", d.Action)
+	}
+	d := Evaluate(b, "project api", 6, "openai/gpt-4o")
+	if d.Action != Downgrade || d.Model != "openai/gpt-4o-mini" || d.Cap != 5 {
+		t.Fatalf("expected downgrade, got %+v", d)
+	}
+	if d := Evaluate(b, "project api", 6, "openai/gpt-4o-mini"); d.Action != Allow {
+		t.Fatalf("expected fallback model to be allowed, got %s", d.Action)
+	}
+	d = Evaluate(b, "project api", 10, "openai/gpt-4o")
+	if d.Action != Block || d.Cap != 10 {
+		t.Fatalf("expected block at cap, got %+v", d)
+	}
+	if d.Reason() != "daily budget exceeded for project api: spent $10.00 of $10.00" {
+		t.Fatalf("unexpected reason: %q", d.Reason())
+	}
+	if d := Evaluate(config.Budget{}, "project api", 100, ""); d.Action != Allow {
+		t.Fatalf("expected no caps to allow, got %s", d.Action)
+	}
+}
+
+func newEnforcer(t *testing.T, cfg config.Config, now time.Time) (*Enforcer, *history.Store, *state.State) {
+	t.Helper()
+	dir := t.TempDir()
+	store := history.NewStore(dir)
+	st := state.New()
+	e := NewEnforcer(config.NewStore(filepath.Join(dir, "config.json"), cfg), st, store, nil)
+	e.now = func() time.Time { return now }
+	return e, store, st
+}
+
+func TestEnforcerProjectSpend(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	cfg := config.DefaultConfig()
+	cfg.Budgets.Projects = map[string]config.Budget{"api": {DailyCap: 1}}
+	e, store, _ := newEnforcer(t, cfg, now)
+	for _, rec := range []history.ProjectCost{
+		{At: now.Add(-24 * time.Hour), Project: "api", Cost: 5},
+		{At: now.Add(-time.Hour), Project: "api", Cost: 0.6},
+	} {
+		if err := store.AppendProjectCost(rec); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	d := e.Check("api", "", "m")
+	if d.Action != Allow || d.Spent != 0.6 {
+		t.Fatalf("expected only today's spend to count, got %+v", d)
+	}
+	if d.Reason() != "within budget: project api spent $0.6000 of $1.000" {
+		t.Fatalf("unexpected allow reason: %q", d.Reason())
+	}
+	e.Record("api", "", 0.5)
+	if totals := e.ProjectTotals(); len(totals) != 1 || totals["api"] != 1.1 {
+		t.Fatalf("expected recorded cost in today's totals, got %v", totals)
+	}
+	if d := e.Check("api", "", "m"); d.Action != Block {
+		t.Fatalf("expected recorded cost to block, got %+v", d)
+	}
+	if d := e.Check("other", "", "m"); d.Action != Allow || d.Reason() != "no budget applies" {
+		t.Fatalf("expected project without budget to be allowed, got %+v", d)
+	}
+
+	e.now = func() time.Time { return now.Add(24 * time.Hour) }
+	if d := e.Check("api", "", "m"); d.Action != Allow || d.Spent != 0 {
+		t.Fatalf("expected spend to reset on a new day, got %+v", d)
+	}
+	if totals := e.ProjectTotals(); len(totals) != 0 {
+		t.Fatalf("expected totals to reset on a new day, got %v", totals)
+	}
+}
+
+func TestEnforcerKeySpend(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "sk-test"
+	cfg.Budgets.Key = config.Budget{DailyCap: 2, SoftCap: 1, FallbackModel: "cheap"}
+	e, _, st := newEnforcer(t, cfg, now)
+	daily := 0.9
+	st.SetSuccess(openrouter.Usage{Daily: &daily}, now.Add(-time.Minute))
+
+	if d := e.Check("api", "sk-other", "m"); d.Action != Allow {
+		t.Fatalf("expected other tokens to be unaffected, got %+v", d)
+	}
+	if d := e.Check("api", "sk-test", "m"); d.Action != Allow || d.Spent != 0.9 {
+		t.Fatalf("expected refreshed daily usage, got %+v", d)
+	}
+	e.Record("api", "sk-test", 0.2)
+	d := e.Check("api", "sk-test", "m")
+	if d.Action != Downgrade || d.Model != "cheap" || d.Scope != "API key" {
+		t.Fatalf("expected downgrade after metered cost, got %+v", d)
+	}
+
+	// A later refresh already includes the metered cost.
+	daily = 1.1
+	st.SetSuccess(openrouter.Usage{Daily: &daily}, now.Add(time.Minute))
+	if d := e.Check("api", "sk-test", "m"); d.Spent != 1.1 {
+		t.Fatalf("expected metered cost to be dropped after refresh, got %+v", d)
+	}
+}
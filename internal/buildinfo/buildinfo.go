@@ -0,0 +1,20 @@
+// Package buildinfo reports which version of the app is running.
+package buildinfo
+
+import "runtime/debug"
+
+// version is set for releases with
+// -ldflags "-X openrouter-costs-tray/internal/buildinfo.version=v1.2.3".
+var version string
+
+// Version returns the release version, else the module version recorded by
+// the Go toolchain, else "dev".
+func Version() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
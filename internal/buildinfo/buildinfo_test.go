@@ -0,0 +1,14 @@
+package buildinfo
+
+import "testing"
+
+func TestVersion(t *testing.T) {
+	if Version() == "" {
+		t.Fatalf("expected a fallback version")
+	}
+	defer func(prev string) { version = prev }(version)
+	version = "v1.2.3"
+	if got := Version(); got != "v1.2.3" {
+		t.Fatalf("expected linker-set version, got %q", got)
+	}
+}
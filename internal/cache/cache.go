@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"openrouter-costs-tray/internal/util"
 )
 
 const CacheFileName = "costs_cache.json"
@@ -14,14 +16,48 @@ const CacheFileName = "costs_cache.json"
 const SchemaVersion = "1"
 
 type CostsCache struct {
-	SchemaVersion string    `json:"schema_version,omitempty"`
-	LastSuccessAt time.Time `json:"last_success_at"`
-	TotalUsage    float64   `json:"total_usage"`
-	DailyUsage    *float64  `json:"daily_usage,omitempty"`
-	WeeklyUsage   *float64  `json:"weekly_usage,omitempty"`
-	MonthlyUsage  *float64  `json:"monthly_usage,omitempty"`
-	KeyHash       string    `json:"key_hash,omitempty"`
-	KeyID         string    `json:"key_id,omitempty"`
+	SchemaVersion string          `json:"schema_version,omitempty"`
+	LastSuccessAt time.Time       `json:"last_success_at"`
+	TotalUsage    float64         `json:"total_usage"`
+	DailyUsage    *float64        `json:"daily_usage,omitempty"`
+	WeeklyUsage   *float64        `json:"weekly_usage,omitempty"`
+	MonthlyUsage  *float64        `json:"monthly_usage,omitempty"`
+	KeyHash       string          `json:"key_hash,omitempty"`
+	KeyID         string          `json:"key_id,omitempty"`
+	Activity      []ModelActivity `json:"activity,omitempty"`
+	Keys          []KeyUsage      `json:"keys,omitempty"`
+	// KeysListed marks Keys as a provisioning listing, even an empty one.
+	KeysListed  bool                  `json:"keys_listed,omitempty"`
+	ModelPrices map[string]ModelPrice `json:"model_prices,omitempty"`
+}
+
+// ModelPrice is the cached USD-per-token price of a watched model.
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// KeyUsage is the cached usage of one account key in provisioning mode.
+type KeyUsage struct {
+	Hash         string   `json:"hash"`
+	Name         string   `json:"name,omitempty"`
+	Label        string   `json:"label,omitempty"`
+	Disabled     bool     `json:"disabled,omitempty"`
+	TotalUsage   float64  `json:"total_usage"`
+	DailyUsage   *float64 `json:"daily_usage,omitempty"`
+	WeeklyUsage  *float64 `json:"weekly_usage,omitempty"`
+	MonthlyUsage *float64 `json:"monthly_usage,omitempty"`
+	Limit        *float64 `json:"limit,omitempty"`
+}
+
+// ModelActivity is the cached spend of one model on one UTC day.
+type ModelActivity struct {
+	Date             string  `json:"date"`
+	Model            string  `json:"model"`
+	Usage            float64 `json:"usage"`
+	Requests         int     `json:"requests,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
 }
 
 func DefaultCacheDir() (string, error) {
@@ -64,7 +100,7 @@ func SaveToPath(path string, cache CostsCache) error {
 	if err != nil {
 		return err
 	}
-	return writeFileAtomic(path, data, 0o600)
+	return util.WriteFileAtomic(path, data, 0o600)
 }
 
 type Store struct {
@@ -91,33 +127,3 @@ func (s *Store) Save(cache CostsCache) error {
 	defer s.mu.Unlock()
 	return SaveToPath(s.path, cache)
 }
-
-func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, ".tmp-cache-*")
-	if err != nil {
-		return err
-	}
-	tmpName := tmp.Name()
-	defer os.Remove(tmpName)
-	if err := tmp.Chmod(perm); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if _, err := tmp.Write(data); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if err := tmp.Sync(); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if err := tmp.Close(); err != nil {
-		return err
-	}
-	if err := os.Rename(tmpName, path); err != nil {
-		_ = os.Remove(path)
-		return os.Rename(tmpName, path)
-	}
-	return nil
-}
@@ -0,0 +1,150 @@
+// Package chart lays out simple charts as plain geometry. It knows nothing
+// about widgets, so layouts and hit testing can be tested without a display;
+// the history window turns the shapes into canvas objects.
+package chart
+
+import (
+	"image/color"
+	"math"
+)
+
+// Size is the area a chart is laid out in.
+type Size struct {
+	W, H float32
+}
+
+// Point is a position inside a chart, with the origin at the top left.
+type Point struct {
+	X, Y float32
+}
+
+// Rect is an axis-aligned rectangle.
+type Rect struct {
+	X, Y, W, H float32
+}
+
+// Contains reports whether p lies inside r.
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.X && p.X < r.X+r.W && p.Y >= r.Y && p.Y < r.Y+r.H
+}
+
+// barGap is the share of a column left empty between bars.
+const barGap = 0.2
+
+// Max returns the largest value, or 0 for none.
+func Max(values []float64) float64 {
+	var out float64
+	for _, v := range values {
+		if v > out {
+			out = v
+		}
+	}
+	return out
+}
+
+// NiceCeil rounds v up to 1, 2 or 5 times a power of ten so the top of an
+// axis gets a readable label.
+func NiceCeil(v float64) float64 {
+	if v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if n := step * exp; n >= v*(1-1e-9) {
+			return n
+		}
+	}
+	return 10 * exp
+}
+
+// Bars lays values out as bars standing on the bottom edge, one per equal
+// column. A value of top reaches the top edge; negative values get no height.
+func Bars(values []float64, top float64, size Size) []Rect {
+	out := make([]Rect, len(values))
+	if len(values) == 0 {
+		return out
+	}
+	column := size.W / float32(len(values))
+	width := column * (1 - barGap)
+	for i, v := range values {
+		h := scale(v, top, size.H)
+		out[i] = Rect{
+			X: float32(i)*column + (column-width)/2,
+			Y: size.H - h,
+			W: width,
+			H: h,
+		}
+	}
+	return out
+}
+
+// Column returns the index of the equal-width column under x, or -1.
+func Column(n int, width, x float32) int {
+	if n <= 0 || width <= 0 || x < 0 || x >= width {
+		return -1
+	}
+	return min(int(x/(width/float32(n))), n-1)
+}
+
+// Polyline maps values to points centred in equal columns, so a line lines
+// up with bars of the same length.
+func Polyline(values []float64, top float64, size Size) []Point {
+	out := make([]Point, len(values))
+	if len(values) == 0 {
+		return out
+	}
+	column := size.W / float32(len(values))
+	for i, v := range values {
+		out[i] = Point{
+			X: (float32(i) + 0.5) * column,
+			Y: size.H - scale(v, top, size.H),
+		}
+	}
+	return out
+}
+
+// Grid splits size into rows x cols equal cells, in row-major order.
+func Grid(rows, cols int, size Size) []Rect {
+	if rows <= 0 || cols <= 0 {
+		return nil
+	}
+	w := size.W / float32(cols)
+	h := size.H / float32(rows)
+	out := make([]Rect, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			out = append(out, Rect{X: float32(c) * w, Y: float32(r) * h, W: w, H: h})
+		}
+	}
+	return out
+}
+
+// Cell returns the grid cell under p.
+func Cell(rows, cols int, size Size, p Point) (row, col int, ok bool) {
+	col = Column(cols, size.W, p.X)
+	row = Column(rows, size.H, p.Y)
+	if row < 0 || col < 0 {
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+// Shade returns c with its opacity scaled by v/top. Non-zero values stay
+// faintly visible so they can be told apart from empty cells.
+func Shade(c color.NRGBA, v, top float64) color.NRGBA {
+	if v <= 0 || top <= 0 {
+		c.A = 0
+		return c
+	}
+	t := math.Min(v/top, 1)
+	const floor = 0.12
+	c.A = uint8(math.Round(float64(c.A) * (floor + (1-floor)*t)))
+	return c
+}
+
+func scale(v, top float64, height float32) float32 {
+	if v <= 0 || top <= 0 {
+		return 0
+	}
+	return float32(math.Min(v/top, 1)) * height
+}
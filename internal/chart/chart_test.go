@@ -0,0 +1,96 @@
+package chart
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestNiceCeil(t *testing.T) {
+	cases := map[float64]float64{0: 0, 0.7: 1, 1: 1, 1.2: 2, 3: 5, 7.5: 10, 0.013: 0.02, 120: 200}
+	for in, want := range cases {
+		got := NiceCeil(in)
+		if got < want*0.999999 || got > want*1.000001 {
+			t.Fatalf("NiceCeil(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestBars(t *testing.T) {
+	bars := Bars([]float64{1, 0, 2, -1}, 2, Size{W: 100, H: 50})
+	if len(bars) != 4 {
+		t.Fatalf("expected 4 bars, got %d", len(bars))
+	}
+	if bars[0] != (Rect{X: 2.5, Y: 25, W: 20, H: 25}) {
+		t.Fatalf("unexpected first bar %+v", bars[0])
+	}
+	if bars[1].H != 0 || bars[3].H != 0 {
+		t.Fatalf("expected empty and negative bars to be flat, got %+v %+v", bars[1], bars[3])
+	}
+	if bars[2].Y != 0 || bars[2].H != 50 {
+		t.Fatalf("expected tallest bar to reach the top, got %+v", bars[2])
+	}
+	if len(Bars(nil, 1, Size{W: 10, H: 10})) != 0 {
+		t.Fatalf("expected no bars without values")
+	}
+}
+
+func TestColumn(t *testing.T) {
+	cases := []struct {
+		x    float32
+		want int
+	}{{-1, -1}, {0, 0}, {24.9, 0}, {25, 1}, {99.9, 3}, {100, -1}}
+	for _, tc := range cases {
+		if got := Column(4, 100, tc.x); got != tc.want {
+			t.Fatalf("Column(4, 100, %v) = %d, want %d", tc.x, got, tc.want)
+		}
+	}
+	if Column(0, 100, 10) != -1 {
+		t.Fatalf("expected -1 without columns")
+	}
+}
+
+func TestPolylineMatchesBarCentres(t *testing.T) {
+	size := Size{W: 90, H: 30}
+	points := Polyline([]float64{0, 1.5, 3}, 3, size)
+	bars := Bars([]float64{0, 1.5, 3}, 3, size)
+	for i, p := range points {
+		if centre := bars[i].X + bars[i].W/2; p.X != centre {
+			t.Fatalf("point %d at x=%v, bar centre %v", i, p.X, centre)
+		}
+	}
+	if points[0].Y != 30 || points[1].Y != 15 || points[2].Y != 0 {
+		t.Fatalf("unexpected heights %+v", points)
+	}
+}
+
+func TestGridAndCell(t *testing.T) {
+	size := Size{W: 240, H: 70}
+	cells := Grid(7, 24, size)
+	if len(cells) != 7*24 {
+		t.Fatalf("expected 168 cells, got %d", len(cells))
+	}
+	if cells[24+3] != (Rect{X: 30, Y: 10, W: 10, H: 10}) {
+		t.Fatalf("unexpected cell %+v", cells[24+3])
+	}
+	row, col, ok := Cell(7, 24, size, Point{X: 35, Y: 15})
+	if !ok || row != 1 || col != 3 || !cells[row*24+col].Contains(Point{X: 35, Y: 15}) {
+		t.Fatalf("unexpected hit %d %d %v", row, col, ok)
+	}
+	if _, _, ok := Cell(7, 24, size, Point{X: 250, Y: 5}); ok {
+		t.Fatalf("expected miss outside the grid")
+	}
+}
+
+func TestShade(t *testing.T) {
+	base := color.NRGBA{R: 10, G: 20, B: 30, A: 200}
+	if Shade(base, 0, 5).A != 0 {
+		t.Fatalf("expected empty cell to be transparent")
+	}
+	if got := Shade(base, 5, 5); got != base {
+		t.Fatalf("expected full colour at the top, got %+v", got)
+	}
+	low, high := Shade(base, 0.1, 5), Shade(base, 2.5, 5)
+	if low.A == 0 || low.A >= high.A || high.A >= base.A {
+		t.Fatalf("expected opacity to grow with value, got %d %d", low.A, high.A)
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func runAutostart(_ context.Context, env Env, args []string) error {
+	fs := newFlagSet("autostart", env)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	if env.Autostart == nil {
+		return errors.New("autostart is unavailable")
+	}
+	switch fs.Arg(0) {
+	case "enable":
+		if err := env.Autostart.Enable(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(env.Stdout, "Start at login enabled")
+		return err
+	case "disable":
+		if err := env.Autostart.Disable(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(env.Stdout, "Start at login disabled")
+		return err
+	case "status":
+		enabled, err := env.Autostart.Enabled()
+		if err != nil {
+			return err
+		}
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		_, err = fmt.Fprintln(env.Stdout, "Start at login "+state)
+		return err
+	}
+	return errUsage
+}
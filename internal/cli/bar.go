@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"openrouter-costs-tray/internal/bar"
+)
+
+// runBar prints the spend for status bars. It only reads the cache the tray
+// app writes, so running it never polls the API.
+func runBar(ctx context.Context, env Env, args []string) error {
+	fs := newFlagSet("bar", env)
+	format := fs.String("format", "waybar", "waybar, polybar, i3blocks or plain")
+	watch := fs.Bool("watch", false, "keep running and print a line after every refresh")
+	interval := fs.Duration("interval", 5*time.Second, "how often -watch checks the cache")
+	th := bar.DefaultThresholds(env.Config)
+	fs.Float64Var(&th.Warn, "warn", th.Warn, "daily spend in USD shown as warning (default: key soft cap)")
+	fs.Float64Var(&th.Critical, "crit", th.Critical, "daily spend in USD shown as critical (default: key daily cap)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || !slices.Contains(bar.Formats, *format) || *interval <= 0 {
+		return errUsage
+	}
+	if env.Cache == nil {
+		return errors.New("cache is unavailable")
+	}
+
+	emit := func() error {
+		cached, err := env.Cache.Load()
+		if err != nil {
+			env.Logger.Warn("cache load failed", "error", err)
+		}
+		line, err := bar.Render(*format, bar.Build(env.Config, cached, th))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(env.Stdout, line)
+		return err
+	}
+	if !*watch {
+		return emit()
+	}
+
+	var lastMod time.Time
+	first := true
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		var modTime time.Time
+		if info, err := os.Stat(env.Cache.Path()); err == nil {
+			modTime = info.ModTime()
+		}
+		if first || !modTime.Equal(lastMod) {
+			if err := emit(); err != nil {
+				return err
+			}
+			first = false
+			lastMod = modTime
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"openrouter-costs-tray/internal/autostart"
+	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/instance"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/redact"
+)
+
+// Env carries everything subcommands need from main.
+type Env struct {
+	Config  config.Config
+	Client  *openrouter.Client
+	History *history.Store
+	Cache   *cache.Store
+	// Socket is the running tray's instance socket.
+	Socket    string
+	Autostart autostart.Manager
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Logger    *slog.Logger
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env Env, args []string) error
+}
+
+var errUsage = errors.New("usage")
+
+func commands() []command {
+	return []command{
+		{name: "generation", summary: "generation <id>  show cost and token stats of one request", run: runGeneration},
+		{name: "bar", summary: "bar [-format waybar|polybar|i3blocks|plain] [-watch] [-warn USD] [-crit USD]  print spend for status bars", run: runBar},
+		{name: "status", summary: "status  print the running tray's spend summary", run: forward(instance.CommandStatus)},
+		{name: "refresh", summary: "refresh  make the running tray refresh now", run: forward(instance.CommandRefresh)},
+		{name: "settings", summary: "settings  open the running tray's settings window", run: forward(instance.CommandShowSettings)},
+		{name: "autostart", summary: "autostart enable|disable|status  start the tray at login", run: runAutostart},
+		{name: "mock-server", summary: "mock-server [-listen addr] [-pattern steady|bursty|workday|ramp] [-rate USD/h] [-limit USD] [-credits USD] [-token key] [-fail 401|429|500|slow|malformed]  serve a fake OpenRouter API for development", run: runMockServer},
+		{name: "export", summary: "export [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-format csv|json|jsonl] [-by none|day|week|month] [-utc] [-o file]  export recorded spend", run: runExport},
+	}
+}
+
+// IsCommand reports whether args start with a known subcommand.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return true
+	}
+	_, ok := lookup(args[0])
+	return ok
+}
+
+// Run executes the subcommand in args and returns the process exit code.
+func Run(ctx context.Context, env Env, args []string) int {
+	if env.Logger == nil {
+		env.Logger = slog.Default()
+	}
+	if len(args) == 0 {
+		printUsage(env.Stderr)
+		return 2
+	}
+	cmd, ok := lookup(args[0])
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			printUsage(env.Stdout)
+			return 0
+		}
+		fmt.Fprintf(env.Stderr, "unknown command %q\n", args[0])
+		printUsage(env.Stderr)
+		return 2
+	}
+	if err := cmd.run(ctx, env, args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(env.Stderr, "usage: openrouter-costs-tray "+cmd.summary)
+			return 2
+		}
+		fmt.Fprintln(env.Stderr, "error: "+redact.String(err.Error(), redact.ConfigSecrets(env.Config)...))
+		return 1
+	}
+	return 0
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: openrouter-costs-tray [command]")
+	fmt.Fprintln(w, "\nWithout a command the tray app is started.\n\ncommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintln(w, "  "+cmd.summary)
+	}
+}
+
+func newFlagSet(name string, env Env) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	return fs
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/autostart"
+	"openrouter-costs-tray/internal/cache"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/instance"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func newTestEnv(t *testing.T, handler http.HandlerFunc) (Env, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	return Env{
+		Config: cfg,
+		Client: openrouter.NewClient(srv.URL, srv.Client(), nil),
+		Stdout: stdout,
+		Stderr: stderr,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, stdout, stderr
+}
+
+func TestIsCommand(t *testing.T) {
+	if IsCommand(nil) {
+		t.Fatalf("expected no command without args")
+	}
+	if !IsCommand([]string{"generation", "x"}) || !IsCommand([]string{"help"}) {
+		t.Fatalf("expected known commands")
+	}
+	if IsCommand([]string{"-psn_0_12345"}) {
+		t.Fatalf("expected unknown args to start the tray")
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	env, _, stderr := newTestEnv(t, nil)
+	if code := Run(context.Background(), env, []string{"bogus"}); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), `unknown command "bogus"`) {
+		t.Fatalf("expected unknown command message, got %q", stderr.String())
+	}
+}
+
+func TestRunGeneration(t *testing.T) {
+	env, stdout, _ := newTestEnv(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generation" || r.URL.Query().Get("id") != "gen-1" {
+			t.Errorf("unexpected request: %s", r.URL.String())
+		}
+		_, _ = w.Write([]byte(`{"data":{"id":"gen-1","model":"openai/gpt-4o","provider_name":"OpenAI","total_cost":0.8}}`))
+	})
+
+	if code := Run(context.Background(), env, []string{"generation", "gen-1"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "Model:") || !strings.Contains(out, "openai/gpt-4o") || !strings.Contains(out, "$0.8000") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestRunGenerationAPIError(t *testing.T) {
+	env, _, stderr := newTestEnv(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":404,"message":"Generation not found"}}`))
+	})
+
+	if code := Run(context.Background(), env, []string{"generation", "missing"}); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "unexpected status 404: Generation not found") {
+		t.Fatalf("expected structured api error, got %q", stderr.String())
+	}
+}
+
+func TestRunGenerationUsage(t *testing.T) {
+	env, _, stderr := newTestEnv(t, nil)
+	if code := Run(context.Background(), env, []string{"generation"}); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "usage: openrouter-costs-tray generation <id>") {
+		t.Fatalf("expected usage, got %q", stderr.String())
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	env, stdout, _ := newTestEnv(t, nil)
+	env.History = history.NewStore(t.TempDir())
+	first := time.Date(2024, 4, 29, 12, 0, 0, 0, time.UTC)
+	err := env.History.AppendSamples([]history.SpendSample{
+		{At: first, KeyID: "k1", KeyLabel: "ci", Total: 1},
+		{At: first.Add(24 * time.Hour), KeyID: "k1", KeyLabel: "ci", Total: 3},
+	})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	args := []string{"export", "-from", "2024-04-29", "-to", "2024-04-30", "-by", "month", "-utc", "-format", "jsonl"}
+	if code := Run(context.Background(), env, args); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	want := `{"period_start":"2024-04-29","period_end":"2024-04-30","key_id":"k1","key_label":"ci","spend":2,"samples":2}` + "\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "spend.csv")
+	if code := Run(context.Background(), env, []string{"export", "-from", "2024-04-29", "-to", "2024-04-30", "-utc", "-o", out}); code != 0 {
+		t.Fatalf("expected exit code 0 writing a file, got %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.HasPrefix(string(data), "at,key_id,key_label,total") {
+		t.Fatalf("unexpected csv file %q: %v", data, err)
+	}
+
+	if code := Run(context.Background(), env, []string{"export", "-format", "xml"}); code != 2 {
+		t.Fatalf("expected usage error for unknown format, got %d", code)
+	}
+}
+
+func TestRunBar(t *testing.T) {
+	env, stdout, _ := newTestEnv(t, nil)
+	env.Cache = cache.NewStore(filepath.Join(t.TempDir(), cache.CacheFileName))
+	daily := 1.5
+	if err := env.Cache.Save(cache.CostsCache{LastSuccessAt: time.Now(), TotalUsage: 9, DailyUsage: &daily}); err != nil {
+		t.Fatalf("save cache: %v", err)
+	}
+
+	if code := Run(context.Background(), env, []string{"bar", "-format", "polybar", "-warn", "1"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if stdout.String() != "%{F#e5c07b}$1.500%{F-}\n" {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestRunBarWatch(t *testing.T) {
+	env, _, _ := newTestEnv(t, nil)
+	env.Cache = cache.NewStore(filepath.Join(t.TempDir(), cache.CacheFileName))
+	pr, pw := io.Pipe()
+	env.Stdout = pw
+	lines := make(chan string, 4)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := pr.Read(buf)
+			if err != nil {
+				return
+			}
+			lines <- string(buf[:n])
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		done <- Run(ctx, env, []string{"bar", "-format", "plain", "-watch", "-interval", "10ms"})
+	}()
+	if line := <-lines; line != "n/a\n" {
+		t.Fatalf("expected no data line first, got %q", line)
+	}
+	if err := env.Cache.Save(cache.CostsCache{TotalUsage: 2}); err != nil {
+		t.Fatalf("save cache: %v", err)
+	}
+	select {
+	case line := <-lines:
+		if line != "$2.000\n" {
+			t.Fatalf("unexpected line after refresh: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a line after the cache changed")
+	}
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("expected clean exit, got %d", code)
+	}
+	pw.Close()
+}
+
+func TestRunStatusForwardsToInstance(t *testing.T) {
+	env, stdout, stderr := newTestEnv(t, nil)
+	env.Socket = filepath.Join(t.TempDir(), instance.SocketFileName)
+	if code := Run(context.Background(), env, []string{"status"}); code != 1 || !strings.Contains(stderr.String(), "not running") {
+		t.Fatalf("expected not running error, got %d %q", code, stderr.String())
+	}
+
+	srv, err := instance.Listen(env.Socket, env.Logger)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer srv.Close()
+	srv.Serve(func(_ context.Context, command string) (string, error) {
+		return "got " + command, nil
+	})
+	if code := Run(context.Background(), env, []string{"status"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if stdout.String() != "got status\n" {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestRunAutostart(t *testing.T) {
+	env, stdout, _ := newTestEnv(t, nil)
+	dir := t.TempDir()
+	env.Autostart = autostart.XDG{Exe: "/usr/bin/openrouter-costs-tray", Dir: dir}
+
+	if code := Run(context.Background(), env, []string{"autostart", "enable"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, autostart.Name+".desktop")); err != nil {
+		t.Fatalf("expected desktop entry: %v", err)
+	}
+	if code := Run(context.Background(), env, []string{"autostart", "status"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if code := Run(context.Background(), env, []string{"autostart", "disable"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if stdout.String() != "Start at login enabled\nStart at login enabled\nStart at login disabled\n" {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+	if code := Run(context.Background(), env, []string{"autostart", "toggle"}); code != 2 {
+		t.Fatalf("expected usage exit code, got %d", code)
+	}
+}
+
+func TestRunMockServer(t *testing.T) {
+	env, _, stderr := newTestEnv(t, nil)
+	pr, pw := io.Pipe()
+	env.Stdout = pw
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		done <- Run(ctx, env, []string{"mock-server", "-listen", "127.0.0.1:0", "-rate", "1"})
+	}()
+
+	line, err := bufio.NewReader(pr).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	go func() { _, _ = io.Copy(io.Discard, pr) }()
+	baseURL := strings.TrimPrefix(strings.TrimSpace(line), "mock OpenRouter API on ")
+	usage, err := openrouter.NewClient(baseURL, nil, nil).FetchUsage(context.Background(), "token")
+	if err != nil || usage.Total <= 0 {
+		t.Fatalf("expected simulated usage from %q, got %+v %v", baseURL, usage, err)
+	}
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("expected clean shutdown, got %d: %s", code, stderr.String())
+	}
+	pw.Close()
+
+	env, _, stderr = newTestEnv(t, nil)
+	if code := Run(context.Background(), env, []string{"mock-server", "-pattern", "random"}); code != 1 || !strings.Contains(stderr.String(), "unknown pattern") {
+		t.Fatalf("expected unknown pattern error, got %d %q", code, stderr.String())
+	}
+}
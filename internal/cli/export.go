@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"openrouter-costs-tray/internal/report"
+)
+
+func runExport(_ context.Context, env Env, args []string) error {
+	fs := newFlagSet("export", env)
+	from := fs.String("from", "", "first day, YYYY-MM-DD (default: first day of this month)")
+	to := fs.String("to", "", "last day, YYYY-MM-DD (default: today)")
+	format := fs.String("format", "csv", "csv, json or jsonl")
+	by := fs.String("by", string(report.None), "aggregate by none, day, week or month")
+	utc := fs.Bool("utc", false, "use UTC instead of local time")
+	output := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || !slices.Contains(report.Formats, *format) || !slices.Contains(report.Periods, *by) {
+		return errUsage
+	}
+	if env.History == nil {
+		return errors.New("history is unavailable")
+	}
+	loc := time.Local
+	if *utc {
+		loc = time.UTC
+	}
+	start, end, err := report.Range(*from, *to, loc, time.Now())
+	if err != nil {
+		return err
+	}
+	samples, err := env.History.SamplesWithBaseline(start, end)
+	if err != nil {
+		return fmt.Errorf("read history: %w", err)
+	}
+
+	opts := report.Options{From: start, To: end, Location: loc, Aggregate: report.Period(*by), Format: *format}
+	if *output == "" {
+		return report.Write(env.Stdout, samples, opts)
+	}
+	//nolint:gosec // path is given by the user running the command
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(file, samples, opts); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"openrouter-costs-tray/internal/summary"
+)
+
+func runGeneration(ctx context.Context, env Env, args []string) error {
+	fs := newFlagSet("generation", env)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	gen, err := env.Client.FetchGeneration(ctx, env.Config.Connection.Token, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+	for _, detail := range summary.GenerationDetails(gen) {
+		fmt.Fprintf(tw, "%s:\t%s\n", detail.Label, detail.Value)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"openrouter-costs-tray/internal/instance"
+)
+
+// forward returns a command that hands command to the running tray and prints
+// its reply.
+func forward(command string) func(ctx context.Context, env Env, args []string) error {
+	return func(ctx context.Context, env Env, args []string) error {
+		fs := newFlagSet(command, env)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+		if env.Socket == "" {
+			return errors.New("instance socket is unavailable")
+		}
+		out, err := instance.Send(ctx, env.Socket, command)
+		if err != nil {
+			return err
+		}
+		if out != "" {
+			_, err = fmt.Fprintln(env.Stdout, out)
+		}
+		return err
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"openrouter-costs-tray/internal/mockserver"
+)
+
+func runMockServer(ctx context.Context, env Env, args []string) error {
+	fs := newFlagSet("mock-server", env)
+	listen := fs.String("listen", "127.0.0.1:8089", "address to listen on")
+	pattern := fs.String("pattern", mockserver.Steady, "spend pattern: "+strings.Join(mockserver.Patterns, ", "))
+	rate := fs.Float64("rate", 0.5, "average spend in USD per hour")
+	limit := fs.Float64("limit", 0, "key credit limit in USD (0: none)")
+	credits := fs.Float64("credits", 1000, "purchased account credit in USD")
+	token := fs.String("token", "", "only accept this API key (default: any)")
+	fault := fs.String("fail", mockserver.FaultNone, "fail every request: "+strings.Join(mockserver.Faults, ", "))
+	slow := fs.Duration("slow", 0, "delay of slow responses (default 30s)")
+	retryAfter := fs.Duration("retry-after", 0, "Retry-After of 429 responses (default 30s)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+	srv, err := mockserver.New(mockserver.Options{
+		Pattern:     *pattern,
+		RatePerHour: *rate,
+		Limit:       *limit,
+		Credits:     *credits,
+		Token:       *token,
+		Fault:       *fault,
+		SlowDelay:   *slow,
+		RetryAfter:  *retryAfter,
+	}, env.Logger.With("component", "mock-server"))
+	if err != nil {
+		return err
+	}
+	return srv.ListenAndServe(ctx, *listen, func(addr string) {
+		fmt.Fprintf(env.Stdout, "mock OpenRouter API on http://%s%s\n", addr, mockserver.BasePath)
+		fmt.Fprintf(env.Stdout, "set connection.base_url to it; inject faults with\n  curl -X POST 'http://%s/_mock/fault?mode=429&count=3'\n", addr)
+	})
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"openrouter-costs-tray/internal/util"
 )
 
 const (
@@ -17,13 +21,41 @@ const (
 
 var PeriodOptions = []string{"5m", "15m", "30m", "1h", "3h", "6h", "12h"}
 
+var (
+	LogLevels  = []string{"debug", "info", "warn", "error"}
+	LogFormats = []string{"json", "text"}
+)
+
 type ConnectionConfig struct {
-	Token string `json:"token"`
+	Token           string `json:"token"`
+	ProvisioningKey string `json:"provisioning_key,omitempty"`
+	// BaseURL replaces the OpenRouter API URL, e.g. with an internal gateway.
+	BaseURL string `json:"base_url,omitempty"`
+	// Proxy is an http://, https:// or socks5:// URL. When empty the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply.
+	Proxy string `json:"proxy,omitempty"`
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile                string `json:"ca_file,omitempty"`
+	TimeoutSeconds        int    `json:"timeout_seconds"`
+	ConnectTimeoutSeconds int    `json:"connect_timeout_seconds"`
+}
+
+// Configured reports whether any credential is set.
+func (c ConnectionConfig) Configured() bool {
+	return c.Token != "" || c.ProvisioningKey != ""
+}
+
+// NeedsCredits reports whether an enabled output shows the account balance,
+// which costs an extra request per refresh.
+func (c Config) NeedsCredits() bool {
+	return c.MQTT.Enabled || c.Dashboard.Enabled || c.Telemetry.Enabled ||
+		c.Export.Influx.Enabled || c.Export.Textfile.Enabled
 }
 
 type UpdatesConfig struct {
 	Period        string `json:"period"`
 	UpdateOnStart bool   `json:"update_on_start"`
+	FetchActivity bool   `json:"fetch_activity"`
 }
 
 type NotificationsConfig struct {
@@ -31,24 +63,128 @@ type NotificationsConfig struct {
 	OnUpdateSpent  bool `json:"on_update_spent"`
 	OnError        bool `json:"on_error"`
 	OnStartSummary bool `json:"on_start_summary"`
+	OnKeyChange    bool `json:"on_key_change"`
+	OnPriceChange  bool `json:"on_price_change"`
+	OnStale        bool `json:"on_stale"`
+	OnSchemaDrift  bool `json:"on_schema_drift"`
+}
+
+type ModelsConfig struct {
+	Watch []string `json:"watch,omitempty"`
 }
 
+// LoggingConfig also controls rotation of the log file. A segment is rotated
+// once it exceeds MaxSizeMB or gets older than MaxAgeDays; zero disables that
+// trigger. MaxFiles old segments are kept, gzipped when Compress is set.
+// Components overrides Level per component, e.g. {"client": "debug"}; Format
+// is "json" or "text" and only applies to stdout.
 type LoggingConfig struct {
-	Level  string `json:"level"`
-	ToFile bool   `json:"to_file"`
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components,omitempty"`
+	Format     string            `json:"format"`
+	ToFile     bool              `json:"to_file"`
+	MaxSizeMB  int               `json:"max_size_mb"`
+	MaxAgeDays int               `json:"max_age_days"`
+	MaxFiles   int               `json:"max_files"`
+	Compress   bool              `json:"compress"`
+}
+
+type ProxyConfig struct {
+	Enabled        bool   `json:"enabled"`
+	Listen         string `json:"listen"`
+	ProjectHeader  string `json:"project_header"`
+	DefaultProject string `json:"default_project"`
+}
+
+// DashboardConfig serves the web dashboard. Listen must be a loopback
+// address; the dashboard has no login and refuses to start elsewhere.
+type DashboardConfig struct {
+	Enabled bool   `json:"enabled"`
+	Listen  string `json:"listen"`
+}
+
+// MQTTConfig configures publishing usage to an MQTT broker. Broker is a URL
+// such as tcp://host:1883 or ssl://host:8883.
+type MQTTConfig struct {
+	Enabled            bool   `json:"enabled"`
+	Broker             string `json:"broker"`
+	Username           string `json:"username,omitempty"`
+	Password           string `json:"password,omitempty"`
+	ClientID           string `json:"client_id,omitempty"`
+	TopicPrefix        string `json:"topic_prefix"`
+	Discovery          bool   `json:"discovery"`
+	DiscoveryPrefix    string `json:"discovery_prefix"`
+	CAFile             string `json:"ca_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// InfluxConfig writes samples as line protocol to an InfluxDB v2 write
+// endpoint at URL, or appends them to File when URL is empty.
+type InfluxConfig struct {
+	Enabled     bool   `json:"enabled"`
+	URL         string `json:"url,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Token       string `json:"token,omitempty"`
+	File        string `json:"file,omitempty"`
+	Measurement string `json:"measurement"`
+}
+
+// TextfileConfig writes a node_exporter textfile-collector .prom file.
+type TextfileConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path,omitempty"`
+}
+
+type ExportConfig struct {
+	Influx   InfluxConfig   `json:"influx"`
+	Textfile TextfileConfig `json:"textfile"`
+}
+
+// TelemetryConfig exports refresh traces and spend metrics as OTLP/HTTP
+// JSON to Endpoint, e.g. http://localhost:4318 for a local collector.
+type TelemetryConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Endpoint    string            `json:"endpoint"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ServiceName string            `json:"service_name"`
+}
+
+// Budget caps daily (UTC) spend in USD. Zero disables a cap. Above SoftCap
+// requests are rewritten to FallbackModel, above DailyCap they are rejected.
+type Budget struct {
+	DailyCap      float64 `json:"daily_cap,omitempty"`
+	SoftCap       float64 `json:"soft_cap,omitempty"`
+	FallbackModel string  `json:"fallback_model,omitempty"`
+}
+
+// BudgetsConfig is enforced by the local proxy. Key applies to requests made
+// with the configured API key.
+type BudgetsConfig struct {
+	Projects map[string]Budget `json:"projects,omitempty"`
+	Key      Budget            `json:"key"`
 }
 
 type Config struct {
 	Connection    ConnectionConfig    `json:"connection"`
 	Updates       UpdatesConfig       `json:"updates"`
 	Notifications NotificationsConfig `json:"notifications"`
+	Models        ModelsConfig        `json:"models"`
+	Proxy         ProxyConfig         `json:"proxy"`
+	Budgets       BudgetsConfig       `json:"budgets"`
+	MQTT          MQTTConfig          `json:"mqtt"`
+	Export        ExportConfig        `json:"export"`
 	Logging       LoggingConfig       `json:"logging"`
+	Telemetry     TelemetryConfig     `json:"telemetry"`
+	Dashboard     DashboardConfig     `json:"dashboard"`
 }
 
 func DefaultConfig() Config {
 	return Config{
 		Connection: ConnectionConfig{
-			Token: "",
+			Token:                 "",
+			TimeoutSeconds:        15,
+			ConnectTimeoutSeconds: 10,
 		},
 		Updates: UpdatesConfig{
 			Period:        "30m",
@@ -59,10 +195,41 @@ func DefaultConfig() Config {
 			OnUpdateSpent:  true,
 			OnError:        true,
 			OnStartSummary: false,
+			OnKeyChange:    true,
+			OnPriceChange:  true,
+			OnStale:        true,
+			OnSchemaDrift:  true,
+		},
+		Dashboard: DashboardConfig{
+			Listen: "127.0.0.1:8790",
+		},
+		Proxy: ProxyConfig{
+			Enabled:        false,
+			Listen:         "127.0.0.1:8787",
+			ProjectHeader:  "X-Project",
+			DefaultProject: "default",
+		},
+		MQTT: MQTTConfig{
+			Enabled:         false,
+			Broker:          "tcp://localhost:1883",
+			TopicPrefix:     "openrouter_costs",
+			Discovery:       true,
+			DiscoveryPrefix: "homeassistant",
+		},
+		Export: ExportConfig{
+			Influx: InfluxConfig{Measurement: "openrouter_usage"},
 		},
 		Logging: LoggingConfig{
-			Level:  "info",
-			ToFile: false,
+			Level:      "info",
+			Format:     "json",
+			ToFile:     false,
+			MaxSizeMB:  10,
+			MaxAgeDays: 7,
+			MaxFiles:   5,
+		},
+		Telemetry: TelemetryConfig{
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "openrouter-costs-tray",
 		},
 	}
 }
@@ -77,6 +244,94 @@ func Normalize(cfg *Config) {
 	if cfg.Logging.Level == "" {
 		cfg.Logging.Level = DefaultConfig().Logging.Level
 	}
+	cfg.Models.Watch = normalizeModelIDs(cfg.Models.Watch)
+	def := DefaultConfig()
+	cfg.Connection.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.Connection.BaseURL), "/")
+	cfg.Connection.Proxy = strings.TrimSpace(cfg.Connection.Proxy)
+	cfg.Connection.CAFile = strings.TrimSpace(cfg.Connection.CAFile)
+	if cfg.Connection.TimeoutSeconds <= 0 {
+		cfg.Connection.TimeoutSeconds = def.Connection.TimeoutSeconds
+	}
+	if cfg.Connection.ConnectTimeoutSeconds <= 0 {
+		cfg.Connection.ConnectTimeoutSeconds = def.Connection.ConnectTimeoutSeconds
+	}
+	if strings.TrimSpace(cfg.Proxy.Listen) == "" {
+		cfg.Proxy.Listen = def.Proxy.Listen
+	}
+	cfg.Dashboard.Listen = strings.TrimSpace(cfg.Dashboard.Listen)
+	if cfg.Dashboard.Listen == "" {
+		cfg.Dashboard.Listen = def.Dashboard.Listen
+	}
+	if strings.TrimSpace(cfg.Proxy.ProjectHeader) == "" {
+		cfg.Proxy.ProjectHeader = def.Proxy.ProjectHeader
+	}
+	if strings.TrimSpace(cfg.Proxy.DefaultProject) == "" {
+		cfg.Proxy.DefaultProject = def.Proxy.DefaultProject
+	}
+	cfg.MQTT.Broker = strings.TrimSpace(cfg.MQTT.Broker)
+	if cfg.MQTT.Broker == "" {
+		cfg.MQTT.Broker = def.MQTT.Broker
+	}
+	cfg.MQTT.TopicPrefix = strings.Trim(strings.TrimSpace(cfg.MQTT.TopicPrefix), "/")
+	if cfg.MQTT.TopicPrefix == "" {
+		cfg.MQTT.TopicPrefix = def.MQTT.TopicPrefix
+	}
+	cfg.MQTT.DiscoveryPrefix = strings.Trim(strings.TrimSpace(cfg.MQTT.DiscoveryPrefix), "/")
+	if cfg.MQTT.DiscoveryPrefix == "" {
+		cfg.MQTT.DiscoveryPrefix = def.MQTT.DiscoveryPrefix
+	}
+	if strings.TrimSpace(cfg.Export.Influx.Measurement) == "" {
+		cfg.Export.Influx.Measurement = def.Export.Influx.Measurement
+	}
+	cfg.Logging.Format = strings.ToLower(strings.TrimSpace(cfg.Logging.Format))
+	if !slices.Contains(LogFormats, cfg.Logging.Format) {
+		cfg.Logging.Format = def.Logging.Format
+	}
+	cfg.Logging.Components = normalizeComponentLevels(cfg.Logging.Components)
+	cfg.Logging.MaxSizeMB = max(cfg.Logging.MaxSizeMB, 0)
+	cfg.Logging.MaxAgeDays = max(cfg.Logging.MaxAgeDays, 0)
+	if cfg.Logging.MaxFiles < 0 {
+		cfg.Logging.MaxFiles = def.Logging.MaxFiles
+	}
+	cfg.Telemetry.Endpoint = strings.TrimRight(strings.TrimSpace(cfg.Telemetry.Endpoint), "/")
+	if cfg.Telemetry.Endpoint == "" {
+		cfg.Telemetry.Endpoint = def.Telemetry.Endpoint
+	}
+	if strings.TrimSpace(cfg.Telemetry.ServiceName) == "" {
+		cfg.Telemetry.ServiceName = def.Telemetry.ServiceName
+	}
+}
+
+// normalizeComponentLevels trims and lowercases the overrides and drops
+// unknown levels.
+func normalizeComponentLevels(levels map[string]string) map[string]string {
+	var out map[string]string
+	for name, level := range levels {
+		name = strings.TrimSpace(name)
+		level = strings.ToLower(strings.TrimSpace(level))
+		if name == "" || !slices.Contains(LogLevels, level) {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[name] = level
+	}
+	return out
+}
+
+func normalizeModelIDs(ids []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
 }
 
 func DefaultConfigDir() (string, error) {
@@ -122,7 +377,7 @@ func SaveToPath(path string, cfg Config) error {
 	if err != nil {
 		return err
 	}
-	return writeFileAtomic(path, data, 0o600)
+	return util.WriteFileAtomic(path, data, 0o600)
 }
 
 type Store struct {
@@ -187,33 +442,3 @@ func IsValidPeriod(period string) bool {
 	}
 	return false
 }
-
-func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, ".tmp-config-*")
-	if err != nil {
-		return err
-	}
-	tmpName := tmp.Name()
-	defer os.Remove(tmpName)
-	if err := tmp.Chmod(perm); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if _, err := tmp.Write(data); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if err := tmp.Sync(); err != nil {
-		_ = tmp.Close()
-		return err
-	}
-	if err := tmp.Close(); err != nil {
-		return err
-	}
-	if err := os.Rename(tmpName, path); err != nil {
-		_ = os.Remove(path)
-		return os.Rename(tmpName, path)
-	}
-	return nil
-}
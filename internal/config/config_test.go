@@ -64,6 +64,76 @@ func TestNormalizeInvalidPeriod(t *testing.T) {
 	}
 }
 
+func TestNormalizeWatchList(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Models.Watch = []string{" openai/gpt-4o ", "", "openai/gpt-4o", "anthropic/claude-3.5-sonnet"}
+	Normalize(&cfg)
+	if len(cfg.Models.Watch) != 2 || cfg.Models.Watch[0] != "openai/gpt-4o" || cfg.Models.Watch[1] != "anthropic/claude-3.5-sonnet" {
+		t.Fatalf("unexpected watch list: %q", cfg.Models.Watch)
+	}
+}
+
+func TestNormalizeLogging(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Logging.Format = " TEXT "
+	cfg.Logging.Components = map[string]string{" client ": "DEBUG", "mqtt": "verbose", "": "info"}
+	Normalize(&cfg)
+	if cfg.Logging.Format != "text" {
+		t.Fatalf("unexpected format %q", cfg.Logging.Format)
+	}
+	if len(cfg.Logging.Components) != 1 || cfg.Logging.Components["client"] != "debug" {
+		t.Fatalf("unexpected component levels %v", cfg.Logging.Components)
+	}
+	cfg.Logging.Format = "xml"
+	Normalize(&cfg)
+	if cfg.Logging.Format != "json" {
+		t.Fatalf("expected unknown format to fall back to json, got %q", cfg.Logging.Format)
+	}
+}
+
+func TestNormalizeTelemetry(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Telemetry.Endpoint = " https://otel.example.com:4318/ "
+	cfg.Telemetry.ServiceName = " "
+	Normalize(&cfg)
+	if cfg.Telemetry.Endpoint != "https://otel.example.com:4318" {
+		t.Fatalf("unexpected endpoint %q", cfg.Telemetry.Endpoint)
+	}
+	if cfg.Telemetry.ServiceName != "openrouter-costs-tray" {
+		t.Fatalf("unexpected service name %q", cfg.Telemetry.ServiceName)
+	}
+}
+
+func TestNormalizeConnection(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Connection.BaseURL = " https://gateway.corp.example/openrouter/v1/ "
+	cfg.Connection.Proxy = " socks5://proxy.corp.example:1080 "
+	cfg.Connection.TimeoutSeconds = 0
+	cfg.Connection.ConnectTimeoutSeconds = -1
+	Normalize(&cfg)
+	if cfg.Connection.BaseURL != "https://gateway.corp.example/openrouter/v1" {
+		t.Fatalf("unexpected base URL %q", cfg.Connection.BaseURL)
+	}
+	if cfg.Connection.Proxy != "socks5://proxy.corp.example:1080" {
+		t.Fatalf("unexpected proxy %q", cfg.Connection.Proxy)
+	}
+	if cfg.Connection.TimeoutSeconds != 15 || cfg.Connection.ConnectTimeoutSeconds != 10 {
+		t.Fatalf("expected default timeouts, got %d/%d", cfg.Connection.TimeoutSeconds, cfg.Connection.ConnectTimeoutSeconds)
+	}
+}
+
+func TestNormalizeDashboard(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Dashboard.Enabled || cfg.Dashboard.Listen != "127.0.0.1:8790" {
+		t.Fatalf("unexpected dashboard default %+v", cfg.Dashboard)
+	}
+	cfg.Dashboard.Listen = "  "
+	Normalize(&cfg)
+	if cfg.Dashboard.Listen != "127.0.0.1:8790" {
+		t.Fatalf("expected default listen address, got %q", cfg.Dashboard.Listen)
+	}
+}
+
 func TestStoreGetSetSave(t *testing.T) {
 	tmp := t.TempDir()
 	path := filepath.Join(tmp, ConfigFileName)
@@ -0,0 +1,145 @@
+// Package connectivity pauses polling while the API is unreachable. A refresh
+// that fails with a network error switches the Monitor offline; it then
+// probes with backoff and refreshes as soon as a probe gets an answer.
+package connectivity
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+)
+
+const (
+	minBackoff   = 5 * time.Second
+	maxBackoff   = 5 * time.Minute
+	probeTimeout = 5 * time.Second
+)
+
+// Probe checks whether the API can be reached.
+type Probe func(ctx context.Context) error
+
+// RequestProbe makes an unauthenticated API request with ping, which goes
+// through the configured transport, so proxies and CA bundles apply as they
+// do for refreshes. Any HTTP response counts as reachable.
+func RequestProbe(ping func(ctx context.Context) (int, error)) Probe {
+	return func(ctx context.Context) error {
+		_, err := ping(ctx)
+		return err
+	}
+}
+
+type Monitor struct {
+	probe    Probe
+	onOnline func()
+	logger   *slog.Logger
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	offline bool
+	// backoff is the next probe delay. Only a successful refresh resets it,
+	// so probes that pass while refreshes still fail slow down.
+	backoff time.Duration
+	stopCh  chan struct{}
+}
+
+// New returns a monitor that calls onOnline, typically a refresh, once probe
+// succeeds after going offline.
+func New(probe Probe, onOnline func(), logger *slog.Logger) *Monitor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Monitor{
+		probe:      probe,
+		onOnline:   onOnline,
+		logger:     logger,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Offline reports whether scheduled refreshes should be skipped.
+func (m *Monitor) Offline() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.offline
+}
+
+// HandleEvent goes offline on network failures and back online on success.
+func (m *Monitor) HandleEvent(ev events.Event) {
+	switch ev := ev.(type) {
+	case events.RefreshFailed:
+		if ev.Offline {
+			m.goOffline()
+		}
+	case events.RefreshSucceeded:
+		m.mu.Lock()
+		m.offline = false
+		m.backoff = 0
+		m.mu.Unlock()
+	}
+}
+
+// Stop ends probing for good.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.stopCh:
+	default:
+		close(m.stopCh)
+	}
+}
+
+func (m *Monitor) goOffline() {
+	m.mu.Lock()
+	if m.offline {
+		m.mu.Unlock()
+		return
+	}
+	m.offline = true
+	m.mu.Unlock()
+	m.logger.Info("network unreachable, pausing refreshes")
+	go m.probeLoop()
+}
+
+func (m *Monitor) probeLoop() {
+	for {
+		delay := m.nextBackoff()
+		select {
+		case <-time.After(delay):
+		case <-m.stopCh:
+			return
+		}
+		if !m.Offline() {
+			// A manual refresh got through in the meantime.
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		err := m.probe(ctx)
+		cancel()
+		if err == nil {
+			m.mu.Lock()
+			m.offline = false
+			m.mu.Unlock()
+			m.logger.Info("network is back, refreshing")
+			m.onOnline()
+			return
+		}
+		m.logger.Debug("connectivity probe failed", "error", err)
+	}
+}
+
+// nextBackoff returns the delay before the next probe and doubles it.
+func (m *Monitor) nextBackoff() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delay := max(m.backoff, m.minBackoff)
+	m.backoff = min(delay*2, m.maxBackoff)
+	return delay
+}
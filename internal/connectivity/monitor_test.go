@@ -0,0 +1,133 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func testMonitor(probe Probe, onOnline func()) *Monitor {
+	m := New(probe, onOnline, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.minBackoff = time.Millisecond
+	m.maxBackoff = 4 * time.Millisecond
+	return m
+}
+
+func TestMonitorProbesUntilOnline(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		probes int
+	)
+	online := make(chan struct{}, 2)
+	m := testMonitor(func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		probes++
+		if probes < 4 {
+			return errors.New("dial tcp: network is unreachable")
+		}
+		return nil
+	}, func() { online <- struct{}{} })
+	defer m.Stop()
+
+	m.HandleEvent(events.RefreshFailed{Err: errors.New("boom")})
+	if m.Offline() {
+		t.Fatalf("expected API errors to keep the monitor online")
+	}
+	m.HandleEvent(events.RefreshFailed{Offline: true})
+	m.HandleEvent(events.RefreshFailed{Offline: true})
+	if !m.Offline() {
+		t.Fatalf("expected offline after network failure")
+	}
+	select {
+	case <-online:
+	case <-time.After(time.Second):
+		t.Fatalf("expected onOnline after a successful probe")
+	}
+	if m.Offline() {
+		t.Fatalf("expected online after successful probe")
+	}
+	select {
+	case <-online:
+		t.Fatalf("expected a single probe loop")
+	case <-time.After(20 * time.Millisecond):
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if probes != 4 {
+		t.Fatalf("expected 4 probes, got %d", probes)
+	}
+}
+
+func TestMonitorStopsProbingAfterSuccess(t *testing.T) {
+	probed := make(chan struct{}, 10)
+	m := testMonitor(func(context.Context) error {
+		probed <- struct{}{}
+		return errors.New("offline")
+	}, func() { t.Errorf("unexpected onOnline") })
+	m.minBackoff = 20 * time.Millisecond
+	defer m.Stop()
+
+	m.HandleEvent(events.RefreshFailed{Offline: true})
+	m.HandleEvent(events.RefreshSucceeded{})
+	if m.Offline() {
+		t.Fatalf("expected online after a successful refresh")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(probed) != 0 {
+		t.Fatalf("expected probing to stop once a refresh succeeded")
+	}
+}
+
+func TestBackoffKeptAcrossOfflineCycles(t *testing.T) {
+	online := make(chan struct{}, 1)
+	m := testMonitor(func(context.Context) error { return nil }, func() { online <- struct{}{} })
+	m.maxBackoff = 8 * time.Millisecond
+	defer m.Stop()
+	backoff := func() time.Duration {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.backoff
+	}
+
+	// The probe passes but the refresh it triggers fails offline again.
+	for _, want := range []time.Duration{2, 4, 8, 8} {
+		m.HandleEvent(events.RefreshFailed{Offline: true})
+		select {
+		case <-online:
+		case <-time.After(time.Second):
+			t.Fatalf("expected onOnline after a successful probe")
+		}
+		if got := backoff(); got != want*time.Millisecond {
+			t.Fatalf("expected next backoff %v, got %v", want*time.Millisecond, got)
+		}
+	}
+	m.HandleEvent(events.RefreshSucceeded{})
+	if got := backoff(); got != 0 {
+		t.Fatalf("expected a successful refresh to reset the backoff, got %v", got)
+	}
+}
+
+func TestRequestProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	client := openrouter.NewClient(srv.URL, srv.Client(), nil)
+	probe := RequestProbe(client.Ping)
+	if err := probe(context.Background()); err != nil {
+		t.Fatalf("expected any HTTP answer to count: %v", err)
+	}
+	srv.Close()
+	if err := probe(context.Background()); err == nil {
+		t.Fatalf("expected probe to fail on a closed server")
+	}
+}
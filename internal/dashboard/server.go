@@ -0,0 +1,527 @@
+// Package dashboard serves a single-page web dashboard of the tray's state.
+// The page subscribes to /api/events and re-renders on every refresh.
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/redact"
+	"openrouter-costs-tray/internal/report"
+	"openrouter-costs-tray/internal/state"
+)
+
+const (
+	// maxAttempts is how many refresh attempts the page lists.
+	maxAttempts = 20
+	// historyDays is the length of the daily spend chart.
+	historyDays = 30
+	// keepAlive is how often an idle event stream gets a comment, so proxies
+	// and browsers do not time it out.
+	keepAlive = 30 * time.Second
+	// actionHeader must be sent with POSTs. Browsers only add custom headers
+	// after a CORS preflight, which this server never grants, so other sites
+	// cannot trigger actions.
+	actionHeader = "X-Dashboard"
+
+	refreshTimeout = 20 * time.Second
+)
+
+//go:embed assets/index.html
+var indexHTML []byte
+
+// Deps are the parts of the app the dashboard reads and drives.
+type Deps struct {
+	State   *state.State
+	Config  *config.Store
+	History *history.Store
+	// Refresh runs a refresh, as the tray menu does.
+	Refresh func(ctx context.Context) error
+	// OpenSettings shows the settings window on the desktop.
+	OpenSettings func()
+	Logger       *slog.Logger
+}
+
+// Attempt is one refresh as listed on the page.
+type Attempt struct {
+	At       time.Time `json:"at"`
+	Result   string    `json:"result"`
+	Duration float64   `json:"duration_seconds,omitempty"`
+	Delta    float64   `json:"delta,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Server is the dashboard HTTP server. Apply starts and stops it as the
+// config changes.
+type Server struct {
+	deps   Deps
+	logger *slog.Logger
+	mux    *http.ServeMux
+	now    func() time.Time
+
+	mu         sync.Mutex
+	server     *http.Server
+	listener   net.Listener
+	applied    config.DashboardConfig
+	done       chan struct{}
+	clients    map[chan []byte]struct{}
+	attempts   []Attempt
+	startedAt  time.Time
+	refreshing bool
+}
+
+// New returns a stopped server; Apply starts it.
+func New(deps Deps) *Server {
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &Server{
+		deps:    deps,
+		logger:  logger,
+		now:     time.Now,
+		clients: map[chan []byte]struct{}{},
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/api/status", s.handleStatus)
+	s.mux.HandleFunc("/api/history", s.handleHistory)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/refresh", s.action(s.handleRefresh))
+	s.mux.HandleFunc("/api/settings", s.action(s.handleSettings))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !hostAllowed(r.Host) {
+		http.Error(w, "forbidden host", http.StatusForbidden)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Apply starts, stops or moves the server to match cfg.
+func (s *Server) Apply(cfg config.DashboardConfig) {
+	s.mu.Lock()
+	if s.server != nil && cfg == s.applied {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applied = cfg
+	if !cfg.Enabled {
+		return
+	}
+	// The API is unauthenticated, so it is never exposed to the network.
+	if err := CheckListen(cfg.Listen); err != nil {
+		s.logger.Error("dashboard not started", "error", err, "addr", cfg.Listen)
+		return
+	}
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		s.logger.Error("dashboard start failed", "error", err, "addr", cfg.Listen)
+		return
+	}
+	server := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.server = server
+	s.listener = listener
+	s.done = make(chan struct{})
+	s.logger.Info("dashboard started", "url", "http://"+listener.Addr().String()+"/")
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("dashboard stopped", "error", err)
+		}
+	}()
+}
+
+// Addr returns the listening address, or "" when stopped.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop closes open event streams and shuts the server down.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	server, done := s.server, s.done
+	s.server, s.listener, s.done = nil, nil, nil
+	s.mu.Unlock()
+	if server == nil {
+		return
+	}
+	// Event streams never end on their own; Shutdown would wait for them.
+	close(done)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		s.logger.Warn("dashboard stop failed", "error", err)
+	}
+	s.logger.Info("dashboard stopped")
+}
+
+// HandleEvent records refresh attempts and pushes the new status to open
+// pages.
+func (s *Server) HandleEvent(ev events.Event) {
+	switch e := ev.(type) {
+	case events.RefreshStarted:
+		s.mu.Lock()
+		s.refreshing, s.startedAt = true, e.At
+		s.mu.Unlock()
+	case events.RefreshSucceeded:
+		s.record(Attempt{At: e.At, Result: "ok", Delta: e.Delta})
+	case events.RefreshFailed:
+		attempt := Attempt{At: e.At, Result: "error"}
+		if e.Offline {
+			attempt.Result = "offline"
+		}
+		if e.Err != nil {
+			attempt.Error = redact.String(e.Err.Error(), redact.ConfigSecrets(s.config())...)
+		}
+		s.record(attempt)
+	case events.NotConfigured:
+		s.mu.Lock()
+		s.refreshing = false
+		s.mu.Unlock()
+	case events.ConfigChanged:
+		s.Apply(e.Config.Dashboard)
+	case events.StalenessChanged, events.SchemaDrift, events.ProjectCostRecorded:
+	default:
+		return
+	}
+	s.broadcast()
+}
+
+func (s *Server) record(attempt Attempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshing && !s.startedAt.IsZero() {
+		attempt.Duration = attempt.At.Sub(s.startedAt).Seconds()
+	}
+	s.refreshing = false
+	s.attempts = append(s.attempts, attempt)
+	if len(s.attempts) > maxAttempts {
+		s.attempts = s.attempts[len(s.attempts)-maxAttempts:]
+	}
+}
+
+func (s *Server) config() config.Config {
+	if s.deps.Config == nil {
+		return config.DefaultConfig()
+	}
+	return s.deps.Config.Get()
+}
+
+// broadcast sends the current status to every open event stream. Slow
+// clients miss updates rather than block the bus.
+func (s *Server) broadcast() {
+	s.mu.Lock()
+	if len(s.clients) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	payload, err := json.Marshal(s.status())
+	if err != nil {
+		s.logger.Warn("dashboard status encode failed", "error", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
+
+// CheckListen reports an error unless listen is a loopback host and port.
+func CheckListen(listen string) error {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("dashboard listen address: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("dashboard listen address %q is not on loopback", listen)
+	}
+	return nil
+}
+
+// hostAllowed rejects DNS rebinding: only "localhost" and IP literals are
+// accepted as Host.
+func hostAllowed(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return host == "localhost" || strings.HasSuffix(host, ".localhost") || net.ParseIP(host) != nil
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(indexHTML)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleEvents streams a "status" event now and after every change.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan []byte, 4)
+	s.mu.Lock()
+	done := s.done
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	payload, err := json.Marshal(s.status())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeEvent(w, payload)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case payload := <-ch:
+			writeEvent(w, payload)
+		case <-ticker.C:
+			_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, payload []byte) {
+	_, _ = fmt.Fprintf(w, "event: status\ndata: %s\n\n", payload)
+}
+
+// action wraps a POST-only endpoint that changes something.
+func (s *Server) action(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		if r.Header.Get(actionHeader) == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "missing " + actionHeader + " header"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.deps.Refresh == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "refresh unavailable"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), refreshTimeout)
+	defer cancel()
+	if err := s.deps.Refresh(ctx); err != nil {
+		msg := redact.String(err.Error(), redact.ConfigSecrets(s.config())...)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": msg})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *Server) handleSettings(w http.ResponseWriter, _ *http.Request) {
+	if s.deps.OpenSettings == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "settings unavailable"})
+		return
+	}
+	s.deps.OpenSettings()
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// historyJSON is the daily spend of all keys over the last historyDays days
+// and the running total of the current month, in local days.
+type historyJSON struct {
+	From      string    `json:"from"`
+	Daily     []float64 `json:"daily"`
+	MonthFrom string    `json:"month_from"`
+	Month     []float64 `json:"month"`
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, _ *http.Request) {
+	if s.deps.History == nil {
+		writeJSON(w, http.StatusOK, historyJSON{})
+		return
+	}
+	now := s.now()
+	// Samples before the window are the baseline of the first increase.
+	samples, err := s.deps.History.Samples(now.AddDate(0, 0, -(historyDays+31)), time.Time{})
+	if err != nil {
+		s.logger.Warn("spend samples load failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "history unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, buildHistory(samples, now, time.Local))
+}
+
+func buildHistory(samples []history.SpendSample, now time.Time, loc *time.Location) historyJSON {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := today.AddDate(0, 0, -(historyDays - 1))
+	monthFrom := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	increases := report.Increases(samples)
+	return historyJSON{
+		From:      from.Format(time.DateOnly),
+		Daily:     report.DailySpend(increases, "", from, historyDays, loc),
+		MonthFrom: monthFrom.Format(time.DateOnly),
+		Month:     report.Cumulative(report.DailySpend(increases, "", monthFrom, now.Day(), loc)),
+	}
+}
+
+type usageJSON struct {
+	Label          string   `json:"label,omitempty"`
+	Total          float64  `json:"total"`
+	Daily          *float64 `json:"daily"`
+	Weekly         *float64 `json:"weekly"`
+	Monthly        *float64 `json:"monthly"`
+	Limit          *float64 `json:"limit"`
+	LimitRemaining *float64 `json:"limit_remaining"`
+}
+
+type creditsJSON struct {
+	Total     float64 `json:"total"`
+	Used      float64 `json:"used"`
+	Remaining float64 `json:"remaining"`
+}
+
+type keyJSON struct {
+	Name     string   `json:"name"`
+	Disabled bool     `json:"disabled,omitempty"`
+	Total    float64  `json:"total"`
+	Daily    *float64 `json:"daily"`
+	Monthly  *float64 `json:"monthly"`
+	Limit    *float64 `json:"limit"`
+}
+
+type statusJSON struct {
+	Configured bool                `json:"configured"`
+	UpdatedAt  *time.Time          `json:"updated_at"`
+	Staleness  string              `json:"staleness"`
+	Refreshing bool                `json:"refreshing"`
+	Offline    bool                `json:"offline"`
+	Error      string              `json:"error,omitempty"`
+	Usage      usageJSON           `json:"usage"`
+	Credits    *creditsJSON        `json:"credits"`
+	Keys       []keyJSON           `json:"keys"`
+	Projects   map[string]float64  `json:"projects,omitempty"`
+	Drift      map[string][]string `json:"drift,omitempty"`
+	Attempts   []Attempt           `json:"attempts"`
+}
+
+// status renders the state shown on the page. Attempts are newest first.
+func (s *Server) status() statusJSON {
+	cfg := s.config()
+	var snap state.Snapshot
+	if s.deps.State != nil {
+		snap = s.deps.State.Snapshot()
+	}
+	out := statusJSON{
+		Configured: cfg.Connection.Configured() && !snap.NotConfigured,
+		Staleness:  snap.Staleness.String(),
+		Offline:    snap.Offline,
+		Error:      snap.LastError,
+		Usage: usageJSON{
+			Label:          snap.Usage.Label,
+			Total:          snap.Usage.Total,
+			Daily:          snap.Usage.Daily,
+			Weekly:         snap.Usage.Weekly,
+			Monthly:        snap.Usage.Monthly,
+			Limit:          snap.Usage.Limit,
+			LimitRemaining: snap.Usage.LimitRemaining,
+		},
+		Keys:     []keyJSON{},
+		Projects: snap.Projects,
+		Drift:    snap.Drift,
+	}
+	if !snap.LastSuccessAt.IsZero() {
+		at := snap.LastSuccessAt
+		out.UpdatedAt = &at
+	}
+	if snap.Credits != nil {
+		out.Credits = &creditsJSON{Total: snap.Credits.Total, Used: snap.Credits.Usage, Remaining: snap.Credits.Remaining()}
+	}
+	for _, key := range snap.Keys {
+		name := key.Name
+		if name == "" {
+			name = key.Label
+		}
+		out.Keys = append(out.Keys, keyJSON{
+			Name:     name,
+			Disabled: key.Disabled,
+			Total:    key.Usage.Total,
+			Daily:    key.Usage.Daily,
+			Monthly:  key.Usage.Monthly,
+			Limit:    key.Usage.Limit,
+		})
+	}
+	sort.SliceStable(out.Keys, func(i, j int) bool { return out.Keys[i].Total > out.Keys[j].Total })
+
+	s.mu.Lock()
+	out.Refreshing = s.refreshing
+	out.Attempts = make([]Attempt, 0, len(s.attempts))
+	for i := len(s.attempts) - 1; i >= 0; i-- {
+		out.Attempts = append(out.Attempts, s.attempts[i])
+	}
+	s.mu.Unlock()
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,280 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/state"
+)
+
+func newTestServer(t *testing.T, deps Deps) *Server {
+	t.Helper()
+	if deps.State == nil {
+		deps.State = state.New()
+	}
+	if deps.Config == nil {
+		cfg := config.DefaultConfig()
+		cfg.Connection.Token = "sk-or-secret"
+		deps.Config = config.NewStore("", cfg)
+	}
+	deps.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(deps)
+}
+
+// request is a test request addressed to localhost, which the host check
+// accepts.
+func request(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = "localhost:8790"
+	return req
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestStatus(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	at := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	s.deps.State.SetSuccess(openrouter.Usage{Label: "main", Total: 12.5, Daily: ptr(1.5), Limit: ptr(20)}, at)
+	s.deps.State.SetKeys([]openrouter.KeyInfo{
+		{Name: "small", Usage: openrouter.Usage{Total: 1}},
+		{Name: "big", Usage: openrouter.Usage{Total: 10}},
+	})
+	s.HandleEvent(events.RefreshStarted{At: at.Add(-2 * time.Second)})
+	s.HandleEvent(events.RefreshSucceeded{At: at, Delta: 0.25})
+	s.HandleEvent(events.RefreshFailed{At: at.Add(time.Minute), Err: errors.New("bad key sk-or-secret")})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, request(http.MethodGet, "/api/status"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+	var got statusJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !got.Configured || got.UpdatedAt == nil || !got.UpdatedAt.Equal(at) || got.Usage.Total != 12.5 || *got.Usage.Limit != 20 {
+		t.Fatalf("unexpected status %+v", got)
+	}
+	if len(got.Keys) != 2 || got.Keys[0].Name != "big" {
+		t.Fatalf("expected keys by spend, got %+v", got.Keys)
+	}
+	if len(got.Attempts) != 2 || got.Attempts[0].Result != "error" || got.Attempts[1].Duration != 2 || got.Attempts[1].Delta != 0.25 {
+		t.Fatalf("unexpected attempts %+v", got.Attempts)
+	}
+	if strings.Contains(got.Attempts[0].Error, "sk-or-secret") {
+		t.Fatalf("expected the token to be redacted, got %q", got.Attempts[0].Error)
+	}
+}
+
+func TestAttemptsAreCapped(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	start := time.Now()
+	for i := 0; i < maxAttempts+5; i++ {
+		s.HandleEvent(events.RefreshSucceeded{At: start.Add(time.Duration(i) * time.Minute)})
+	}
+	got := s.status().Attempts
+	if len(got) != maxAttempts || !got[0].At.Equal(start.Add(time.Duration(maxAttempts+4)*time.Minute)) {
+		t.Fatalf("expected the newest %d attempts, got %d starting %v", maxAttempts, len(got), got[0].At)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events", nil)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				lines <- data
+			}
+		}
+		close(lines)
+	}()
+	next := func() statusJSON {
+		t.Helper()
+		select {
+		case data, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream ended")
+			}
+			var st statusJSON
+			if err := json.Unmarshal([]byte(data), &st); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			return st
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event")
+		}
+		return statusJSON{}
+	}
+
+	if first := next(); len(first.Attempts) != 0 {
+		t.Fatalf("unexpected initial status %+v", first)
+	}
+	s.HandleEvent(events.RefreshSucceeded{At: time.Now(), Delta: 1})
+	if st := next(); len(st.Attempts) != 1 || st.Attempts[0].Result != "ok" {
+		t.Fatalf("expected the refresh to be pushed, got %+v", st)
+	}
+}
+
+func TestActions(t *testing.T) {
+	var refreshed, opened int
+	s := newTestServer(t, Deps{
+		Refresh:      func(context.Context) error { refreshed++; return nil },
+		OpenSettings: func() { opened++ },
+	})
+	cases := []struct {
+		method, path, header string
+		want                 int
+	}{
+		{http.MethodGet, "/api/refresh", "1", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/refresh", "", http.StatusForbidden},
+		{http.MethodPost, "/api/refresh", "1", http.StatusOK},
+		{http.MethodPost, "/api/settings", "1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := request(tc.method, tc.path)
+		if tc.header != "" {
+			req.Header.Set(actionHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, rec.Code)
+		}
+	}
+	if refreshed != 1 || opened != 1 {
+		t.Fatalf("expected one refresh and one settings call, got %d and %d", refreshed, opened)
+	}
+
+	s = newTestServer(t, Deps{Refresh: func(context.Context) error { return errors.New("token sk-or-secret rejected") }})
+	req := request(http.MethodPost, "/api/refresh")
+	req.Header.Set(actionHeader, "1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadGateway || strings.Contains(rec.Body.String(), "sk-or-secret") {
+		t.Fatalf("expected a redacted failure, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	for host, want := range map[string]bool{
+		"localhost:8790":      true,
+		"app.localhost:8790":  true,
+		"127.0.0.1:8790":      true,
+		"[::1]:8790":          true,
+		"evil.example.com":    false,
+		"evil.example.com:80": false,
+	} {
+		req := request(http.MethodGet, "/")
+		req.Host = host
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if got := rec.Code == http.StatusOK; got != want {
+			t.Fatalf("host %q: expected allowed=%v, got status %d", host, want, rec.Code)
+		}
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2025, 3, 3, 15, 0, 0, 0, loc)
+	samples := []history.SpendSample{
+		{At: time.Date(2025, 2, 28, 10, 0, 0, 0, loc), KeyID: "a", Total: 1},
+		{At: time.Date(2025, 3, 1, 10, 0, 0, 0, loc), KeyID: "a", Total: 3},
+		{At: time.Date(2025, 3, 3, 10, 0, 0, 0, loc), KeyID: "a", Total: 4},
+		{At: time.Date(2025, 3, 3, 11, 0, 0, 0, loc), KeyID: "b", Total: 7},
+		{At: time.Date(2025, 3, 3, 12, 0, 0, 0, loc), KeyID: "b", Total: 7.5},
+	}
+	got := buildHistory(samples, now, loc)
+	if got.From != "2025-02-02" || len(got.Daily) != historyDays || got.Daily[historyDays-1] != 1.5 || got.Daily[historyDays-3] != 2 {
+		t.Fatalf("unexpected daily history %+v", got)
+	}
+	if got.MonthFrom != "2025-03-01" || len(got.Month) != 3 || got.Month[0] != 2 || got.Month[2] != 3.5 {
+		t.Fatalf("unexpected month history %+v", got)
+	}
+}
+
+func TestApplyAndStop(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	s.Apply(config.DashboardConfig{Enabled: true, Listen: "127.0.0.1:0"})
+	addr := s.Addr()
+	if addr == "" {
+		t.Fatalf("expected the server to listen")
+	}
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "EventSource") {
+		t.Fatalf("unexpected index %d", resp.StatusCode)
+	}
+
+	// An open event stream must not hold up the shutdown.
+	stream, err := http.Get("http://" + addr + "/api/events")
+	if err != nil {
+		t.Fatalf("get events: %v", err)
+	}
+	defer stream.Body.Close()
+	s.Apply(config.DashboardConfig{Enabled: false, Listen: "127.0.0.1:0"})
+	if s.Addr() != "" {
+		t.Fatalf("expected the server to stop")
+	}
+	if _, err := http.Get("http://" + addr + "/"); err == nil {
+		t.Fatalf("expected the port to be closed")
+	}
+}
+
+func TestRefusesNonLoopback(t *testing.T) {
+	for listen, ok := range map[string]bool{
+		"127.0.0.1:8790":     true,
+		"[::1]:8790":         true,
+		"localhost:8790":     true,
+		"0.0.0.0:8790":       false,
+		":8790":              false,
+		"192.168.1.10:8790":  false,
+		"dashboard.lan:8790": false,
+		"127.0.0.1":          false,
+	} {
+		if err := CheckListen(listen); (err == nil) != ok {
+			t.Fatalf("%q: expected ok=%v, got %v", listen, ok, err)
+		}
+	}
+
+	s := newTestServer(t, Deps{})
+	s.Apply(config.DashboardConfig{Enabled: true, Listen: "0.0.0.0:0"})
+	if addr := s.Addr(); addr != "" {
+		s.Stop()
+		t.Fatalf("expected a wildcard address to be refused, listening on %s", addr)
+	}
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuffer is the subscription buffer size used when a non-positive size is requested.
+const DefaultBuffer = 16
+
+// Bus fans out events to subscribers without ever blocking the publisher.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	logger *slog.Logger
+}
+
+// Subscription receives events on a buffered channel until closed.
+type Subscription struct {
+	bus     *Bus
+	ch      chan Event
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Bus{subs: map[*Subscription]struct{}{}, logger: logger}
+}
+
+// Subscribe registers a channel subscriber. Events that do not fit in the
+// buffer are dropped for that subscriber only.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	sub := &Subscription{bus: b, ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Listen subscribes and calls fn for every event on a dedicated goroutine.
+func (b *Bus) Listen(buffer int, fn func(Event)) *Subscription {
+	sub := b.Subscribe(buffer)
+	go func() {
+		for ev := range sub.ch {
+			fn(ev)
+		}
+	}()
+	return sub
+}
+
+// On subscribes fn to events of type T only.
+func On[T Event](b *Bus, buffer int, fn func(T)) *Subscription {
+	return b.Listen(buffer, func(ev Event) {
+		if typed, ok := ev.(T); ok {
+			fn(typed)
+		}
+	})
+}
+
+// Publish delivers ev to every subscriber that has room in its buffer.
+func (b *Bus) Publish(ev Event) {
+	if b == nil || ev == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+			b.logger.Warn("event dropped: subscriber buffer full", "event", eventName(ev))
+		}
+	}
+}
+
+// C returns the channel events are delivered on. It is closed by Close.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Dropped reports how many events did not fit into the buffer.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the delivery channel.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		close(s.ch)
+		s.bus.mu.Unlock()
+	})
+}
+
+func eventName(ev Event) string {
+	switch ev.(type) {
+	case RefreshStarted:
+		return "refresh_started"
+	case RefreshSucceeded:
+		return "refresh_succeeded"
+	case RefreshFailed:
+		return "refresh_failed"
+	case ConfigChanged:
+		return "config_changed"
+	case NotConfigured:
+		return "not_configured"
+	case KeysChanged:
+		return "keys_changed"
+	case PricesChanged:
+		return "prices_changed"
+	case ProjectCostRecorded:
+		return "project_cost_recorded"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestBus() *Bus {
+	return NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestPublishDeliversToAllSubscribers(t *testing.T) {
+	bus := newTestBus()
+	first := bus.Subscribe(1)
+	second := bus.Subscribe(1)
+
+	bus.Publish(RefreshStarted{})
+
+	for i, sub := range []*Subscription{first, second} {
+		select {
+		case ev := <-sub.C():
+			if _, ok := ev.(RefreshStarted); !ok {
+				t.Fatalf("subscriber %d: unexpected event %T", i, ev)
+			}
+		default:
+			t.Fatalf("subscriber %d: expected event", i)
+		}
+	}
+}
+
+func TestPublishDoesNotBlockWhenBufferFull(t *testing.T) {
+	bus := newTestBus()
+	sub := bus.Subscribe(1)
+
+	done := make(chan struct{})
+	go func() {
+		bus.Publish(RefreshStarted{})
+		bus.Publish(RefreshStarted{})
+		bus.Publish(RefreshStarted{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("publish blocked on full subscriber")
+	}
+	if got := sub.Dropped(); got != 2 {
+		t.Fatalf("expected 2 dropped events, got %d", got)
+	}
+}
+
+func TestCloseStopsDelivery(t *testing.T) {
+	bus := newTestBus()
+	sub := bus.Subscribe(1)
+	sub.Close()
+	sub.Close()
+
+	bus.Publish(RefreshStarted{})
+	if _, ok := <-sub.C(); ok {
+		t.Fatalf("expected closed channel")
+	}
+}
+
+func TestOnFiltersByType(t *testing.T) {
+	bus := newTestBus()
+	got := make(chan RefreshFailed, 1)
+	sub := On(bus, 4, func(ev RefreshFailed) {
+		got <- ev
+	})
+	defer sub.Close()
+
+	bus.Publish(RefreshStarted{})
+	bus.Publish(RefreshFailed{Err: errors.New("boom")})
+
+	select {
+	case ev := <-got:
+		if ev.Err == nil || ev.Err.Error() != "boom" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected typed event")
+	}
+	select {
+	case ev := <-got:
+		t.Fatalf("unexpected extra event: %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
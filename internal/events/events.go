@@ -0,0 +1,95 @@
+package events
+
+import (
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
+	"openrouter-costs-tray/internal/state"
+)
+
+// Event is implemented by every message published on the Bus.
+type Event interface {
+	event()
+}
+
+// RefreshStarted is published when a configured refresh begins.
+type RefreshStarted struct {
+	At time.Time
+}
+
+// RefreshSucceeded carries the fetched usage and the spend since the previous
+// refresh. Keys is set in provisioning mode; Credits is nil when the balance
+// could not be fetched.
+type RefreshSucceeded struct {
+	At      time.Time
+	Usage   openrouter.Usage
+	Delta   float64
+	Keys    []openrouter.KeyInfo
+	Credits *openrouter.Credits
+}
+
+// RefreshFailed carries the error of a failed refresh. Offline is set when
+// the API could not be reached at all, e.g. without a network.
+type RefreshFailed struct {
+	At      time.Time
+	Err     error
+	Offline bool
+}
+
+// ConfigChanged is published after a new config has been saved and applied.
+type ConfigChanged struct {
+	Config config.Config
+}
+
+// NotConfigured is published when a refresh is skipped because no token is set.
+type NotConfigured struct {
+	At time.Time
+}
+
+// KeysChanged is published when provisioning mode sees new, removed or newly
+// disabled keys.
+type KeysChanged struct {
+	Added    []openrouter.KeyInfo
+	Removed  []openrouter.KeyInfo
+	Disabled []openrouter.KeyInfo
+}
+
+// PricesChanged is published when a watched model gets pricier, gains a free
+// variant or disappears from the catalogue.
+type PricesChanged struct {
+	Changes []pricing.Change
+}
+
+// ProjectCostRecorded is published when the local proxy attributes a request's cost.
+type ProjectCostRecorded struct {
+	Cost history.ProjectCost
+}
+
+// StalenessChanged is published when the age of the shown data crosses a
+// staleness level, including the return to fresh after a refresh.
+type StalenessChanged struct {
+	At            time.Time
+	Level         state.Staleness
+	LastSuccessAt time.Time
+}
+
+// SchemaDrift is published when an API response starts or stops deviating
+// from the schema the client knows.
+type SchemaDrift struct {
+	Endpoint string
+	Issues   []string
+}
+
+func (RefreshStarted) event()      {}
+func (RefreshSucceeded) event()    {}
+func (RefreshFailed) event()       {}
+func (ConfigChanged) event()       {}
+func (NotConfigured) event()       {}
+func (KeysChanged) event()         {}
+func (PricesChanged) event()       {}
+func (ProjectCostRecorded) event() {}
+func (StalenessChanged) event()    {}
+func (SchemaDrift) event()         {}
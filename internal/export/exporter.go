@@ -0,0 +1,70 @@
+package export
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+)
+
+// Exporter writes every successful refresh to the enabled time-series outputs.
+type Exporter struct {
+	http   *http.Client
+	logger *slog.Logger
+	mu     sync.RWMutex
+	cfg    config.ExportConfig
+}
+
+func NewExporter(cfg config.ExportConfig, httpClient *http.Client, logger *slog.Logger) *Exporter {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Exporter{http: httpClient, logger: logger, cfg: cfg}
+}
+
+func (e *Exporter) UpdateConfig(cfg config.ExportConfig) {
+	e.mu.Lock()
+	e.cfg = cfg
+	e.mu.Unlock()
+}
+
+// HandleEvent exports refresh results and applies config changes.
+func (e *Exporter) HandleEvent(ev events.Event) {
+	switch ev := ev.(type) {
+	case events.RefreshSucceeded:
+		e.Export(SampleFromEvent(ev))
+	case events.ConfigChanged:
+		e.UpdateConfig(ev.Config.Export)
+	}
+}
+
+// Export writes s to every enabled output. Failures are logged only.
+func (e *Exporter) Export(s Sample) {
+	e.mu.RLock()
+	cfg := e.cfg
+	e.mu.RUnlock()
+	if cfg.Influx.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := WriteInflux(ctx, e.http, cfg.Influx, LineProtocol(cfg.Influx.Measurement, s))
+		cancel()
+		if err != nil {
+			e.logger.Warn("influx export failed", "error", err)
+		} else {
+			e.logger.Debug("influx export written")
+		}
+	}
+	if cfg.Textfile.Enabled {
+		if err := WriteTextfile(cfg.Textfile.Path, s); err != nil {
+			e.logger.Warn("textfile export failed", "error", err, "path", cfg.Textfile.Path)
+		} else {
+			e.logger.Debug("textfile export written", "path", cfg.Textfile.Path)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestExporterHandleEvent(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewExporter(config.ExportConfig{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	refresh := events.RefreshSucceeded{
+		At:      time.Unix(1714644000, 0),
+		Usage:   openrouter.Usage{Total: 3},
+		Credits: &openrouter.Credits{Total: 10, Usage: 3},
+	}
+
+	exporter.HandleEvent(refresh)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected nothing written while disabled")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Export.Influx.Enabled = true
+	cfg.Export.Influx.File = filepath.Join(dir, "usage.lp")
+	cfg.Export.Textfile.Enabled = true
+	cfg.Export.Textfile.Path = filepath.Join(dir, "openrouter.prom")
+	exporter.HandleEvent(events.ConfigChanged{Config: cfg})
+	exporter.HandleEvent(refresh)
+
+	line, err := os.ReadFile(cfg.Export.Influx.File)
+	if err != nil || string(line) != "openrouter_usage total=3,remaining_credit=7 1714644000\n" {
+		t.Fatalf("unexpected influx file %q: %v", line, err)
+	}
+	prom, err := os.ReadFile(cfg.Export.Textfile.Path)
+	if err != nil || !strings.Contains(string(prom), "openrouter_remaining_credit_dollars 7\n") {
+		t.Fatalf("unexpected textfile %q: %v", prom, err)
+	}
+}
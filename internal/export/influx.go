@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// LineProtocol formats s as one InfluxDB line with second precision.
+func LineProtocol(measurement string, s Sample) string {
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurement))
+	if s.Key != "" {
+		b.WriteString(",key=")
+		b.WriteString(tagEscaper.Replace(s.Key))
+	}
+	for i, f := range s.fields() {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(f.name)
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(s.At.Unix(), 10))
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// WriteInflux sends line to an InfluxDB v2 write endpoint, or appends it to
+// cfg.File when no URL is set.
+func WriteInflux(ctx context.Context, client *http.Client, cfg config.InfluxConfig, line string) error {
+	if cfg.URL == "" {
+		return appendFile(cfg.File, line)
+	}
+	query := url.Values{"org": {cfg.Org}, "bucket": {cfg.Bucket}, "precision": {"s"}}
+	endpoint := strings.TrimRight(cfg.URL, "/") + "/api/v2/write?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+cfg.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("influx write failed: status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func appendFile(path, line string) error {
+	if path == "" {
+		return fmt.Errorf("influx file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	//nolint:gosec // path comes from config
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+func testSample() Sample {
+	daily := 1.5
+	credit := 7.25
+	return Sample{
+		At:              time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+		Key:             "ci key,1",
+		Total:           12.5,
+		Daily:           &daily,
+		RemainingCredit: &credit,
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	got := LineProtocol("openrouter usage", testSample())
+	want := "openrouter\\ usage,key=ci\\ key\\,1 total=12.5,daily=1.5,remaining_credit=7.25 1714644000\n"
+	if got != want {
+		t.Fatalf("unexpected line:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteInfluxHTTP(t *testing.T) {
+	var body, auth, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/write" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		body, auth, query = string(data), r.Header.Get("Authorization"), r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.InfluxConfig{URL: srv.URL + "/", Org: "acme", Bucket: "costs", Token: "tok"}
+	if err := WriteInflux(context.Background(), srv.Client(), cfg, "m total=1 1\n"); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if body != "m total=1 1\n" || auth != "Token tok" {
+		t.Fatalf("unexpected request: body %q auth %q", body, auth)
+	}
+	if !strings.Contains(query, "bucket=costs") || !strings.Contains(query, "org=acme") || !strings.Contains(query, "precision=s") {
+		t.Fatalf("unexpected query: %s", query)
+	}
+}
+
+func TestWriteInfluxHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"unauthorized access"}`))
+	}))
+	defer srv.Close()
+
+	err := WriteInflux(context.Background(), srv.Client(), config.InfluxConfig{URL: srv.URL}, "m total=1 1\n")
+	if err == nil || !strings.Contains(err.Error(), "unauthorized access") {
+		t.Fatalf("expected error with body, got %v", err)
+	}
+}
+
+func TestWriteInfluxFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "influx", "usage.lp")
+	cfg := config.InfluxConfig{File: path}
+	for _, line := range []string{"m total=1 1\n", "m total=2 2\n"} {
+		if err := WriteInflux(context.Background(), nil, cfg, line); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "m total=1 1\nm total=2 2\n" {
+		t.Fatalf("unexpected file content %q: %v", data, err)
+	}
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+)
+
+// Sample is one refresh result as written by the exporters. Optional values
+// are nil when OpenRouter did not report them.
+type Sample struct {
+	At              time.Time
+	Key             string
+	Total           float64
+	Daily           *float64
+	Weekly          *float64
+	Monthly         *float64
+	LimitRemaining  *float64
+	RemainingCredit *float64
+}
+
+// SampleFromEvent builds a sample from a successful refresh.
+func SampleFromEvent(ev events.RefreshSucceeded) Sample {
+	sample := Sample{
+		At:             ev.At,
+		Key:            ev.Usage.Label,
+		Total:          ev.Usage.Total,
+		Daily:          ev.Usage.Daily,
+		Weekly:         ev.Usage.Weekly,
+		Monthly:        ev.Usage.Monthly,
+		LimitRemaining: ev.Usage.LimitRemaining,
+	}
+	if ev.Credits != nil {
+		remaining := ev.Credits.Remaining()
+		sample.RemainingCredit = &remaining
+	}
+	return sample
+}
+
+type field struct {
+	name  string
+	value float64
+}
+
+// fields lists the values present in s in a stable order.
+func (s Sample) fields() []field {
+	out := []field{{"total", s.Total}}
+	for _, opt := range []struct {
+		name  string
+		value *float64
+	}{
+		{"daily", s.Daily},
+		{"weekly", s.Weekly},
+		{"monthly", s.Monthly},
+		{"limit_remaining", s.LimitRemaining},
+		{"remaining_credit", s.RemainingCredit},
+	} {
+		if opt.value != nil {
+			out = append(out, field{opt.name, *opt.value})
+		}
+	}
+	return out
+}
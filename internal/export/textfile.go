@@ -0,0 +1,81 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"openrouter-costs-tray/internal/util"
+)
+
+// Textfile formats s for the node_exporter textfile collector.
+func Textfile(s Sample) []byte {
+	var b strings.Builder
+	labels := ""
+	if s.Key != "" {
+		labels = `key="` + escapeLabel(s.Key) + `"`
+	}
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	sample := func(name, extra string, value float64) {
+		all := labels
+		if extra != "" {
+			if all != "" {
+				all += ","
+			}
+			all += extra
+		}
+		if all != "" {
+			name += "{" + all + "}"
+		}
+		b.WriteString(name + " " + strconv.FormatFloat(value, 'f', -1, 64) + "\n")
+	}
+
+	gauge("openrouter_usage_total_dollars", "Total spend reported by OpenRouter in USD.")
+	sample("openrouter_usage_total_dollars", "", s.Total)
+	periods := []struct {
+		name  string
+		value *float64
+	}{{"daily", s.Daily}, {"weekly", s.Weekly}, {"monthly", s.Monthly}}
+	headerWritten := false
+	for _, p := range periods {
+		if p.value == nil {
+			continue
+		}
+		if !headerWritten {
+			gauge("openrouter_usage_period_dollars", "Spend in the current UTC period in USD.")
+			headerWritten = true
+		}
+		sample("openrouter_usage_period_dollars", `period="`+p.name+`"`, *p.value)
+	}
+	if s.LimitRemaining != nil {
+		gauge("openrouter_limit_remaining_dollars", "Remaining key limit in USD.")
+		sample("openrouter_limit_remaining_dollars", "", *s.LimitRemaining)
+	}
+	if s.RemainingCredit != nil {
+		gauge("openrouter_remaining_credit_dollars", "Unspent account credit in USD.")
+		sample("openrouter_remaining_credit_dollars", "", *s.RemainingCredit)
+	}
+	gauge("openrouter_last_refresh_timestamp_seconds", "Unix time of the last successful refresh.")
+	sample("openrouter_last_refresh_timestamp_seconds", "", float64(s.At.Unix()))
+	return []byte(b.String())
+}
+
+// WriteTextfile atomically replaces path so the collector never reads a partial file.
+func WriteTextfile(path string, s Sample) error {
+	if path == "" {
+		return fmt.Errorf("textfile path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// node_exporter usually runs as another user, so the file is world-readable.
+	return util.WriteFileAtomic(path, Textfile(s), 0o644)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
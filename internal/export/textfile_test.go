@@ -0,0 +1,37 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextfile(t *testing.T) {
+	got := string(Textfile(testSample()))
+	for _, want := range []string{
+		"# TYPE openrouter_usage_total_dollars gauge\n",
+		`openrouter_usage_total_dollars{key="ci key,1"} 12.5` + "\n",
+		`openrouter_usage_period_dollars{key="ci key,1",period="daily"} 1.5` + "\n",
+		`openrouter_remaining_credit_dollars{key="ci key,1"} 7.25` + "\n",
+		`openrouter_last_refresh_timestamp_seconds{key="ci key,1"} 1714644000` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in textfile:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `period="weekly"`) || strings.Contains(got, "limit_remaining") {
+		t.Fatalf("expected missing values to be omitted:\n%s", got)
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openrouter.prom")
+	if err := WriteTextfile(path, Sample{Total: 1}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "openrouter_usage_total_dollars 1\n") {
+		t.Fatalf("unexpected textfile %q: %v", data, err)
+	}
+}
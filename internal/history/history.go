@@ -0,0 +1,259 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/util"
+)
+
+const (
+	ProjectCostsFileName = "project_costs.jsonl"
+	SamplesFileName      = "spend_samples.jsonl"
+	// DefaultSampleRetention keeps a bit over a year of spend samples.
+	DefaultSampleRetention = 400 * 24 * time.Hour
+)
+
+// SpendSample is the usage of one key as seen by one refresh.
+type SpendSample struct {
+	At       time.Time `json:"at"`
+	KeyID    string    `json:"key_id,omitempty"`
+	KeyLabel string    `json:"key_label,omitempty"`
+	Total    float64   `json:"total"`
+	Daily    *float64  `json:"daily,omitempty"`
+	Weekly   *float64  `json:"weekly,omitempty"`
+	Monthly  *float64  `json:"monthly,omitempty"`
+}
+
+// ProjectCost is the cost of one proxied request attributed to a project.
+type ProjectCost struct {
+	At               time.Time `json:"at"`
+	Project          string    `json:"project"`
+	Model            string    `json:"model,omitempty"`
+	GenerationID     string    `json:"generation_id,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	Cost             float64   `json:"cost"`
+}
+
+// Store appends records as JSON Lines files in one directory.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+
+	retention time.Duration
+	prunedAt  time.Time
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// SetSampleRetention makes AppendSamples drop samples older than d, at most
+// once a day. Zero keeps every sample.
+func (s *Store) SetSampleRetention(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = d
+}
+
+func (s *Store) AppendProjectCost(rec ProjectCost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendLine(filepath.Join(s.dir, ProjectCostsFileName), rec)
+}
+
+// ProjectCosts returns records at or after since, oldest first.
+func (s *Store) ProjectCosts(since time.Time) ([]ProjectCost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []ProjectCost
+	err := readLines(filepath.Join(s.dir, ProjectCostsFileName), func(line []byte) bool {
+		var rec ProjectCost
+		if json.Unmarshal(line, &rec) == nil && !rec.At.Before(since) {
+			out = append(out, rec)
+		}
+		return true
+	})
+	return out, err
+}
+
+// Key identifies the key a sample belongs to.
+func (s SpendSample) Key() string {
+	if s.KeyID != "" {
+		return s.KeyID
+	}
+	return "label:" + s.KeyLabel
+}
+
+// AppendSamples records the samples of one refresh and, with a retention set,
+// prunes samples that fell out of it.
+func (s *Store) AppendSamples(samples []SpendSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := filepath.Join(s.dir, SamplesFileName)
+	for _, sample := range samples {
+		if err := appendLine(path, sample); err != nil {
+			return err
+		}
+	}
+	if s.retention <= 0 || len(samples) == 0 {
+		return nil
+	}
+	now := samples[len(samples)-1].At
+	if now.Sub(s.prunedAt) < 24*time.Hour {
+		return nil
+	}
+	s.prunedAt = now
+	return s.pruneSamples(now.Add(-s.retention))
+}
+
+// pruneSamples rewrites the samples file without samples before cutoff. The
+// last older sample of every key stays as the baseline of the first day kept.
+func (s *Store) pruneSamples(cutoff time.Time) error {
+	path := filepath.Join(s.dir, SamplesFileName)
+	baseline := map[string][]byte{}
+	var order []string
+	var kept [][]byte
+	err := readLines(path, func(line []byte) bool {
+		var sample SpendSample
+		if json.Unmarshal(line, &sample) != nil {
+			return true
+		}
+		line = bytes.Clone(line)
+		if !sample.At.Before(cutoff) {
+			kept = append(kept, line)
+			return true
+		}
+		key := sample.Key()
+		if _, ok := baseline[key]; !ok {
+			order = append(order, key)
+		}
+		baseline[key] = line
+		return true
+	})
+	if err != nil || len(baseline) == 0 {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, key := range order {
+		buf.Write(baseline[key])
+		buf.WriteByte('\n')
+	}
+	for _, line := range kept {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return util.WriteFileAtomic(path, buf.Bytes(), 0o600)
+}
+
+// Samples returns samples in [from, to), oldest first. A zero to means no
+// upper bound. Samples are appended in time order, so reading stops at to.
+func (s *Store) Samples(from, to time.Time) ([]SpendSample, error) {
+	return s.samples(from, to, false)
+}
+
+// SamplesWithBaseline returns samples in [from, to) preceded by the last
+// earlier sample of every key, which spend aggregation needs as a starting
+// point.
+func (s *Store) SamplesWithBaseline(from, to time.Time) ([]SpendSample, error) {
+	return s.samples(from, to, true)
+}
+
+func (s *Store) samples(from, to time.Time, withBaseline bool) ([]SpendSample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	baseline := map[string]SpendSample{}
+	var out []SpendSample
+	err := readLines(filepath.Join(s.dir, SamplesFileName), func(line []byte) bool {
+		var sample SpendSample
+		if json.Unmarshal(line, &sample) != nil {
+			return true
+		}
+		if !to.IsZero() && !sample.At.Before(to) {
+			return false
+		}
+		if !sample.At.Before(from) {
+			out = append(out, sample)
+		} else if withBaseline {
+			baseline[sample.Key()] = sample
+		}
+		return true
+	})
+	if len(baseline) == 0 {
+		return out, err
+	}
+	first := make([]SpendSample, 0, len(baseline)+len(out))
+	for _, sample := range baseline {
+		first = append(first, sample)
+	}
+	sort.Slice(first, func(i, j int) bool {
+		if !first[i].At.Equal(first[j].At) {
+			return first[i].At.Before(first[j].At)
+		}
+		return first[i].Key() < first[j].Key()
+	})
+	return append(first, out...), err
+}
+
+// ProjectTotals sums record costs per project.
+func ProjectTotals(records []ProjectCost) map[string]float64 {
+	totals := map[string]float64{}
+	for _, rec := range records {
+		totals[rec.Project] += rec.Cost
+	}
+	return totals
+}
+
+func appendLine(path string, value any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // path comes from the cache directory, not user input
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readLines calls fn for every non-empty line until it returns false.
+// Malformed lines are left to fn; a missing file yields no lines.
+func readLines(path string, fn func([]byte) bool) error {
+	//nolint:gosec // path comes from the cache directory, not user input
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 && !fn(line) {
+			break
+		}
+	}
+	return scanner.Err()
+}
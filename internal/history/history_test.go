@@ -0,0 +1,128 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProjectCostsRoundTrip(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nested"))
+	base := time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+
+	records := []ProjectCost{
+		{At: base.Add(-time.Hour), Project: "old", Cost: 5},
+		{At: base, Project: "api", Model: "openai/gpt-4o", Cost: 0.25},
+		{At: base.Add(time.Minute), Project: "api", Cost: 0.5},
+		{At: base.Add(2 * time.Minute), Project: "web", Cost: 1},
+	}
+	for _, rec := range records {
+		if err := store.AppendProjectCost(rec); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+
+	got, err := store.ProjectCosts(base)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(got) != 3 || got[0].Model != "openai/gpt-4o" {
+		t.Fatalf("unexpected records: %+v", got)
+	}
+	totals := ProjectTotals(got)
+	if totals["api"] != 0.75 || totals["web"] != 1 || totals["old"] != 0 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+}
+
+func TestProjectCostsMissingAndMalformed(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	got, err := store.ProjectCosts(time.Time{})
+	if err != nil || got != nil {
+		t.Fatalf("expected no records for missing file, got %+v %v", got, err)
+	}
+
+	data := "{not json}\n\n{\"at\":\"2025-02-03T10:00:00Z\",\"project\":\"api\",\"cost\":1}\n"
+	if err := os.WriteFile(filepath.Join(dir, ProjectCostsFileName), []byte(data), 0o600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	got, err = store.ProjectCosts(time.Time{})
+	if err != nil || len(got) != 1 || got[0].Project != "api" {
+		t.Fatalf("expected malformed lines to be skipped, got %+v %v", got, err)
+	}
+}
+
+func TestSamplesRange(t *testing.T) {
+	store := NewStore(t.TempDir())
+	base := time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+	daily := 0.5
+	err := store.AppendSamples([]SpendSample{
+		{At: base.Add(-time.Hour), KeyID: "k1", Total: 1},
+		{At: base, KeyID: "k1", KeyLabel: "ci", Total: 2, Daily: &daily},
+		{At: base.Add(time.Hour), KeyID: "k1", Total: 3},
+	})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	got, err := store.Samples(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(got) != 1 || got[0].KeyLabel != "ci" || got[0].Daily == nil || *got[0].Daily != 0.5 {
+		t.Fatalf("unexpected samples: %+v", got)
+	}
+	if all, _ := store.Samples(time.Time{}, time.Time{}); len(all) != 3 {
+		t.Fatalf("expected all samples without bounds, got %d", len(all))
+	}
+}
+
+func TestSamplesWithBaseline(t *testing.T) {
+	store := NewStore(t.TempDir())
+	base := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	err := store.AppendSamples([]SpendSample{
+		{At: base.Add(-3 * time.Hour), KeyID: "k1", Total: 1},
+		{At: base.Add(-2 * time.Hour), KeyID: "k1", Total: 2},
+		{At: base.Add(-time.Hour), KeyLabel: "ci", Total: 5},
+		{At: base.Add(time.Hour), KeyID: "k1", Total: 3},
+		{At: base.Add(25 * time.Hour), KeyID: "k1", Total: 4},
+	})
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	got, err := store.SamplesWithBaseline(base, base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(got) != 3 || got[0].Total != 2 || got[1].KeyLabel != "ci" || got[2].Total != 3 {
+		t.Fatalf("expected the last earlier sample per key before the window, got %+v", got)
+	}
+}
+
+func TestSampleRetention(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.SetSampleRetention(48 * time.Hour)
+	base := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	for day := range 5 {
+		err := store.AppendSamples([]SpendSample{{At: base.AddDate(0, 0, day), KeyID: "k1", Total: float64(day)}})
+		if err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+
+	all, err := store.Samples(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	totals := make([]float64, 0, len(all))
+	for _, sample := range all {
+		totals = append(totals, sample.Total)
+	}
+	// Day 1 stays as the baseline of day 2, the first day kept.
+	if len(totals) != 4 || totals[0] != 1 || totals[3] != 4 {
+		t.Fatalf("expected samples older than the retention pruned, got %v", totals)
+	}
+}
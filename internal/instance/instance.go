@@ -0,0 +1,230 @@
+// Package instance keeps a single tray running per user. The first launch
+// listens on a Unix socket; later launches forward a command to it and exit.
+package instance
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	SocketFileName = "instance.sock"
+	// runtimeSocketName is used in XDG_RUNTIME_DIR, which other apps share.
+	runtimeSocketName = "openrouter-costs-tray.sock"
+)
+
+// Commands understood by the running instance.
+const (
+	CommandShowSettings = "show-settings"
+	CommandRefresh      = "refresh"
+	CommandStatus       = "status"
+)
+
+const (
+	dialTimeout    = time.Second
+	requestTimeout = 30 * time.Second
+	maxRequestLine = 1024
+)
+
+var (
+	// ErrRunning is returned by Listen when another instance owns the socket.
+	ErrRunning = errors.New("another instance is running")
+	// ErrNotRunning is returned by Send when no instance listens on the socket.
+	ErrNotRunning = errors.New("tray is not running")
+
+	errLocked = errors.New("lock is held")
+)
+
+// Handler executes a forwarded command and returns its text output.
+type Handler func(ctx context.Context, command string) (string, error)
+
+type response struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SocketPath returns the socket in XDG_RUNTIME_DIR when set, else in dir.
+func SocketPath(dir string) string {
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		return filepath.Join(runtime, runtimeSocketName)
+	}
+	return filepath.Join(dir, SocketFileName)
+}
+
+// Server owns the instance socket and the lock file next to it.
+type Server struct {
+	listener net.Listener
+	lock     *os.File
+	logger   *slog.Logger
+	wg       sync.WaitGroup
+	once     sync.Once
+	// ctx is cancelled by Close so running handlers give up.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Listen claims the socket at path. It first locks path+".lock", so two
+// launches never probe or replace the socket at the same time. A socket left
+// behind by a crashed instance is detected by a refused connection and
+// replaced.
+func Listen(path string, logger *slog.Logger) (*Server, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	lock, err := acquireLock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	listener, err := listen(path, logger)
+	if err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		logger.Debug("instance socket chmod failed", "error", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{listener: listener, lock: lock, logger: logger, ctx: ctx, cancel: cancel}, nil
+}
+
+// acquireLock opens and locks the file at path. It returns ErrRunning while
+// another instance holds the lock.
+func acquireLock(path string) (*os.File, error) {
+	//nolint:gosec // path is derived from the socket path, not user input
+	lock, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		_ = lock.Close()
+		if errors.Is(err, errLocked) {
+			return nil, ErrRunning
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// listen binds the socket, replacing a stale one. The caller holds the lock.
+func listen(path string, logger *slog.Logger) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err == nil {
+		return listener, nil
+	}
+	if _, statErr := os.Lstat(path); statErr != nil {
+		return nil, err
+	}
+	conn, dialErr := net.DialTimeout("unix", path, dialTimeout)
+	if dialErr == nil {
+		_ = conn.Close()
+		return nil, ErrRunning
+	}
+	logger.Info("removing stale instance socket", "path", path, "error", dialErr)
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// Serve answers forwarded commands with handler until Close. Connections that
+// arrive before Serve wait in the listen backlog.
+func (s *Server) Serve(handler Handler) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					s.logger.Warn("instance accept failed", "error", err)
+				}
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.handle(conn, handler)
+			}()
+		}
+	}()
+}
+
+func (s *Server) handle(conn net.Conn, handler Handler) {
+	defer conn.Close()
+	// Close also unblocks a connection still waiting for its command.
+	stop := context.AfterFunc(s.ctx, func() { _ = conn.Close() })
+	defer stop()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, maxRequestLine), maxRequestLine)
+	if !scanner.Scan() {
+		return
+	}
+	command := scanner.Text()
+	s.logger.Info("instance command received", "command", command)
+
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	var resp response
+	output, err := handler(ctx, command)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Output = output
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.logger.Debug("instance reply failed", "error", err)
+	}
+}
+
+// Close stops accepting commands, cancels the running ones, removes the
+// socket and releases the lock. It returns once handlers have seen the
+// cancellation, so a handler must honour its context.
+func (s *Server) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		err = s.listener.Close()
+		s.wg.Wait()
+		err = errors.Join(err, s.lock.Close())
+	})
+	return err
+}
+
+// Send forwards command to the running instance and returns its output.
+func Send(ctx context.Context, path, command string) (string, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(requestTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return "", fmt.Errorf("read instance reply: %w", err)
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Output, nil
+}
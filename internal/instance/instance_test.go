@@ -0,0 +1,151 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestListenSendAndSecondInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	srv, err := Listen(path, testLogger())
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer srv.Close()
+	srv.Serve(func(_ context.Context, command string) (string, error) {
+		switch command {
+		case CommandStatus:
+			return "Today: $1.00", nil
+		default:
+			return "", errors.New("unknown command " + command)
+		}
+	})
+
+	if _, err := Listen(path, testLogger()); !errors.Is(err, ErrRunning) {
+		t.Fatalf("expected ErrRunning, got %v", err)
+	}
+
+	out, err := Send(context.Background(), path, CommandStatus)
+	if err != nil || out != "Today: $1.00" {
+		t.Fatalf("unexpected status reply %q %v", out, err)
+	}
+	if _, err := Send(context.Background(), path, "bogus"); err == nil || err.Error() != "unknown command bogus" {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestCloseCancelsRunningCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	srv, err := Listen(path, testLogger())
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	started := make(chan struct{})
+	srv.Serve(func(ctx context.Context, _ string) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	// A client that connected but never sent its command.
+	idle, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer idle.Close()
+	go func() { _, _ = Send(context.Background(), path, CommandRefresh) }()
+	<-started
+
+	start := time.Now()
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Fatalf("close waited %v for running commands", waited)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	// A listener that does not unlink on close leaves the file behind, as a crash would.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("stale listen: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected stale socket file: %v", err)
+	}
+
+	srv, err := Listen(path, testLogger())
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	srv.Serve(func(context.Context, string) (string, error) { return "ok", nil })
+	if out, err := Send(context.Background(), path, CommandRefresh); err != nil || out != "ok" {
+		t.Fatalf("unexpected reply %q %v", out, err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected socket removed on close, got %v", err)
+	}
+}
+
+func TestListenLeavesSocketWhileLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("stale listen: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+	// Another launch is between its probe and its remove.
+	lock, err := acquireLock(path + ".lock")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	if _, err := Listen(path, testLogger()); !errors.Is(err, ErrRunning) {
+		t.Fatalf("expected ErrRunning while the lock is held, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the socket to be left alone: %v", err)
+	}
+	lock.Close()
+	srv, err := Listen(path, testLogger())
+	if err != nil {
+		t.Fatalf("expected listen once the lock is released, got %v", err)
+	}
+	srv.Close()
+}
+
+func TestSendNotRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	if _, err := Send(context.Background(), path, CommandStatus); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if got := SocketPath("/cfg"); got != filepath.Join("/cfg", SocketFileName) {
+		t.Fatalf("unexpected path %q", got)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := SocketPath("/cfg"); got != filepath.Join("/run/user/1000", runtimeSocketName) {
+		t.Fatalf("unexpected runtime path %q", got)
+	}
+}
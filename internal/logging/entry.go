@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Entry is one log record split into the columns of the log viewer.
+type Entry struct {
+	Time      time.Time
+	Level     string
+	Message   string
+	Component string
+	// Attrs holds the remaining attributes as key=value pairs.
+	Attrs string
+	Raw   string
+}
+
+// ParseEntry splits a JSON log line into columns. Lines that are not JSON
+// records are kept whole as the message.
+func ParseEntry(line string) Entry {
+	entry := Entry{Raw: line, Message: line}
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return entry
+	}
+	var attrs []string
+	parsed := Entry{Raw: line}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return entry
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return entry
+		}
+		switch key {
+		case slog.TimeKey:
+			_ = json.Unmarshal(value, &parsed.Time)
+		case slog.LevelKey:
+			parsed.Level = rawString(value)
+		case slog.MessageKey:
+			parsed.Message = rawString(value)
+		case "component":
+			parsed.Component = rawString(value)
+		default:
+			attrs = append(attrs, key+"="+attrValue(value))
+		}
+	}
+	parsed.Attrs = strings.Join(attrs, " ")
+	return parsed
+}
+
+func rawString(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// attrValue shows strings bare unless quoting keeps the pairs readable.
+func attrValue(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return string(value)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"") {
+		return string(value)
+	}
+	return s
+}
+
+// SlogLevel parses the level column, defaulting to info.
+func (e Entry) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(e.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Matches reports whether the entry is at least min and contains query,
+// ignoring case.
+func (e Entry) Matches(min slog.Level, query string) bool {
+	if e.SlogLevel() < min {
+		return false
+	}
+	query = strings.TrimSpace(query)
+	return query == "" || strings.Contains(strings.ToLower(e.Raw), strings.ToLower(query))
+}
+
+// ReadTail parses the records in the last maxBytes of the log file at path.
+// A record cut in half by the limit is skipped.
+func ReadTail(path string, maxBytes int64) ([]Entry, error) {
+	//nolint:gosec // path comes from app config directory, not user input
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-maxBytes, 0)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		} else {
+			data = nil
+		}
+	}
+	var entries []Entry
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			entries = append(entries, ParseEntry(line))
+		}
+	}
+	return entries, nil
+}
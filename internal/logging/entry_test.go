@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEntry(t *testing.T) {
+	line := `{"time":"2024-05-01T10:00:00.5Z","level":"WARN","msg":"refresh failed","app":"tray","component":"refresher","error":"boom: timeout","status":502}`
+	entry := ParseEntry(line)
+	if !entry.Time.Equal(time.Date(2024, 5, 1, 10, 0, 0, 500_000_000, time.UTC)) || entry.Level != "WARN" || entry.Message != "refresh failed" || entry.Component != "refresher" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry.Attrs != `app=tray error="boom: timeout" status=502` {
+		t.Fatalf("unexpected attrs %q", entry.Attrs)
+	}
+	if entry.SlogLevel() != slog.LevelWarn {
+		t.Fatalf("unexpected level %v", entry.SlogLevel())
+	}
+
+	plain := ParseEntry("panic: something")
+	if plain.Message != "panic: something" || plain.Level != "" || plain.SlogLevel() != slog.LevelInfo {
+		t.Fatalf("expected plain line kept whole, got %+v", plain)
+	}
+}
+
+func TestEntryMatches(t *testing.T) {
+	entry := ParseEntry(`{"level":"INFO","msg":"cache loaded","path":"/tmp/Cache.json"}`)
+	if !entry.Matches(slog.LevelDebug, "cache.JSON") {
+		t.Fatalf("expected case-insensitive match")
+	}
+	if entry.Matches(slog.LevelWarn, "") {
+		t.Fatalf("expected info entry hidden at warn")
+	}
+	if entry.Matches(slog.LevelInfo, "error") {
+		t.Fatalf("expected search to filter")
+	}
+}
+
+func TestReadTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	var b strings.Builder
+	for _, msg := range []string{"one", "two", "three"} {
+		b.WriteString(`{"level":"INFO","msg":"` + msg + `"}` + "\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	all, err := ReadTail(path, 1<<20)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d %v", len(all), err)
+	}
+	tail, err := ReadTail(path, 40)
+	if err != nil || len(tail) != 1 || tail[0].Message != "three" {
+		t.Fatalf("expected cut record skipped, got %+v %v", tail, err)
+	}
+}
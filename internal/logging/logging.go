@@ -1,17 +1,34 @@
 package logging
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+// Log formats for stdout. The log file is always JSON so the viewer can parse it.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
 )
 
+// ComponentKey is the attribute that names the component of a logger.
+const ComponentKey = "component"
+
+// Output routes records to stdout and the optional log file and holds the
+// settings that can change while the app runs.
 type Output struct {
-	writer *multiWriter
+	levelVar   *slog.LevelVar
+	components atomic.Pointer[map[string]slog.Level]
+	textStdout atomic.Bool
+	file       *fileSink
 }
 
 type WriteCloser interface {
@@ -19,103 +36,181 @@ type WriteCloser interface {
 	io.Closer
 }
 
-func NewLogger(level string) (*slog.Logger, *slog.LevelVar, *Output) {
-	levelVar := &slog.LevelVar{}
-	SetLevel(levelVar, level)
-	writer := &multiWriter{stdout: os.Stdout}
-	handler := slog.NewJSONHandler(writer, &slog.HandlerOptions{Level: levelVar})
-	return slog.New(handler), levelVar, &Output{writer: writer}
+func NewLogger(cfg config.LoggingConfig) (*slog.Logger, *Output) {
+	return newLogger(os.Stdout, cfg)
 }
 
-func SetLevel(levelVar *slog.LevelVar, level string) {
-	if levelVar == nil {
+func newLogger(stdout io.Writer, cfg config.LoggingConfig) (*slog.Logger, *Output) {
+	out := &Output{levelVar: &slog.LevelVar{}, file: &fileSink{}}
+	out.Apply(cfg)
+	// Levels are checked by the handler; the inner handlers pass everything.
+	opts := &slog.HandlerOptions{Level: slog.Level(-100)}
+	h := &handler{
+		out:  out,
+		json: slog.NewJSONHandler(stdout, opts),
+		text: slog.NewTextHandler(stdout, opts),
+		file: slog.NewJSONHandler(out.file, opts),
+	}
+	return slog.New(h), out
+}
+
+// Apply sets the level, per-component overrides and stdout format.
+func (o *Output) Apply(cfg config.LoggingConfig) {
+	if o == nil {
 		return
 	}
+	SetLevel(o.levelVar, cfg.Level)
+	components := make(map[string]slog.Level, len(cfg.Components))
+	for name, level := range cfg.Components {
+		if parsed, ok := ParseLevel(level); ok {
+			components[name] = parsed
+		}
+	}
+	o.components.Store(&components)
+	o.textStdout.Store(cfg.Format == FormatText)
+}
+
+func (o *Output) enabled(component string, level slog.Level) bool {
+	if component != "" {
+		if min, ok := (*o.components.Load())[component]; ok {
+			return level >= min
+		}
+	}
+	return level >= o.levelVar.Level()
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(level string) (slog.Level, bool) {
 	switch strings.ToLower(strings.TrimSpace(level)) {
 	case "debug":
-		levelVar.Set(slog.LevelDebug)
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
 	case "warn", "warning":
-		levelVar.Set(slog.LevelWarn)
+		return slog.LevelWarn, true
 	case "error":
-		levelVar.Set(slog.LevelError)
-	default:
-		levelVar.Set(slog.LevelInfo)
+		return slog.LevelError, true
+	}
+	return slog.LevelInfo, false
+}
+
+func SetLevel(levelVar *slog.LevelVar, level string) {
+	if levelVar == nil {
+		return
 	}
+	parsed, _ := ParseLevel(level)
+	levelVar.Set(parsed)
 }
 
-func (o *Output) EnableFile(path string) error {
-	if o == nil || o.writer == nil {
+// EnableFile tees log records to path, rotating it as configured.
+func (o *Output) EnableFile(path string, rotation Rotation) error {
+	if o == nil || o.file == nil {
 		return errors.New("log output not initialized")
 	}
 	if strings.TrimSpace(path) == "" {
 		return errors.New("log file path is empty")
 	}
-	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
-		return err
-	}
-	//nolint:gosec // path comes from app config directory, not user input
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	file, err := openRotating(path, rotation, nil)
 	if err != nil {
 		return err
 	}
-	o.writer.setFile(file)
+	o.file.set(file)
 	return nil
 }
 
 func (o *Output) EnableWriter(writer WriteCloser) error {
-	if o == nil || o.writer == nil {
+	if o == nil || o.file == nil {
 		return errors.New("log output not initialized")
 	}
 	if writer == nil {
 		return errors.New("log output writer is nil")
 	}
-	o.writer.setFile(writer)
+	o.file.set(writer)
 	return nil
 }
 
 func (o *Output) DisableFile() error {
-	if o == nil || o.writer == nil {
+	if o == nil || o.file == nil {
 		return nil
 	}
-	return o.writer.clearFile()
+	return o.file.set(nil)
+}
+
+// handler sends every record to stdout in the selected format and, when a
+// file is enabled, to the file as JSON.
+type handler struct {
+	out       *Output
+	component string
+	json      slog.Handler
+	text      slog.Handler
+	file      slog.Handler
 }
 
-type multiWriter struct {
-	mu     sync.Mutex
-	stdout io.Writer
-	file   WriteCloser
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.out.enabled(h.component, level)
 }
 
-func (w *multiWriter) Write(p []byte) (int, error) {
-	if w == nil || w.stdout == nil {
-		return 0, errors.New("log output not initialized")
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	stdout := h.json
+	if h.out.textStdout.Load() {
+		stdout = h.text
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	n, err := w.stdout.Write(p)
-	if w.file != nil {
-		if _, fileErr := w.file.Write(p); fileErr != nil && err == nil {
+	err := stdout.Handle(ctx, rec)
+	if h.out.file.active() {
+		if fileErr := h.file.Handle(ctx, rec.Clone()); fileErr != nil && err == nil {
 			err = fileErr
 		}
 	}
-	return n, err
+	return err
 }
 
-func (w *multiWriter) setFile(file WriteCloser) {
-	w.mu.Lock()
-	old := w.file
-	w.file = file
-	w.mu.Unlock()
-	if old != nil {
-		_ = old.Close()
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			clone.component = a.Value.String()
+		}
 	}
+	clone.json = h.json.WithAttrs(attrs)
+	clone.text = h.text.WithAttrs(attrs)
+	clone.file = h.file.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.json = h.json.WithGroup(name)
+	clone.text = h.text.WithGroup(name)
+	clone.file = h.file.WithGroup(name)
+	return &clone
+}
+
+// fileSink is the swappable log file.
+type fileSink struct {
+	mu   sync.Mutex
+	file WriteCloser
+}
+
+func (s *fileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return len(p), nil
+	}
+	return s.file.Write(p)
+}
+
+func (s *fileSink) active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file != nil
 }
 
-func (w *multiWriter) clearFile() error {
-	w.mu.Lock()
-	old := w.file
-	w.file = nil
-	w.mu.Unlock()
+func (s *fileSink) set(file WriteCloser) error {
+	s.mu.Lock()
+	old := s.file
+	s.file = file
+	s.mu.Unlock()
 	if old != nil {
 		return old.Close()
 	}
@@ -2,8 +2,11 @@ package logging
 
 import (
 	"bytes"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"openrouter-costs-tray/internal/config"
 )
 
 type nopWriteCloser struct {
@@ -15,7 +18,7 @@ func (n nopWriteCloser) Close() error {
 }
 
 func TestOutputEnableWriterWrites(t *testing.T) {
-	logger, _, output := NewLogger("info")
+	logger, output := NewLogger(config.LoggingConfig{Level: "info"})
 	buf := &bytes.Buffer{}
 	if err := output.EnableWriter(nopWriteCloser{Buffer: buf}); err != nil {
 		t.Fatalf("enable writer: %v", err)
@@ -31,7 +34,7 @@ func TestOutputEnableWriterWrites(t *testing.T) {
 }
 
 func TestOutputDisableFileStopsWriting(t *testing.T) {
-	logger, _, output := NewLogger("info")
+	logger, output := NewLogger(config.LoggingConfig{Level: "info"})
 	buf := &bytes.Buffer{}
 	if err := output.EnableWriter(nopWriteCloser{Buffer: buf}); err != nil {
 		t.Fatalf("enable writer: %v", err)
@@ -46,3 +49,60 @@ func TestOutputDisableFileStopsWriting(t *testing.T) {
 		t.Fatalf("expected no new output after disable, got %q", buf.String())
 	}
 }
+
+func TestOutputEnableFileRotates(t *testing.T) {
+	logger, output := NewLogger(config.LoggingConfig{Level: "info"})
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := output.EnableFile(path, Rotation{MaxSize: 200, MaxFiles: 1}); err != nil {
+		t.Fatalf("enable file: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		logger.Info("a record long enough to fill the file quickly", "i", i)
+	}
+	if err := output.DisableFile(); err != nil {
+		t.Fatalf("disable file: %v", err)
+	}
+	entries, err := ReadTail(path, 1<<20)
+	if err != nil || len(entries) == 0 || len(entries) == 5 {
+		t.Fatalf("expected the current file to hold only the latest records, got %d %v", len(entries), err)
+	}
+	if segments := Segments(path); len(segments) != 1 {
+		t.Fatalf("expected one kept segment, got %v", segments)
+	}
+}
+
+func TestComponentLevelsAndFormat(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	logger, output := newLogger(stdout, config.LoggingConfig{Level: "info", Components: map[string]string{"client": "debug", "mqtt": "error"}})
+	file := &bytes.Buffer{}
+	if err := output.EnableWriter(nopWriteCloser{Buffer: file}); err != nil {
+		t.Fatalf("enable writer: %v", err)
+	}
+	client := logger.With(ComponentKey, "client")
+	mqtt := logger.With(ComponentKey, "mqtt")
+	other := logger.With(ComponentKey, "tray")
+
+	client.Debug("client debug")
+	mqtt.Warn("mqtt warn")
+	other.Debug("tray debug")
+	other.Info("tray info")
+	got := stdout.String()
+	if !strings.Contains(got, "client debug") || strings.Contains(got, "mqtt warn") || strings.Contains(got, "tray debug") || !strings.Contains(got, "tray info") {
+		t.Fatalf("unexpected filtering: %s", got)
+	}
+	if !strings.HasPrefix(got, "{") {
+		t.Fatalf("expected JSON on stdout by default, got %q", got)
+	}
+
+	stdout.Reset()
+	file.Reset()
+	output.Apply(config.LoggingConfig{Level: "warn", Format: "text"})
+	client.Debug("client hidden now")
+	other.Warn("tray warn", "n", 1)
+	if strings.Contains(stdout.String(), "client hidden") || !strings.Contains(stdout.String(), `level=WARN msg="tray warn" component=tray n=1`) {
+		t.Fatalf("expected live change to text and warn, got %q", stdout.String())
+	}
+	if !strings.Contains(file.String(), `"msg":"tray warn"`) {
+		t.Fatalf("expected file to stay JSON, got %q", file.String())
+	}
+}
@@ -0,0 +1,259 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+const segmentTimeLayout = "20060102-150405"
+
+// Rotation controls when the log file is rotated. Zero MaxSize or MaxAge
+// disables that trigger; MaxFiles old segments are kept.
+type Rotation struct {
+	MaxSize  int64
+	MaxAge   time.Duration
+	MaxFiles int
+	Compress bool
+}
+
+// RotationFromConfig converts the logging config to a Rotation.
+func RotationFromConfig(cfg config.LoggingConfig) Rotation {
+	return Rotation{
+		MaxSize:  int64(cfg.MaxSizeMB) << 20,
+		MaxAge:   time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		MaxFiles: cfg.MaxFiles,
+		Compress: cfg.Compress,
+	}
+}
+
+// rotatingFile appends to path and moves it aside as path.<timestamp> when it
+// grows too big or too old.
+type rotatingFile struct {
+	path     string
+	rotation Rotation
+	now      func() time.Time
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	started time.Time
+	// background tracks compression of rotated segments.
+	background sync.WaitGroup
+}
+
+func openRotating(path string, rotation Rotation, now func() time.Time) (*rotatingFile, error) {
+	if now == nil {
+		now = time.Now
+	}
+	r := &rotatingFile{path: path, rotation: rotation, now: now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if r.due(0) {
+		if err := r.rotate(); err != nil {
+			_ = r.file.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	//nolint:gosec // path comes from app config directory, not user input
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.started = r.now()
+	if r.size > 0 {
+		r.started = segmentStart(r.path, info.ModTime())
+	}
+	return nil
+}
+
+// segmentStart returns the time of the first record in an existing log file,
+// falling back to its modification time.
+func segmentStart(path string, fallback time.Time) time.Time {
+	//nolint:gosec // path comes from app config directory, not user input
+	file, err := os.Open(path)
+	if err != nil {
+		return fallback
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fallback
+	}
+	var record struct {
+		Time time.Time `json:"time"`
+	}
+	if json.Unmarshal(line, &record) != nil || record.Time.IsZero() {
+		return fallback
+	}
+	return record.Time
+}
+
+func (r *rotatingFile) due(n int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.rotation.MaxSize > 0 && r.size+int64(n) > r.rotation.MaxSize {
+		return true
+	}
+	return r.rotation.MaxAge > 0 && r.now().Sub(r.started) >= r.rotation.MaxAge
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.due(len(p)) {
+		if err := r.rotate(); err != nil {
+			// Keep logging to the old file rather than losing records.
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+			if r.file == nil {
+				if err := r.open(); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	segment := r.path + "." + r.now().Format(segmentTimeLayout)
+	for i := 2; fileExists(segment) || fileExists(segment+".gz"); i++ {
+		segment = fmt.Sprintf("%s.%s-%d", r.path, r.now().Format(segmentTimeLayout), i)
+	}
+	if err := os.Rename(r.path, segment); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if !r.rotation.Compress {
+		r.prune()
+		return nil
+	}
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+		if err := gzipFile(segment); err != nil {
+			fmt.Fprintf(os.Stderr, "log compression failed: %v\n", err)
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// prune removes the oldest segments beyond MaxFiles.
+func (r *rotatingFile) prune() {
+	segments := Segments(r.path)
+	for len(segments) > r.rotation.MaxFiles {
+		_ = os.Remove(segments[0])
+		segments = segments[1:]
+	}
+}
+
+// Segments lists the rotated segments of the log file at path, oldest first.
+func Segments(path string) []string {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(path) + "."
+	var out []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		// A segment being compressed is counted once, by its gzip.
+		if !strings.HasSuffix(name, ".gz") && fileExists(filepath.Join(filepath.Dir(path), name+".gz")) {
+			continue
+		}
+		out = append(out, filepath.Join(filepath.Dir(path), name))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.background.Wait()
+	return err
+}
+
+func gzipFile(path string) error {
+	//nolint:gosec // path comes from app config directory, not user input
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	//nolint:gosec // path comes from app config directory, not user input
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	r, err := openRotating(path, Rotation{MaxSize: 20, MaxFiles: 2}, clock.Now)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		clock.now = clock.now.Add(time.Second)
+		if _, err := r.Write([]byte("0123456789abcde\n")); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	segments := Segments(path)
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 kept segments, got %v", segments)
+	}
+	if filepath.Base(segments[0]) != "app.log.20240501-100003" || filepath.Base(segments[1]) != "app.log.20240501-100004" {
+		t.Fatalf("expected oldest segment pruned, got %v", segments)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "0123456789abcde\n" {
+		t.Fatalf("expected only the last record in the current file, got %q %v", data, err)
+	}
+}
+
+func TestRotatingFileRotatesByAgeOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	old := `{"time":"2024-04-01T10:00:00Z","level":"INFO","msg":"old"}` + "\n"
+	if err := os.WriteFile(path, []byte(old), 0o600); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	clock := &fakeClock{now: time.Date(2024, 4, 9, 10, 0, 0, 0, time.UTC)}
+	r, err := openRotating(path, Rotation{MaxAge: 7 * 24 * time.Hour, MaxFiles: 5, Compress: true}, clock.Now)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := r.Write([]byte("new\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	segments := Segments(path)
+	if len(segments) != 1 || !strings.HasSuffix(segments[0], ".gz") {
+		t.Fatalf("expected one gzipped segment, got %v", segments)
+	}
+	file, err := os.Open(segments[0])
+	if err != nil {
+		t.Fatalf("open segment: %v", err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil || string(data) != old {
+		t.Fatalf("unexpected segment content %q %v", data, err)
+	}
+}
+
+func TestRotatingFileKeepsFreshFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := &fakeClock{now: time.Date(2024, 4, 9, 10, 0, 0, 0, time.UTC)}
+	if err := os.WriteFile(path, []byte(`{"time":"2024-04-08T10:00:00Z","msg":"recent"}`+"\n"), 0o600); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	r, err := openRotating(path, Rotation{MaxAge: 7 * 24 * time.Hour, MaxSize: 1 << 20, MaxFiles: 5}, clock.Now)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer r.Close()
+	if segments := Segments(path); len(segments) != 0 {
+		t.Fatalf("expected no rotation, got %v", segments)
+	}
+}
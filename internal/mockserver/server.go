@@ -0,0 +1,509 @@
+// Package mockserver is a fake OpenRouter API for development and demos. It
+// simulates spend that grows with the wall clock and can be told to fail in
+// the ways the real API does.
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BasePath is where the API is served, so base URLs look like the real one.
+const BasePath = "/api/v1"
+
+// Spend patterns.
+const (
+	// Steady spends the rate evenly.
+	Steady = "steady"
+	// Bursty spends each hour's share within its first ten minutes.
+	Bursty = "bursty"
+	// Workday spends the week's share during 9-18 local time on weekdays.
+	Workday = "workday"
+	// Ramp adds the rate again for every hour the server has been running.
+	Ramp = "ramp"
+)
+
+// Patterns lists the valid spend patterns.
+var Patterns = []string{Steady, Bursty, Workday, Ramp}
+
+// Faults injected into API responses.
+const (
+	FaultNone         = "none"
+	FaultUnauthorized = "401"
+	FaultRateLimit    = "429"
+	FaultServerError  = "500"
+	FaultSlow         = "slow"
+	FaultMalformed    = "malformed"
+)
+
+// Faults lists the valid faults.
+var Faults = []string{FaultNone, FaultUnauthorized, FaultRateLimit, FaultServerError, FaultSlow, FaultMalformed}
+
+// workHours is how many hours Workday spends in per week.
+const workHours = 5 * 9
+
+type Options struct {
+	Pattern string
+	// RatePerHour is the average spend in USD per hour.
+	RatePerHour float64
+	// Limit is the key's credit limit in USD; zero means no limit.
+	Limit float64
+	// Credits is the purchased account credit in USD.
+	Credits float64
+	// Token, when set, is the only accepted API key. Otherwise any bearer
+	// token is accepted.
+	Token string
+	// Fault is injected into every API response until changed.
+	Fault string
+	// SlowDelay is how long slow responses take.
+	SlowDelay time.Duration
+	// RetryAfter is sent with 429 responses.
+	RetryAfter time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+type model struct {
+	id, name, provider string
+	// prompt and completion are USD per token.
+	prompt, completion float64
+	// share is the fraction of the spend going to this model.
+	share float64
+}
+
+var models = []model{
+	{"anthropic/claude-3.5-sonnet", "Anthropic: Claude 3.5 Sonnet", "Anthropic", 0.000003, 0.000015, 0.5},
+	{"meta-llama/llama-3.1-70b-instruct", "Meta: Llama 3.1 70B Instruct", "DeepInfra", 0.00000052, 0.00000075, 0.3},
+	{"openai/gpt-4o-mini", "OpenAI: GPT-4o-mini", "OpenAI", 0.00000015, 0.0000006, 0.15},
+	{"google/gemini-flash-1.5", "Google: Gemini Flash 1.5", "Google", 0.000000075, 0.0000003, 0.05},
+}
+
+// Server serves the mock API. The zero value is not usable; use New.
+type Server struct {
+	opts    Options
+	logger  *slog.Logger
+	mux     *http.ServeMux
+	started time.Time
+
+	mu sync.Mutex
+	// simulated is how far spend has been simulated.
+	simulated time.Time
+	// days holds the spend per UTC day ("2006-01-02") and model.
+	days       map[string][]float64
+	fault      string
+	faultCount int
+}
+
+// New returns a server whose history starts at the beginning of the previous
+// month, so daily, weekly and monthly totals look lived-in from the start.
+func New(opts Options, logger *slog.Logger) (*Server, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = Steady
+	}
+	if !slices.Contains(Patterns, opts.Pattern) {
+		return nil, fmt.Errorf("unknown pattern %q", opts.Pattern)
+	}
+	if opts.Fault == "" {
+		opts.Fault = FaultNone
+	}
+	if !slices.Contains(Faults, opts.Fault) {
+		return nil, fmt.Errorf("unknown fault %q", opts.Fault)
+	}
+	if opts.RatePerHour < 0 {
+		return nil, fmt.Errorf("negative rate %v", opts.RatePerHour)
+	}
+	if opts.SlowDelay <= 0 {
+		opts.SlowDelay = 30 * time.Second
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = 30 * time.Second
+	}
+	now := opts.Now().UTC()
+	s := &Server{
+		opts:      opts,
+		logger:    logger,
+		started:   now,
+		simulated: time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC),
+		days:      map[string][]float64{},
+		fault:     opts.Fault,
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/_mock/fault", s.handleFault)
+	s.mux.Handle(BasePath+"/", http.StripPrefix(BasePath, s.api()))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// SetFault injects fault into the next count API responses, or into all of
+// them until changed when count is zero. FaultNone clears it.
+func (s *Server) SetFault(fault string, count int) error {
+	if !slices.Contains(Faults, fault) {
+		return fmt.Errorf("unknown fault %q", fault)
+	}
+	s.mu.Lock()
+	s.fault, s.faultCount = fault, max(count, 0)
+	s.mu.Unlock()
+	s.logger.Info("fault set", "fault", fault, "count", count)
+	return nil
+}
+
+// takeFault returns the fault for the current request and counts it down.
+func (s *Server) takeFault() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fault := s.fault
+	if s.faultCount > 0 {
+		s.faultCount--
+		if s.faultCount == 0 {
+			s.fault = FaultNone
+		}
+	}
+	return fault
+}
+
+// rate returns the spend rate in USD per hour at t.
+func (s *Server) rate(t time.Time) float64 {
+	r := s.opts.RatePerHour
+	switch s.opts.Pattern {
+	case Bursty:
+		if t.Minute() < 10 {
+			return r * 6
+		}
+		return 0
+	case Workday:
+		local := t.Local()
+		if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday || local.Hour() < 9 || local.Hour() >= 18 {
+			return 0
+		}
+		return r * 24 * 7 / workHours
+	case Ramp:
+		if t.After(s.started) {
+			return r * (1 + math.Floor(t.Sub(s.started).Hours()))
+		}
+	}
+	return r
+}
+
+// advance simulates spend up to now in one-minute steps. The caller holds mu.
+func (s *Server) advance(now time.Time) {
+	const step = time.Minute
+	for s.simulated.Before(now) {
+		d := min(step, now.Sub(s.simulated))
+		spend := s.rate(s.simulated) * d.Hours()
+		if spend > 0 {
+			date := s.simulated.UTC().Format(time.DateOnly)
+			day := s.days[date]
+			if day == nil {
+				day = make([]float64, len(models))
+				s.days[date] = day
+			}
+			for i, m := range models {
+				day[i] += spend * m.share
+			}
+		}
+		s.simulated = s.simulated.Add(d)
+	}
+}
+
+type totals struct {
+	total, daily, weekly, monthly float64
+}
+
+// usage returns the spend totals as of now, in UTC calendar periods.
+func (s *Server) usage() totals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.opts.Now().UTC()
+	s.advance(now)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var t totals
+	for date, day := range s.days {
+		d, _ := time.Parse(time.DateOnly, date)
+		var sum float64
+		for _, v := range day {
+			sum += v
+		}
+		t.total += sum
+		if !d.Before(today) {
+			t.daily += sum
+		}
+		if !d.Before(weekStart) {
+			t.weekly += sum
+		}
+		if !d.Before(monthStart) {
+			t.monthly += sum
+		}
+	}
+	return t
+}
+
+func (s *Server) api() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/key", s.authorized(s.handleKey))
+	mux.HandleFunc("/credits", s.authorized(s.handleCredits))
+	mux.HandleFunc("/activity", s.authorized(s.handleActivity))
+	mux.HandleFunc("/keys", s.authorized(s.handleKeys))
+	mux.HandleFunc("/models", s.handleModels)
+	return s.withFaults(mux)
+}
+
+// withFaults applies the injected fault before the real handler runs.
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := s.takeFault()
+		if fault != FaultNone {
+			s.logger.Debug("injecting fault", "fault", fault, "path", r.URL.Path)
+		}
+		switch fault {
+		case FaultUnauthorized:
+			writeError(w, http.StatusUnauthorized, "User not found.")
+			return
+		case FaultRateLimit:
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.opts.RetryAfter.Seconds())))
+			writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		case FaultServerError:
+			writeError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		case FaultMalformed:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data": {"usage": 12.5, "label": `))
+			return
+		case FaultSlow:
+			select {
+			case <-time.After(s.opts.SlowDelay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "No auth credentials found")
+			return
+		}
+		if s.opts.Token != "" && token != s.opts.Token {
+			writeError(w, http.StatusUnauthorized, "User not found.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleKey(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{"data": s.keyData()})
+}
+
+func (s *Server) keyData() map[string]any {
+	t := s.usage()
+	data := map[string]any{
+		"id":              "mock-key",
+		"label":           "mock key",
+		"usage":           round(t.total),
+		"usage_daily":     round(t.daily),
+		"usage_weekly":    round(t.weekly),
+		"usage_monthly":   round(t.monthly),
+		"is_free_tier":    false,
+		"limit":           nil,
+		"limit_remaining": nil,
+	}
+	if s.opts.Limit > 0 {
+		data["limit"] = s.opts.Limit
+		data["limit_remaining"] = round(max(s.opts.Limit-t.total, 0))
+	}
+	return data
+}
+
+func (s *Server) handleCredits(w http.ResponseWriter, _ *http.Request) {
+	t := s.usage()
+	writeJSON(w, map[string]any{"data": map[string]any{
+		"total_credits": s.opts.Credits,
+		"total_usage":   round(t.total),
+	}})
+}
+
+func (s *Server) handleKeys(w http.ResponseWriter, _ *http.Request) {
+	key := s.keyData()
+	created := s.started.AddDate(0, -2, 0).Format(time.RFC3339)
+	writeJSON(w, map[string]any{"data": []map[string]any{
+		{
+			"hash":          "mock-key",
+			"name":          key["label"],
+			"label":         "sk-or-v1-moc...key",
+			"disabled":      false,
+			"created_at":    created,
+			"limit":         key["limit"],
+			"usage":         key["usage"],
+			"usage_daily":   key["usage_daily"],
+			"usage_weekly":  key["usage_weekly"],
+			"usage_monthly": key["usage_monthly"],
+		},
+		{
+			"hash":          "mock-ci-key",
+			"name":          "ci (disabled)",
+			"label":         "sk-or-v1-moc...ci0",
+			"disabled":      true,
+			"created_at":    created,
+			"limit":         nil,
+			"usage":         0,
+			"usage_daily":   0,
+			"usage_weekly":  0,
+			"usage_monthly": 0,
+		},
+	}})
+}
+
+// handleActivity returns per-model rows for the last 30 completed UTC days,
+// or for the day given as ?date=. Like the real endpoint it never reports the
+// current day.
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	s.usage()
+	now := s.opts.Now().UTC()
+	today := now.Format(time.DateOnly)
+	from := now.AddDate(0, 0, -31).Format(time.DateOnly)
+	only := r.URL.Query().Get("date")
+	rows := []map[string]any{}
+	s.mu.Lock()
+	dates := make([]string, 0, len(s.days))
+	for date := range s.days {
+		if date >= today {
+			continue
+		}
+		if (only == "" && date > from) || date == only {
+			dates = append(dates, date)
+		}
+	}
+	slices.Sort(dates)
+	for _, date := range dates {
+		for i, m := range models {
+			spend := s.days[date][i]
+			if spend == 0 {
+				continue
+			}
+			// Three prompt tokens per completion token, 2000 tokens a request.
+			completion := int(spend / (3*m.prompt + m.completion))
+			rows = append(rows, map[string]any{
+				"date":              date,
+				"model":             m.id,
+				"provider_name":     m.provider,
+				"usage":             round(spend),
+				"requests":          max(completion*4/2000, 1),
+				"prompt_tokens":     completion * 3,
+				"completion_tokens": completion,
+				"reasoning_tokens":  0,
+			})
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{"data": rows})
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, _ *http.Request) {
+	data := make([]map[string]any, 0, len(models))
+	for _, m := range models {
+		data = append(data, map[string]any{
+			"id":   m.id,
+			"name": m.name,
+			"pricing": map[string]string{
+				"prompt":     strconv.FormatFloat(m.prompt, 'f', -1, 64),
+				"completion": strconv.FormatFloat(m.completion, 'f', -1, 64),
+			},
+		})
+	}
+	writeJSON(w, map[string]any{"data": data})
+}
+
+// handleFault shows the injected fault on GET and changes it on POST with
+// ?mode=<fault>&count=<n>.
+func (s *Server) handleFault(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		count := 0
+		if raw := r.URL.Query().Get("count"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				http.Error(w, "count must be a number", http.StatusBadRequest)
+				return
+			}
+			count = n
+		}
+		if err := s.SetFault(r.URL.Query().Get("mode"), count); err != nil {
+			http.Error(w, err.Error()+", use one of "+strings.Join(Faults, ", "), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	fault, count := s.fault, s.faultCount
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{"fault": fault, "remaining": count})
+}
+
+// ListenAndServe serves on addr until ctx is done. ready is called with the
+// bound address, which matters for port 0.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, ready func(addr string)) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	if ready != nil {
+		ready(ln.Addr().String())
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": status, "message": message}})
+}
+
+// round keeps amounts at the API's precision.
+func round(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
@@ -0,0 +1,210 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+// testClock is a settable Now.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestServer(t *testing.T, opts Options) (*Server, *openrouter.Client, *testClock) {
+	t.Helper()
+	// Wednesday, so the week started two days ago.
+	clock := &testClock{now: time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)}
+	opts.Now = clock.Now
+	s, err := New(opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, openrouter.NewClient(srv.URL+BasePath, srv.Client(), nil), clock
+}
+
+func near(a, b float64) bool {
+	return a-b < 1e-6 && b-a < 1e-6
+}
+
+func TestUsageGrowsSteadily(t *testing.T) {
+	_, client, clock := newTestServer(t, Options{RatePerHour: 0.5, Limit: 1000})
+	usage, err := client.FetchUsage(context.Background(), "any-token")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	// History starts on February 1st: 28 days of February plus 11.5 days of March.
+	if !near(usage.Total, 0.5*24*(28+11.5)) {
+		t.Fatalf("unexpected total %v", usage.Total)
+	}
+	if !near(*usage.Daily, 6) || !near(*usage.Weekly, 0.5*24*2.5) || !near(*usage.Monthly, 0.5*24*11.5) {
+		t.Fatalf("unexpected periods %v %v %v", *usage.Daily, *usage.Weekly, *usage.Monthly)
+	}
+	if usage.LimitRemaining == nil || !near(*usage.LimitRemaining, 1000-usage.Total) {
+		t.Fatalf("unexpected remaining limit %v", usage.LimitRemaining)
+	}
+
+	clock.Advance(2 * time.Hour)
+	later, err := client.FetchUsage(context.Background(), "any-token")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !near(later.Total-usage.Total, 1) || !near(*later.Daily, 7) {
+		t.Fatalf("expected one dollar over two hours, got %v -> %v", usage.Total, later.Total)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	start := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		pattern string
+		at      time.Time
+		want    float64
+	}{
+		{Bursty, start.Add(5 * time.Minute), 6},
+		{Bursty, start.Add(30 * time.Minute), 0},
+		{Ramp, start.Add(-time.Hour), 1},
+		{Ramp, start.Add(150 * time.Minute), 3},
+		{Workday, time.Date(2025, 3, 15, 12, 0, 0, 0, time.Local), 0},
+		{Workday, time.Date(2025, 3, 12, 3, 0, 0, 0, time.Local), 0},
+		{Workday, time.Date(2025, 3, 12, 10, 0, 0, 0, time.Local), 24 * 7.0 / 45},
+	}
+	for _, tc := range cases {
+		s, err := New(Options{Pattern: tc.pattern, RatePerHour: 1, Now: func() time.Time { return start }}, nil)
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		if got := s.rate(tc.at); !near(got, tc.want) {
+			t.Fatalf("%s at %v: expected %v, got %v", tc.pattern, tc.at, tc.want, got)
+		}
+	}
+	if _, err := New(Options{Pattern: "random"}, nil); err == nil {
+		t.Fatalf("expected unknown pattern to fail")
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	_, client, _ := newTestServer(t, Options{RatePerHour: 1, Credits: 2000, Token: "secret"})
+	ctx := context.Background()
+	if _, err := client.FetchUsage(ctx, "wrong"); !errors.Is(err, openrouter.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized for a wrong token, got %v", err)
+	}
+	credits, err := client.FetchCredits(ctx, "secret")
+	if err != nil || credits.Total != 2000 || credits.Usage <= 0 {
+		t.Fatalf("unexpected credits %+v %v", credits, err)
+	}
+	activity, err := client.FetchActivity(ctx, "secret", "2025-03-11")
+	if err != nil || len(activity) != len(models) {
+		t.Fatalf("unexpected activity %+v %v", activity, err)
+	}
+	var day float64
+	for _, item := range activity {
+		day += item.Usage
+		if item.Date != "2025-03-11" || item.Requests == 0 || item.PromptTokens == 0 {
+			t.Fatalf("unexpected activity row %+v", item)
+		}
+	}
+	if !near(day, 24) {
+		t.Fatalf("expected a full day of spend, got %v", day)
+	}
+	all, err := client.FetchActivity(ctx, "secret", "")
+	if err != nil || len(all) == 0 {
+		t.Fatalf("unexpected activity %+v %v", all, err)
+	}
+	for _, item := range all {
+		if item.Date >= "2025-03-12" {
+			t.Fatalf("expected the current UTC day to be left out, got %+v", item)
+		}
+	}
+	if today, err := client.FetchActivity(ctx, "secret", "2025-03-12"); err != nil || len(today) != 0 {
+		t.Fatalf("expected no activity for the current day, got %+v %v", today, err)
+	}
+	keys, err := client.ListKeys(ctx, "secret")
+	if err != nil || len(keys) != 2 || keys[0].Usage.Total <= 0 || !keys[1].Disabled {
+		t.Fatalf("unexpected keys %+v %v", keys, err)
+	}
+	list, err := client.FetchModels(ctx, "")
+	if err != nil || len(list) != len(models) || list[0].PromptPrice == 0 {
+		t.Fatalf("unexpected models %+v %v", list, err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s, client, _ := newTestServer(t, Options{RatePerHour: 1, SlowDelay: 200 * time.Millisecond, RetryAfter: 7 * time.Second})
+	ctx := context.Background()
+
+	if err := s.SetFault(FaultRateLimit, 1); err != nil {
+		t.Fatalf("set fault: %v", err)
+	}
+	var apiErr *openrouter.APIError
+	if _, err := client.FetchUsage(ctx, "token"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %v", err)
+	}
+	if _, err := client.FetchUsage(ctx, "token"); err != nil {
+		t.Fatalf("expected the fault to be used up, got %v", err)
+	}
+
+	_ = s.SetFault(FaultMalformed, 0)
+	for i := 0; i < 2; i++ {
+		if _, err := client.FetchUsage(ctx, "token"); err == nil {
+			t.Fatalf("expected malformed JSON to fail")
+		}
+	}
+	_ = s.SetFault(FaultUnauthorized, 0)
+	if _, err := client.FetchUsage(ctx, "token"); !errors.Is(err, openrouter.ErrUnauthorized) {
+		t.Fatalf("expected 401, got %v", err)
+	}
+
+	_ = s.SetFault(FaultSlow, 1)
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, err := client.FetchUsage(shortCtx, "token")
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected slow response to time out, got %v", err)
+	}
+	if err := s.SetFault("teapot", 0); err == nil {
+		t.Fatalf("expected unknown fault to fail")
+	}
+}
+
+func TestFaultControlEndpoint(t *testing.T) {
+	s, _, _ := newTestServer(t, Options{RetryAfter: 7 * time.Second})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_mock/fault?mode=429&count=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, BasePath+"/models", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "7" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_mock/fault?mode=bogus", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an unknown fault, got %d", rec.Code)
+	}
+}
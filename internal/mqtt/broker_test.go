@@ -0,0 +1,206 @@
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBroker is a minimal in-process MQTT 3.1.1 broker that accepts
+// CONNECT, PUBLISH and PINGREQ and keeps retained messages.
+type fakeBroker struct {
+	t        *testing.T
+	listener net.Listener
+	username string
+	password string
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	connects []connectPacket
+	retained map[string]string
+	changed  chan struct{}
+}
+
+func newFakeBroker(t *testing.T, tlsCfg *tls.Config) *fakeBroker {
+	t.Helper()
+	var listener net.Listener
+	var err error
+	if tlsCfg != nil {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsCfg)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	b := &fakeBroker{t: t, listener: listener, retained: map[string]string{}, changed: make(chan struct{}, 1)}
+	go b.accept()
+	t.Cleanup(b.close)
+	return b
+}
+
+func (b *fakeBroker) addr() string {
+	return b.listener.Addr().String()
+}
+
+func (b *fakeBroker) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns = append(b.conns, conn)
+		b.mu.Unlock()
+		go b.serve(conn)
+	}
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	first, err := readPacket(reader)
+	if err != nil || first.typ != typeConnect {
+		return
+	}
+	connect, err := parseConnect(first.body)
+	if err != nil {
+		b.t.Errorf("parse connect: %v", err)
+		return
+	}
+	code := byte(0)
+	if b.username != "" && (connect.username != b.username || connect.password != b.password) {
+		code = 4
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, connect)
+	b.mu.Unlock()
+	if _, err := conn.Write([]byte{typeConnack << 4, 2, 0, code}); err != nil || code != 0 {
+		return
+	}
+	for {
+		pkt, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch pkt.typ {
+		case typePublish:
+			topic, payload, err := readString(pkt.body)
+			if err != nil {
+				b.t.Errorf("parse publish: %v", err)
+				return
+			}
+			if pkt.flags&0x01 == 0 {
+				b.t.Errorf("expected retained publish on %s", topic)
+			}
+			b.mu.Lock()
+			b.retained[topic] = string(payload)
+			b.mu.Unlock()
+			select {
+			case b.changed <- struct{}{}:
+			default:
+			}
+		case typePingreq:
+			_, _ = conn.Write([]byte{typePingresp << 4, 0})
+		case typeDisconnect:
+			return
+		}
+	}
+}
+
+func parseConnect(body []byte) (connectPacket, error) {
+	_, rest, err := readString(body)
+	if err != nil {
+		return connectPacket{}, err
+	}
+	flags := rest[1]
+	var c connectPacket
+	c.keepAlive = uint16(rest[2])<<8 | uint16(rest[3])
+	rest = rest[4:]
+	if c.clientID, rest, err = readString(rest); err != nil {
+		return c, err
+	}
+	if flags&0x04 != 0 {
+		var payload string
+		if c.willTopic, rest, err = readString(rest); err != nil {
+			return c, err
+		}
+		if payload, rest, err = readString(rest); err != nil {
+			return c, err
+		}
+		c.willPayload = []byte(payload)
+		c.willRetain = flags&0x20 != 0
+	}
+	if flags&0x80 != 0 {
+		if c.username, rest, err = readString(rest); err != nil {
+			return c, err
+		}
+	}
+	if flags&0x40 != 0 {
+		if c.password, _, err = readString(rest); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// dropClients closes every client connection to simulate a broker restart.
+func (b *fakeBroker) dropClients() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+func (b *fakeBroker) close() {
+	b.listener.Close()
+	b.dropClients()
+}
+
+func (b *fakeBroker) connectCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.connects)
+}
+
+// waitFor polls until cond holds on the retained messages.
+func (b *fakeBroker) waitFor(cond func(map[string]string) bool) map[string]string {
+	b.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		b.mu.Lock()
+		snapshot := make(map[string]string, len(b.retained))
+		for k, v := range b.retained {
+			snapshot[k] = v
+		}
+		b.mu.Unlock()
+		if cond(snapshot) {
+			return snapshot
+		}
+		select {
+		case <-b.changed:
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			b.t.Fatalf("timed out waiting for broker state, have %v", snapshot)
+		}
+	}
+}
+
+// readString reads a length-prefixed string and returns the rest of b.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
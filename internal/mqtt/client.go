@@ -0,0 +1,213 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const writeTimeout = 10 * time.Second
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Options describes how to reach a broker. Broker is a URL with a tcp, mqtt,
+// ssl, tls or mqtts scheme; the TLS schemes default to port 8883.
+type Options struct {
+	Broker      string
+	ClientID    string
+	Username    string
+	Password    string
+	TLS         *tls.Config
+	KeepAlive   time.Duration
+	WillTopic   string
+	WillPayload []byte
+	WillRetain  bool
+}
+
+// Conn is a connected MQTT 3.1.1 session that publishes at QoS 0.
+type Conn struct {
+	conn net.Conn
+	wmu  sync.Mutex
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// Dial connects to the broker and completes the CONNECT handshake.
+func Dial(ctx context.Context, opts Options) (*Conn, error) {
+	addr, useTLS, err := brokerAddr(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	if useTLS {
+		tlsCfg := opts.TLS
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsCfg}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive := opts.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = 60 * time.Second
+	}
+	connect := connectPacket{
+		clientID:    opts.ClientID,
+		username:    opts.Username,
+		password:    opts.Password,
+		keepAlive:   uint16(keepAlive / time.Second),
+		willTopic:   opts.WillTopic,
+		willPayload: opts.WillPayload,
+		willRetain:  opts.WillRetain,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(writeTimeout))
+	}
+	reader := bufio.NewReader(conn)
+	if _, err := conn.Write(connect.encode()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ack, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read connack: %w", err)
+	}
+	if ack.typ != typeConnack || len(ack.body) != 2 {
+		conn.Close()
+		return nil, errors.New("unexpected reply to connect")
+	}
+	if code := ack.body[1]; code != 0 {
+		conn.Close()
+		if msg, ok := connackErrors[code]; ok {
+			return nil, fmt.Errorf("connection refused: %s", msg)
+		}
+		return nil, fmt.Errorf("connection refused: code %d", code)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	c := &Conn{conn: conn, done: make(chan struct{})}
+	go c.readLoop(reader)
+	go c.pingLoop(keepAlive)
+	return c, nil
+}
+
+func brokerAddr(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, err
+	}
+	var useTLS bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", false, errors.New("broker host is empty")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// Publish sends a QoS 0 message.
+func (c *Conn) Publish(topic string, payload []byte, retain bool) error {
+	return c.write(encodePublish(topic, payload, retain))
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended.
+func (c *Conn) Err() error {
+	<-c.done
+	return c.err
+}
+
+// Close sends DISCONNECT and closes the connection.
+func (c *Conn) Close() error {
+	_ = c.write(encodePacket(typeDisconnect<<4, nil))
+	c.fail(errors.New("connection closed"))
+	return nil
+}
+
+func (c *Conn) write(data []byte) error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Conn) fail(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// readLoop drains incoming packets. The broker only sends PINGRESP to a
+// publish-only client; a missing reply is caught by the read deadline.
+func (c *Conn) readLoop(r *bufio.Reader) {
+	for {
+		if _, err := readPacket(r); err != nil {
+			c.fail(err)
+			return
+		}
+	}
+}
+
+func (c *Conn) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			_ = c.conn.SetReadDeadline(time.Now().Add(keepAlive))
+			if err := c.write(encodePacket(typePingreq<<4, nil)); err != nil {
+				return
+			}
+		}
+	}
+}
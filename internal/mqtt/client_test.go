@@ -0,0 +1,98 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemainingLengthRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		encoded := encodePacket(typePublish<<4, make([]byte, n))
+		pkt, err := readPacket(bufio.NewReader(bytes.NewReader(encoded)))
+		if err != nil {
+			t.Fatalf("%d: read failed: %v", n, err)
+		}
+		if pkt.typ != typePublish || len(pkt.body) != n {
+			t.Fatalf("%d: unexpected packet type %d len %d", n, pkt.typ, len(pkt.body))
+		}
+	}
+}
+
+func TestBrokerAddr(t *testing.T) {
+	cases := []struct {
+		broker, addr string
+		tls          bool
+	}{
+		{"tcp://localhost", "localhost:1883", false},
+		{"mqtt://10.0.0.2:1884", "10.0.0.2:1884", false},
+		{"ssl://broker.local", "broker.local:8883", true},
+		{"mqtts://broker.local:9000", "broker.local:9000", true},
+	}
+	for _, tc := range cases {
+		addr, useTLS, err := brokerAddr(tc.broker)
+		if err != nil || addr != tc.addr || useTLS != tc.tls {
+			t.Fatalf("%s: got %q %v %v", tc.broker, addr, useTLS, err)
+		}
+	}
+	if _, _, err := brokerAddr("http://localhost"); err == nil {
+		t.Fatalf("expected unsupported scheme error")
+	}
+}
+
+func TestDialCredentials(t *testing.T) {
+	broker := newFakeBroker(t, nil)
+	broker.username, broker.password = "user", "secret"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := Dial(ctx, Options{Broker: "tcp://" + broker.addr(), ClientID: "c", Username: "user", Password: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+
+	conn, err := Dial(ctx, Options{Broker: "tcp://" + broker.addr(), ClientID: "c", Username: "user", Password: "secret", WillTopic: "p/status", WillPayload: []byte("offline"), WillRetain: true})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Publish("p/value", []byte("1.5"), true); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	broker.waitFor(func(m map[string]string) bool { return m["p/value"] == "1.5" })
+
+	broker.mu.Lock()
+	connect := broker.connects[len(broker.connects)-1]
+	broker.mu.Unlock()
+	if connect.willTopic != "p/status" || string(connect.willPayload) != "offline" || !connect.willRetain {
+		t.Fatalf("unexpected will: %+v", connect)
+	}
+}
+
+func TestDialTLS(t *testing.T) {
+	// Borrow the httptest certificate for the broker.
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	broker := newFakeBroker(t, &tls.Config{Certificates: srv.TLS.Certificates, MinVersion: tls.VersionTLS12})
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, port, _ := strings.Cut(broker.addr(), ":")
+	if _, err := Dial(ctx, Options{Broker: "ssl://127.0.0.1:" + port, ClientID: "c"}); err == nil {
+		t.Fatalf("expected untrusted certificate to fail")
+	}
+	conn, err := Dial(ctx, Options{Broker: "ssl://127.0.0.1:" + port, ClientID: "c", TLS: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}})
+	if err != nil {
+		t.Fatalf("tls dial failed: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,139 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1 used by this client.
+const (
+	typeConnect    byte = 1
+	typeConnack    byte = 2
+	typePublish    byte = 3
+	typePingreq    byte = 12
+	typePingresp   byte = 13
+	typeDisconnect byte = 14
+)
+
+const maxRemainingLength = 268435455
+
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+type connectPacket struct {
+	clientID    string
+	username    string
+	password    string
+	keepAlive   uint16
+	willTopic   string
+	willPayload []byte
+	willRetain  bool
+}
+
+func (c connectPacket) encode() []byte {
+	var flags byte = 0x02 // clean session
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	if c.willTopic != "" {
+		flags |= 0x04
+		if c.willRetain {
+			flags |= 0x20
+		}
+	}
+	if c.username != "" {
+		flags |= 0x80
+		if c.password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, c.keepAlive)
+	body = appendString(body, c.clientID)
+	if c.willTopic != "" {
+		body = appendString(body, c.willTopic)
+		body = appendBytes(body, c.willPayload)
+	}
+	if c.username != "" {
+		body = appendString(body, c.username)
+		if c.password != "" {
+			body = appendString(body, c.password)
+		}
+	}
+	return encodePacket(typeConnect<<4, body)
+}
+
+func encodePublish(topic string, payload []byte, retain bool) []byte {
+	header := typePublish << 4
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(make([]byte, 0, 2+len(topic)+len(payload)), topic)
+	body = append(body, payload...)
+	return encodePacket(header, body)
+}
+
+func encodePacket(header byte, body []byte) []byte {
+	out := make([]byte, 0, len(body)+5)
+	out = append(out, header)
+	out = appendLength(out, len(body))
+	return append(out, body...)
+}
+
+func appendLength(out []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+func appendString(out []byte, s string) []byte {
+	return appendBytes(out, []byte(s))
+}
+
+func appendBytes(out, b []byte) []byte {
+	out = binary.BigEndian.AppendUint16(out, uint16(len(b)))
+	return append(out, b...)
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxRemainingLength {
+		return packet{}, fmt.Errorf("packet too large: %d bytes", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{typ: header >> 4, flags: header & 0x0f, body: body}, nil
+}
@@ -0,0 +1,335 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/redact"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 2 * time.Minute
+	// noError is published as last_error after a successful refresh. An empty
+	// retained payload would delete the topic instead.
+	noError = "none"
+)
+
+// sensor is one published value and its Home Assistant discovery settings.
+type sensor struct {
+	key         string
+	name        string
+	unit        string
+	deviceClass string
+	stateClass  string
+	diagnostic  bool
+}
+
+var sensors = []sensor{
+	{key: "total_usage", name: "Total usage", unit: "USD", deviceClass: "monetary", stateClass: "total"},
+	{key: "daily_usage", name: "Daily usage", unit: "USD", deviceClass: "monetary", stateClass: "total"},
+	{key: "weekly_usage", name: "Weekly usage", unit: "USD", deviceClass: "monetary", stateClass: "total"},
+	{key: "monthly_usage", name: "Monthly usage", unit: "USD", deviceClass: "monetary", stateClass: "total"},
+	{key: "remaining_credit", name: "Remaining credit", unit: "USD", deviceClass: "monetary", stateClass: "total"},
+	{key: "last_error", name: "Last error", diagnostic: true},
+	{key: "last_update", name: "Last update", deviceClass: "timestamp", diagnostic: true},
+}
+
+var nodeIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Publisher keeps the latest values as retained topics on a broker,
+// reconnecting with backoff and republishing everything after each reconnect.
+type Publisher struct {
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	cfg     config.MQTTConfig
+	values  map[string]string
+	dirty   map[string]bool
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	wake    chan struct{}
+}
+
+func NewPublisher(logger *slog.Logger) *Publisher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Publisher{
+		logger: logger,
+		values: map[string]string{},
+		dirty:  map[string]bool{},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// HandleEvent records refresh results and applies config changes.
+func (p *Publisher) HandleEvent(ev events.Event) {
+	switch e := ev.(type) {
+	case events.RefreshSucceeded:
+		values := map[string]string{
+			"total_usage": formatFloat(e.Usage.Total),
+			"last_error":  noError,
+			"last_update": e.At.UTC().Format(time.RFC3339),
+		}
+		setOptional(values, "daily_usage", e.Usage.Daily)
+		setOptional(values, "weekly_usage", e.Usage.Weekly)
+		setOptional(values, "monthly_usage", e.Usage.Monthly)
+		if e.Credits != nil {
+			values["remaining_credit"] = formatFloat(e.Credits.Remaining())
+		} else {
+			setOptional(values, "remaining_credit", e.Usage.LimitRemaining)
+		}
+		p.set(values)
+	case events.RefreshFailed:
+		if e.Err != nil {
+			p.set(map[string]string{"last_error": redact.String(e.Err.Error())})
+		}
+	case events.ConfigChanged:
+		p.Apply(e.Config.MQTT)
+	}
+}
+
+func setOptional(values map[string]string, key string, value *float64) {
+	if value != nil {
+		values[key] = formatFloat(*value)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (p *Publisher) set(values map[string]string) {
+	p.mu.Lock()
+	for key, value := range values {
+		if old, ok := p.values[key]; ok && old == value {
+			continue
+		}
+		p.values[key] = value
+		p.dirty[key] = true
+	}
+	p.mu.Unlock()
+	p.signal()
+}
+
+func (p *Publisher) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Apply starts, restarts or stops the connection for cfg.
+func (p *Publisher) Apply(cfg config.MQTTConfig) {
+	p.mu.Lock()
+	if p.cancel != nil && cfg == p.cfg {
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.Stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg = cfg
+	if !cfg.Enabled {
+		return
+	}
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		p.logger.Error("mqtt tls config failed", "error", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.stopped = make(chan struct{})
+	go p.run(ctx, cfg, tlsCfg, p.stopped)
+}
+
+// Stop disconnects and waits for the connection loop to exit.
+func (p *Publisher) Stop() {
+	p.mu.Lock()
+	cancel, stopped := p.cancel, p.stopped
+	p.cancel, p.stopped = nil, nil
+	p.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-stopped
+}
+
+func (p *Publisher) run(ctx context.Context, cfg config.MQTTConfig, tlsCfg *tls.Config, stopped chan struct{}) {
+	defer close(stopped)
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = randomClientID()
+	}
+	status := cfg.TopicPrefix + "/status"
+	backoff := minBackoff
+	for {
+		conn, err := Dial(ctx, Options{
+			Broker:      cfg.Broker,
+			ClientID:    clientID,
+			Username:    cfg.Username,
+			Password:    cfg.Password,
+			TLS:         tlsCfg,
+			WillTopic:   status,
+			WillPayload: []byte("offline"),
+			WillRetain:  true,
+		})
+		if err == nil {
+			p.logger.Info("mqtt connected", "broker", cfg.Broker)
+			backoff = minBackoff
+			err = p.serve(ctx, conn, cfg)
+			if ctx.Err() != nil {
+				_ = conn.Publish(status, []byte("offline"), true)
+				conn.Close()
+				return
+			}
+			conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Warn("mqtt connection failed", "error", err, "broker", cfg.Broker, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// serve publishes discovery configs and all known values, then publishes
+// changes until the connection drops or ctx is cancelled.
+func (p *Publisher) serve(ctx context.Context, conn *Conn, cfg config.MQTTConfig) error {
+	if cfg.Discovery {
+		for _, s := range sensors {
+			topic, payload := discoveryConfig(cfg, s)
+			if err := conn.Publish(topic, payload, true); err != nil {
+				return err
+			}
+		}
+	}
+	if err := conn.Publish(cfg.TopicPrefix+"/status", []byte("online"), true); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	for key := range p.values {
+		p.dirty[key] = true
+	}
+	p.mu.Unlock()
+	for {
+		if err := p.flush(conn, cfg.TopicPrefix); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-conn.Done():
+			return conn.Err()
+		case <-p.wake:
+		}
+	}
+}
+
+func (p *Publisher) flush(conn *Conn, prefix string) error {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.dirty))
+	for key := range p.dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = p.values[key]
+	}
+	p.mu.Unlock()
+
+	for i, key := range keys {
+		if err := conn.Publish(prefix+"/"+key, []byte(values[i]), true); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		if p.values[key] == values[i] {
+			delete(p.dirty, key)
+		}
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func discoveryConfig(cfg config.MQTTConfig, s sensor) (string, []byte) {
+	nodeID := nodeIDPattern.ReplaceAllString(cfg.TopicPrefix, "_")
+	payload := map[string]any{
+		"name":               s.name,
+		"unique_id":          nodeID + "_" + s.key,
+		"object_id":          nodeID + "_" + s.key,
+		"state_topic":        cfg.TopicPrefix + "/" + s.key,
+		"availability_topic": cfg.TopicPrefix + "/status",
+		"device": map[string]any{
+			"identifiers": []string{nodeID},
+			"name":        "OpenRouter Costs",
+			"model":       "openrouter-costs-tray",
+		},
+	}
+	if s.unit != "" {
+		payload["unit_of_measurement"] = s.unit
+	}
+	if s.deviceClass != "" {
+		payload["device_class"] = s.deviceClass
+	}
+	if s.stateClass != "" {
+		payload["state_class"] = s.stateClass
+	}
+	if s.diagnostic {
+		payload["entity_category"] = "diagnostic"
+	}
+	data, _ := json.Marshal(payload)
+	return cfg.DiscoveryPrefix + "/sensor/" + nodeID + "/" + s.key + "/config", data
+}
+
+func tlsConfig(cfg config.MQTTConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//nolint:gosec // explicitly requested for self-signed brokers
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile == "" {
+		return tlsCfg, nil
+	}
+	//nolint:gosec // path comes from config
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + cfg.CAFile)
+	}
+	tlsCfg.RootCAs = pool
+	return tlsCfg, nil
+}
+
+func randomClientID() string {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return "openrouter-costs-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,86 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestPublisherPublishesRetainedValues(t *testing.T) {
+	broker := newFakeBroker(t, nil)
+	cfg := config.DefaultConfig().MQTT
+	cfg.Enabled = true
+	cfg.Broker = "tcp://" + broker.addr()
+	cfg.TopicPrefix = "office/openrouter"
+
+	p := NewPublisher(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer p.Stop()
+	daily := 1.25
+	at := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	p.HandleEvent(events.RefreshSucceeded{
+		At:      at,
+		Usage:   openrouter.Usage{Total: 12.5, Daily: &daily},
+		Credits: &openrouter.Credits{Total: 20, Usage: 12.5},
+	})
+	p.Apply(cfg)
+
+	got := broker.waitFor(func(m map[string]string) bool {
+		return m["office/openrouter/total_usage"] != "" && m["office/openrouter/last_update"] != ""
+	})
+	if got["office/openrouter/total_usage"] != "12.5" || got["office/openrouter/daily_usage"] != "1.25" {
+		t.Fatalf("unexpected usage topics: %v", got)
+	}
+	if got["office/openrouter/remaining_credit"] != "7.5" || got["office/openrouter/last_update"] != "2024-05-02T10:00:00Z" {
+		t.Fatalf("unexpected credit or update topics: %v", got)
+	}
+	if _, ok := got["office/openrouter/weekly_usage"]; ok {
+		t.Fatalf("expected missing weekly usage not to be published")
+	}
+	if got["office/openrouter/last_error"] != noError {
+		t.Fatalf("expected %q last error after success, got %q", noError, got["office/openrouter/last_error"])
+	}
+	if got["office/openrouter/status"] != "online" {
+		t.Fatalf("expected online status, got %q", got["office/openrouter/status"])
+	}
+
+	var discovery map[string]any
+	if err := json.Unmarshal([]byte(got["homeassistant/sensor/office_openrouter/total_usage/config"]), &discovery); err != nil {
+		t.Fatalf("expected discovery config: %v", err)
+	}
+	if discovery["state_topic"] != "office/openrouter/total_usage" || discovery["device_class"] != "monetary" || discovery["unit_of_measurement"] != "USD" {
+		t.Fatalf("unexpected discovery config: %v", discovery)
+	}
+
+	p.HandleEvent(events.RefreshFailed{At: at, Err: errors.New("boom")})
+	broker.waitFor(func(m map[string]string) bool { return m["office/openrouter/last_error"] == "boom" })
+}
+
+func TestPublisherReconnects(t *testing.T) {
+	broker := newFakeBroker(t, nil)
+	cfg := config.DefaultConfig().MQTT
+	cfg.Enabled = true
+	cfg.Broker = "tcp://" + broker.addr()
+	cfg.Discovery = false
+
+	p := NewPublisher(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Apply(cfg)
+	p.HandleEvent(events.RefreshSucceeded{At: time.Now(), Usage: openrouter.Usage{Total: 1}})
+	broker.waitFor(func(m map[string]string) bool { return m["openrouter_costs/total_usage"] == "1" })
+
+	broker.dropClients()
+	p.HandleEvent(events.RefreshSucceeded{At: time.Now(), Usage: openrouter.Usage{Total: 2}})
+	broker.waitFor(func(m map[string]string) bool { return m["openrouter_costs/total_usage"] == "2" })
+	if broker.connectCount() < 2 {
+		t.Fatalf("expected a reconnect, got %d connects", broker.connectCount())
+	}
+
+	p.HandleEvent(events.ConfigChanged{Config: config.Config{MQTT: config.MQTTConfig{Enabled: false}}})
+	broker.waitFor(func(m map[string]string) bool { return m["openrouter_costs/status"] == "offline" })
+}
@@ -1,18 +1,29 @@
 package notify
 
 import (
+	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 
+	"openrouter-costs-tray/internal/buildinfo"
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
+	"openrouter-costs-tray/internal/redact"
+	"openrouter-costs-tray/internal/state"
 	"openrouter-costs-tray/internal/util"
 )
 
 const errorNotifyInterval = 10 * time.Minute
 
+// driftNotifiedKey stores the app version schema drift was last notified for.
+const driftNotifiedKey = "schema_drift_notified_version"
+
 type Notifier struct {
 	app         fyne.App
 	logger      *slog.Logger
@@ -34,6 +45,78 @@ func (n *Notifier) UpdateConfig(cfg config.NotificationsConfig) {
 	n.mu.Unlock()
 }
 
+// HandleEvent turns bus events into desktop notifications.
+func (n *Notifier) HandleEvent(ev events.Event) {
+	switch e := ev.(type) {
+	case events.RefreshSucceeded:
+		if e.Delta > 0 {
+			n.NotifyUpdateSpent(e.Delta)
+		}
+	case events.RefreshFailed:
+		// Being offline is shown in the tooltip; it is not worth a popup.
+		if !e.Offline {
+			n.NotifyError(e.Err)
+		}
+	case events.KeysChanged:
+		n.NotifyKeysChanged(e.Added, e.Removed, e.Disabled)
+	case events.PricesChanged:
+		n.NotifyPriceChanges(e.Changes)
+	case events.StalenessChanged:
+		if e.Level == state.Stale {
+			n.NotifyStale(e.LastSuccessAt)
+		}
+	case events.SchemaDrift:
+		if len(e.Issues) > 0 {
+			n.NotifySchemaDrift(e.Issues)
+		}
+	case events.ConfigChanged:
+		n.UpdateConfig(e.Config.Notifications)
+	}
+}
+
+// NotifyPriceChanges reports price changes of watched models.
+func (n *Notifier) NotifyPriceChanges(changes []pricing.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	n.mu.RLock()
+	cfg := n.cfg
+	n.mu.RUnlock()
+	if !cfg.Enabled || !cfg.OnPriceChange {
+		return
+	}
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, pricing.Describe(change))
+	}
+	n.send("OpenRouter Costs", strings.Join(lines, "\n"))
+}
+
+// NotifyKeysChanged reports keys that appeared, were removed or were disabled
+// on the account.
+func (n *Notifier) NotifyKeysChanged(added, removed, disabled []openrouter.KeyInfo) {
+	if len(added) == 0 && len(removed) == 0 && len(disabled) == 0 {
+		return
+	}
+	n.mu.RLock()
+	cfg := n.cfg
+	n.mu.RUnlock()
+	if !cfg.Enabled || !cfg.OnKeyChange {
+		return
+	}
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "New keys: "+keyNames(added))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "Removed keys: "+keyNames(removed))
+	}
+	if len(disabled) > 0 {
+		parts = append(parts, "Disabled keys: "+keyNames(disabled))
+	}
+	n.send("OpenRouter Costs", strings.Join(parts, "\n"))
+}
+
 func (n *Notifier) NotifyUpdateSpent(amount float64) {
 	n.mu.RLock()
 	cfg := n.cfg
@@ -62,7 +145,40 @@ func (n *Notifier) NotifyError(err error) {
 	n.lastErrorAt = time.Now()
 	n.mu.Unlock()
 
-	n.send("OpenRouter Costs", "Error: "+err.Error()+" (retrying on schedule)")
+	n.send("OpenRouter Costs", "Error: "+redact.String(err.Error())+" (retrying on schedule)")
+}
+
+// NotifyStale reports that no refresh succeeded for several periods.
+func (n *Notifier) NotifyStale(lastSuccess time.Time) {
+	n.mu.RLock()
+	cfg := n.cfg
+	n.mu.RUnlock()
+	if !cfg.Enabled || !cfg.OnStale {
+		return
+	}
+	n.send("OpenRouter Costs", "Usage data is stale, last updated "+util.FormatAge(time.Since(lastSuccess)))
+}
+
+// NotifySchemaDrift warns that API responses changed shape. It fires once
+// per app version, since only an update can fix the parser.
+func (n *Notifier) NotifySchemaDrift(issues []string) {
+	n.mu.RLock()
+	cfg := n.cfg
+	n.mu.RUnlock()
+	if !cfg.Enabled || !cfg.OnSchemaDrift || n.app == nil {
+		return
+	}
+	version := buildinfo.Version()
+	prefs := n.app.Preferences()
+	if prefs.String(driftNotifiedKey) == version {
+		return
+	}
+	prefs.SetString(driftNotifiedKey, version)
+	content := "OpenRouter API response changed, numbers may be wrong until the app is updated: " + issues[0]
+	if len(issues) > 1 {
+		content += fmt.Sprintf(" (+%d more)", len(issues)-1)
+	}
+	n.send("OpenRouter Costs", content)
 }
 
 func (n *Notifier) NotifyStartSummary(content string) {
@@ -78,6 +194,18 @@ func (n *Notifier) NotifyStartSummary(content string) {
 	n.send("OpenRouter Costs", content)
 }
 
+func keyNames(keys []openrouter.KeyInfo) string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name := key.Name
+		if name == "" {
+			name = key.Label
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
 func (n *Notifier) send(title, content string) {
 	if n.app == nil {
 		n.logger.Warn("notification dropped: no app", "title", title, "content", content)
@@ -11,6 +11,10 @@ import (
 	"fyne.io/fyne/v2/test"
 
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
+	"openrouter-costs-tray/internal/state"
 	"openrouter-costs-tray/internal/util"
 )
 
@@ -86,3 +90,137 @@ func TestNotifyErrorRespectsInterval(t *testing.T) {
 		n.NotifyError(errors.New("boom"))
 	})
 }
+
+func TestHandleEvent(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnUpdateSpent: true, OnError: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	spent := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "Recently spent: " + util.FormatUSD(0.5),
+	}
+	test.AssertNotificationSent(t, spent, func() {
+		n.HandleEvent(events.RefreshSucceeded{Delta: 0.5})
+	})
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(events.RefreshSucceeded{Delta: 0})
+	})
+
+	failed := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "Error: boom (retrying on schedule)",
+	}
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(events.RefreshFailed{Err: errors.New("dial tcp: connection refused"), Offline: true})
+	})
+	test.AssertNotificationSent(t, failed, func() {
+		n.HandleEvent(events.RefreshFailed{Err: errors.New("boom")})
+	})
+
+	disabled := config.DefaultConfig()
+	disabled.Notifications.Enabled = false
+	n.HandleEvent(events.ConfigChanged{Config: disabled})
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(events.RefreshSucceeded{Delta: 1})
+	})
+}
+
+func TestNotifyStale(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnStale: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	last := time.Now().Add(-3*time.Hour - time.Minute)
+
+	stale := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "Usage data is stale, last updated 3h ago",
+	}
+	test.AssertNotificationSent(t, stale, func() {
+		n.HandleEvent(events.StalenessChanged{Level: state.Stale, LastSuccessAt: last})
+	})
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(events.StalenessChanged{Level: state.Late, LastSuccessAt: last})
+	})
+	n.UpdateConfig(config.NotificationsConfig{Enabled: true})
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(events.StalenessChanged{Level: state.Stale, LastSuccessAt: last})
+	})
+}
+
+func TestNotifySchemaDriftOncePerVersion(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnSchemaDrift: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	drift := events.SchemaDrift{Endpoint: "/auth/key", Issues: []string{"$.data: missing", "$.key.usage: read through fallback"}}
+
+	expected := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "OpenRouter API response changed, numbers may be wrong until the app is updated: $.data: missing (+1 more)",
+	}
+	test.AssertNotificationSent(t, expected, func() {
+		n.HandleEvent(drift)
+	})
+	test.AssertNotificationSent(t, nil, func() {
+		n.HandleEvent(drift)
+	})
+	// A new version warns again.
+	app.Preferences().SetString(driftNotifiedKey, "v0.0.1")
+	test.AssertNotificationSent(t, expected, func() {
+		n.HandleEvent(drift)
+	})
+}
+
+func TestNotifyKeysChanged(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnKeyChange: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	expected := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "New keys: ci, sk-or-v1-abc\nRemoved keys: old\nDisabled keys: dev",
+	}
+	test.AssertNotificationSent(t, expected, func() {
+		n.HandleEvent(events.KeysChanged{
+			Added:    []openrouter.KeyInfo{{Name: "ci"}, {Label: "sk-or-v1-abc"}},
+			Removed:  []openrouter.KeyInfo{{Name: "old"}},
+			Disabled: []openrouter.KeyInfo{{Name: "dev"}},
+		})
+	})
+
+	n.UpdateConfig(config.NotificationsConfig{Enabled: true})
+	test.AssertNotificationSent(t, nil, func() {
+		n.NotifyKeysChanged([]openrouter.KeyInfo{{Name: "ci"}}, nil, nil)
+	})
+}
+
+func TestNotifyPriceChanges(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnPriceChange: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	expected := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "Free variant available: a:free\nModel removed: b",
+	}
+	test.AssertNotificationSent(t, expected, func() {
+		n.HandleEvent(events.PricesChanged{Changes: []pricing.Change{
+			{Kind: pricing.ChangeFreeVariant, Model: "a:free"},
+			{Kind: pricing.ChangeRemoved, Model: "b"},
+		}})
+	})
+}
+
+func TestNotifyErrorRedactsKeys(t *testing.T) {
+	app := test.NewApp()
+	cfg := config.NotificationsConfig{Enabled: true, OnError: true}
+	n := New(app, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	expected := &fyne.Notification{
+		Title:   "OpenRouter Costs",
+		Content: "Error: unexpected status 401: key [REDACTED] revoked (retrying on schedule)",
+	}
+	test.AssertNotificationSent(t, expected, func() {
+		n.NotifyError(errors.New("unexpected status 401: key sk-or-v1-0123abcd revoked"))
+	})
+}
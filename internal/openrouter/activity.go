@@ -0,0 +1,71 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// ActivityItem is the spend of one model on one UTC day.
+type ActivityItem struct {
+	Date             string
+	Model            string
+	Provider         string
+	Usage            float64
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	ReasoningTokens  int
+}
+
+type activityPayload struct {
+	Data []struct {
+		Date             string  `json:"date"`
+		Model            string  `json:"model"`
+		ProviderName     string  `json:"provider_name"`
+		Usage            float64 `json:"usage"`
+		Requests         int     `json:"requests"`
+		PromptTokens     int     `json:"prompt_tokens"`
+		CompletionTokens int     `json:"completion_tokens"`
+		ReasoningTokens  int     `json:"reasoning_tokens"`
+	} `json:"data"`
+}
+
+// FetchActivity returns per-day, per-model usage for the last 30 UTC days.
+// When date (YYYY-MM-DD) is set, only that day is requested.
+func (c *Client) FetchActivity(ctx context.Context, token, date string) ([]ActivityItem, error) {
+	var query url.Values
+	if date != "" {
+		query = url.Values{"date": {date}}
+	}
+	body, err := c.get(ctx, "/activity", token, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseActivity(body)
+}
+
+func parseActivity(body []byte) ([]ActivityItem, error) {
+	var payload activityPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	items := make([]ActivityItem, 0, len(payload.Data))
+	for _, row := range payload.Data {
+		date := row.Date
+		if len(date) > len("2006-01-02") {
+			date = date[:len("2006-01-02")]
+		}
+		items = append(items, ActivityItem{
+			Date:             date,
+			Model:            row.Model,
+			Provider:         row.ProviderName,
+			Usage:            row.Usage,
+			Requests:         row.Requests,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			ReasoningTokens:  row.ReasoningTokens,
+		})
+	}
+	return items, nil
+}
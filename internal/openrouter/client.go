@@ -9,109 +9,255 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"openrouter-costs-tray/internal/trace"
 )
 
 const DefaultBaseURL = "https://openrouter.ai/api/v1"
 
 var ErrUnauthorized = errors.New("openrouter unauthorized")
 
+// errMalformed marks a body that is not JSON at all.
+var errMalformed = errors.New("malformed response")
+
+// APIError is returned for non-2xx responses other than auth failures.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status, Body: strings.TrimSpace(string(body))}
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Message = payload.Error.Message
+	}
+	return apiErr
+}
+
 // Usage represents the usage totals returned by the API.
 type Usage struct {
-	Total   float64
-	Daily   *float64
-	Weekly  *float64
-	Monthly *float64
-	KeyID   string
-	Label   string
+	Total          float64
+	Daily          *float64
+	Weekly         *float64
+	Monthly        *float64
+	Limit          *float64
+	LimitRemaining *float64
+	KeyID          string
+	Label          string
 }
 
 type Client struct {
+	mu      sync.RWMutex
 	baseURL string
 	http    *http.Client
-	logger  *slog.Logger
+	// disabled fails every request while set.
+	disabled error
+	onDrift  func(Drift)
+	// drift is the last reported drift per endpoint.
+	drift  map[string][]string
+	logger *slog.Logger
 }
 
 func NewClient(baseURL string, httpClient *http.Client, logger *slog.Logger) *Client {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := &Client{logger: logger}
+	c.Reconfigure(baseURL, httpClient)
+	return c
+}
+
+// Reconfigure switches the base URL and HTTP client for subsequent requests.
+// Empty values fall back to the defaults, as in NewClient.
+func (c *Client) Reconfigure(baseURL string, httpClient *http.Client) {
 	if baseURL == "" {
 		baseURL = DefaultBaseURL
 	}
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: 15 * time.Second}
 	}
-	if logger == nil {
-		logger = slog.Default()
+	c.mu.Lock()
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	c.http = httpClient
+	c.disabled = nil
+	c.mu.Unlock()
+}
+
+// Disable makes every request fail with err until the next Reconfigure, so
+// broken connection settings never fall back to a direct connection.
+func (c *Client) Disable(err error) {
+	c.mu.Lock()
+	c.disabled = err
+	c.mu.Unlock()
+}
+
+// BaseURL returns the API URL requests are sent to.
+func (c *Client) BaseURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.baseURL
+}
+
+// Ping checks that the API answers at all, without credentials. Any HTTP
+// response counts, including 401; it returns the status code.
+func (c *Client) Ping(ctx context.Context) (int, error) {
+	_, status, err := c.do(ctx, "/auth/key", "", nil)
+	if status != 0 {
+		return status, nil
 	}
-	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: httpClient, logger: logger}
+	return 0, err
 }
 
 func (c *Client) FetchUsage(ctx context.Context, token string) (Usage, error) {
-	if strings.TrimSpace(token) == "" {
-		return Usage{}, errors.New("token is empty")
-	}
-	url := c.baseURL + "/auth/key"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	ctx, span := trace.Start(ctx, "openrouter.FetchUsage", trace.KindInternal)
+	defer span.End()
+	body, err := c.get(ctx, "/auth/key", token, nil)
 	if err != nil {
+		span.SetError(err)
 		return Usage{}, err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	resp, err := c.http.Do(req)
+	usage, drift, err := parseUsage(body)
+	// A body that is not JSON says nothing about the schema.
+	if !errors.Is(err, errMalformed) {
+		c.reportDrift("/auth/key", drift)
+	}
 	if err != nil {
+		span.SetError(err)
 		return Usage{}, err
 	}
+	span.SetAttributes(trace.String("openrouter.key.label", usage.Label))
+	return usage, nil
+}
+
+// get performs an authenticated GET and returns the body of a 2xx response.
+func (c *Client) get(ctx context.Context, path, token string, query url.Values) ([]byte, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, errors.New("token is empty")
+	}
+	return c.fetch(ctx, path, token, query)
+}
+
+// fetch performs a GET, sending the token only when it is set. Each request
+// is a client span when ctx carries a trace.
+func (c *Client) fetch(ctx context.Context, path, token string, query url.Values) ([]byte, error) {
+	ctx, span := trace.Start(ctx, "GET "+path, trace.KindClient)
+	defer span.End()
+	body, status, err := c.do(ctx, path, token, query)
+	span.SetAttributes(
+		trace.String("http.request.method", http.MethodGet),
+		trace.String("url.path", path),
+	)
+	if status != 0 {
+		span.SetAttributes(trace.Int("http.response.status_code", status))
+	}
+	if kind := NetworkKind(err); kind != "" {
+		span.SetAttributes(trace.String("error.type", kind))
+	}
+	span.SetError(err)
+	return body, err
+}
+
+func (c *Client) do(ctx context.Context, path, token string, query url.Values) ([]byte, int, error) {
+	c.mu.RLock()
+	endpoint, httpClient, disabled := c.baseURL+path, c.http, c.disabled
+	c.mu.RUnlock()
+	if disabled != nil {
+		return nil, 0, disabled
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return Usage{}, err
+		return nil, resp.StatusCode, err
 	}
 	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-		return Usage{}, ErrUnauthorized
+		return nil, resp.StatusCode, ErrUnauthorized
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return Usage{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-	usage, err := parseUsage(body)
-	if err != nil {
-		return Usage{}, err
+		return nil, resp.StatusCode, newAPIError(resp.StatusCode, body)
 	}
-	return usage, nil
+	return body, resp.StatusCode, nil
 }
 
-func parseUsage(body []byte) (Usage, error) {
+// parseUsage reads the /auth/key payload. The documented {"data": {...}}
+// shape is checked against usageSchema; anything else is still read through
+// the fallbacks, and every deviation is returned as drift.
+func parseUsage(body []byte) (Usage, []string, error) {
 	dec := json.NewDecoder(bytes.NewReader(body))
 	dec.UseNumber()
 	var payload any
 	if err := dec.Decode(&payload); err != nil {
-		return Usage{}, err
+		return Usage{}, nil, fmt.Errorf("%w: %w", errMalformed, err)
 	}
-	var usageMap map[string]any
-	if top, ok := payload.(map[string]any); ok {
-		if data, ok := top["data"].(map[string]any); ok {
-			if _, has := data["usage"]; has {
-				usageMap = data
-			}
+	var (
+		usageMap map[string]any
+		drift    []string
+	)
+	top, _ := payload.(map[string]any)
+	if data, ok := top["data"].(map[string]any); ok {
+		drift = checkSchema("$.data", data, usageSchema)
+		if _, has := data["usage"]; has {
+			usageMap = data
 		}
-		if usageMap == nil {
-			if key, ok := top["key"].(map[string]any); ok {
-				if _, has := key["usage"]; has {
-					usageMap = key
-				}
+	} else if top == nil {
+		drift = append(drift, "$: expected object, got "+jsonType(payload))
+	} else if raw, has := top["data"]; has {
+		drift = append(drift, "$.data: expected object, got "+jsonType(raw))
+	} else {
+		drift = append(drift, "$.data: missing")
+	}
+	if usageMap == nil {
+		if key, ok := top["key"].(map[string]any); ok {
+			if _, has := key["usage"]; has {
+				usageMap = key
+				drift = append(drift, "$.key.usage: read through fallback")
 			}
 		}
 	}
 	if usageMap == nil {
-		var ok bool
-		usageMap, ok = findUsageMap(payload)
+		found, path, ok := findUsageMap(payload, "$")
 		if !ok {
-			return Usage{}, errors.New("usage field not found in response")
+			return Usage{}, drift, errors.New("usage field not found in response")
 		}
+		usageMap = found
+		drift = append(drift, path+".usage: read through fallback")
 	}
 	usage := Usage{}
 	if total, ok := toFloat(usageMap["usage"]); ok {
 		usage.Total = total
 	} else {
-		return Usage{}, errors.New("usage value missing or invalid")
+		return Usage{}, drift, errors.New("usage value missing or invalid")
 	}
 	if daily, ok := toFloat(usageMap["usage_daily"]); ok {
 		usage.Daily = &daily
@@ -122,30 +268,43 @@ func parseUsage(body []byte) (Usage, error) {
 	if monthly, ok := toFloat(usageMap["usage_monthly"]); ok {
 		usage.Monthly = &monthly
 	}
+	if limit, ok := toFloat(usageMap["limit"]); ok {
+		usage.Limit = &limit
+	}
+	if remaining, ok := toFloat(usageMap["limit_remaining"]); ok {
+		usage.LimitRemaining = &remaining
+	}
 	usage.KeyID = firstString(usageMap, "id", "key_id", "api_key_id")
 	usage.Label = firstString(usageMap, "name", "label")
-	return usage, nil
+	return usage, drift, nil
 }
 
-func findUsageMap(value any) (map[string]any, bool) {
+// findUsageMap returns the first object with a "usage" key and its JSON
+// path. Object keys are visited in sorted order so the pick is stable.
+func findUsageMap(value any, path string) (map[string]any, string, bool) {
 	switch v := value.(type) {
 	case map[string]any:
 		if _, ok := v["usage"]; ok {
-			return v, true
+			return v, path, true
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
 		}
-		for _, child := range v {
-			if found, ok := findUsageMap(child); ok {
-				return found, true
+		sort.Strings(keys)
+		for _, key := range keys {
+			if found, at, ok := findUsageMap(v[key], path+"."+key); ok {
+				return found, at, true
 			}
 		}
 	case []any:
-		for _, child := range v {
-			if found, ok := findUsageMap(child); ok {
-				return found, true
+		for i, child := range v {
+			if found, at, ok := findUsageMap(child, fmt.Sprintf("%s[%d]", path, i)); ok {
+				return found, at, true
 			}
 		}
 	}
-	return nil, false
+	return nil, "", false
 }
 
 func toFloat(value any) (float64, bool) {
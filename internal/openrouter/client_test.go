@@ -2,10 +2,18 @@ package openrouter
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/trace"
 )
 
 func TestFetchUsageSuccess(t *testing.T) {
@@ -61,3 +69,392 @@ func TestFetchUsageMalformed(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestParseUsageDrift(t *testing.T) {
+	cases := []struct {
+		name  string
+		body  string
+		total float64
+		drift []string
+	}{
+		{
+			"documented",
+			`{"data":{"label":"k","usage":1.5,"usage_daily":0.5,"usage_weekly":1,"usage_monthly":1.5,"limit":null,"limit_remaining":null,"is_free_tier":false}}`,
+			1.5, nil,
+		},
+		{
+			"missing and retyped fields",
+			`{"data":{"label":"k","usage":"1.5","usage_daily":0.5,"usage_weekly":1,"limit":null,"limit_remaining":null}}`,
+			1.5, []string{"$.data.usage: expected number, got string", "$.data.usage_monthly: missing"},
+		},
+		{
+			"new wrapper",
+			`{"result":{"keys":[{"label":"k","usage":2}]}}`,
+			2, []string{"$.data: missing", "$.result.keys[0].usage: read through fallback"},
+		},
+		{
+			"key wrapper",
+			`{"key":{"usage":3}}`,
+			3, []string{"$.data: missing", "$.key.usage: read through fallback"},
+		},
+	}
+	for _, tc := range cases {
+		usage, drift, err := parseUsage([]byte(tc.body))
+		if err != nil || usage.Total != tc.total {
+			t.Fatalf("%s: unexpected usage %+v %v", tc.name, usage, err)
+		}
+		if strings.Join(drift, "\n") != strings.Join(tc.drift, "\n") {
+			t.Fatalf("%s: unexpected drift %q", tc.name, drift)
+		}
+	}
+}
+
+func TestDriftHandlerCalledOnChange(t *testing.T) {
+	body := `{"data":{"usage":1}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	var reports []Drift
+	client.SetDriftHandler(func(d Drift) { reports = append(reports, d) })
+	for i := 0; i < 2; i++ {
+		if _, err := client.FetchUsage(context.Background(), "token"); err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+	}
+	if len(reports) != 1 || reports[0].Endpoint != "/auth/key" || len(reports[0].Issues) != 6 {
+		t.Fatalf("expected one drift report, got %+v", reports)
+	}
+	body = `{"data":{"label":"k","usage":1,"usage_daily":0,"usage_weekly":0,"usage_monthly":0,"limit":null,"limit_remaining":null}}`
+	if _, err := client.FetchUsage(context.Background(), "token"); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(reports) != 2 || len(reports[1].Issues) != 0 {
+		t.Fatalf("expected drift to clear, got %+v", reports)
+	}
+}
+
+func TestMalformedBodyKeepsDrift(t *testing.T) {
+	body := `{"data":{"usage":1}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	var reports []Drift
+	client.SetDriftHandler(func(d Drift) { reports = append(reports, d) })
+	if _, err := client.FetchUsage(context.Background(), "token"); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	body = `{"data":`
+	if _, err := client.FetchUsage(context.Background(), "token"); err == nil {
+		t.Fatalf("expected malformed JSON to fail")
+	}
+	if len(reports) != 1 || len(reports[0].Issues) == 0 {
+		t.Fatalf("expected the drift to stand after a malformed body, got %+v", reports)
+	}
+}
+
+func TestFetchUsageAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"message":"Rate limit exceeded"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	_, err := client.FetchUsage(context.Background(), "token")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "Rate limit exceeded" {
+		t.Fatalf("unexpected api error: %+v", apiErr)
+	}
+	if err.Error() != "unexpected status 429: Rate limit exceeded" {
+		t.Fatalf("unexpected error string: %q", err.Error())
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Enabled() bool { return true }
+
+func (r *spanRecorder) RecordSpan(s trace.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func TestFetchUsageSpans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	ctx, root := trace.Root(context.Background(), rec, "refresh", trace.KindInternal)
+	client := NewClient(srv.URL, srv.Client(), nil)
+	if _, err := client.FetchUsage(ctx, "token"); err == nil {
+		t.Fatalf("expected error")
+	}
+	root.End()
+
+	if len(rec.spans) != 3 {
+		t.Fatalf("expected request, FetchUsage and root spans, got %d", len(rec.spans))
+	}
+	req, fetch := rec.spans[0], rec.spans[1]
+	if req.Name != "GET /auth/key" || req.Kind != trace.KindClient || req.ParentID != fetch.SpanID {
+		t.Fatalf("unexpected request span %+v", req)
+	}
+	if fetch.Name != "openrouter.FetchUsage" || fetch.Status != trace.StatusError || req.Status != trace.StatusError {
+		t.Fatalf("expected both spans failed: %+v %+v", fetch, req)
+	}
+	var status any
+	for _, attr := range req.Attrs {
+		if attr.Key == "http.response.status_code" {
+			status = attr.Value
+		}
+	}
+	if status != int64(http.StatusBadGateway) {
+		t.Fatalf("unexpected status attribute %v in %+v", status, req.Attrs)
+	}
+}
+
+func TestPingAndReconfigure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gateway/auth/key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", nil, nil)
+	if client.BaseURL() != DefaultBaseURL {
+		t.Fatalf("unexpected default base URL %q", client.BaseURL())
+	}
+	client.Reconfigure(srv.URL+"/gateway/", srv.Client())
+	status, err := client.Ping(context.Background())
+	if err != nil || status != http.StatusUnauthorized {
+		t.Fatalf("expected reachable 401, got %d %v", status, err)
+	}
+
+	srv.Close()
+	if _, err := client.Ping(context.Background()); err == nil {
+		t.Fatalf("expected error for a closed server")
+	}
+}
+
+func TestFetchActivity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/activity" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("date"); got != "2025-02-03" {
+			t.Errorf("unexpected date query: %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"date":"2025-02-03 00:00:00","model":"openai/gpt-4o","provider_name":"OpenAI","usage":0.42,"requests":3,"prompt_tokens":100,"completion_tokens":50,"reasoning_tokens":0}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	items, err := client.FetchActivity(context.Background(), "token", "2025-02-03")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected one item, got %d", len(items))
+	}
+	item := items[0]
+	if item.Date != "2025-02-03" || item.Model != "openai/gpt-4o" || item.Usage != 0.42 || item.Requests != 3 {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/keys" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[
+			{"hash":"h1","name":"ci","label":"sk-or-v1-abc...123","disabled":false,"limit":10,"limit_remaining":7.5,"usage":2.5,"usage_daily":0.5,"created_at":"2025-02-19T20:52:27.363244+00:00"},
+			{"hash":"h2","name":"dev","disabled":true,"usage":1,"usage_daily":0.25}
+		]}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	keys, err := client.ListKeys(context.Background(), "prov")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected two keys, got %d", len(keys))
+	}
+	first := keys[0]
+	if first.Hash != "h1" || first.Name != "ci" || first.Disabled || first.CreatedAt.IsZero() {
+		t.Fatalf("unexpected first key: %+v", first)
+	}
+	if first.Usage.LimitRemaining == nil || *first.Usage.LimitRemaining != 7.5 {
+		t.Fatalf("expected limit remaining")
+	}
+	if !keys[1].Disabled {
+		t.Fatalf("expected second key disabled")
+	}
+
+	account := AccountUsage(keys)
+	if account.Total != 3.5 || account.Daily == nil || *account.Daily != 0.75 || account.Weekly != nil {
+		t.Fatalf("unexpected account usage: %+v", account)
+	}
+}
+
+func TestFetchModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no authorization header, got %q", auth)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[
+			{"id":"openai/gpt-4o","name":"GPT-4o","pricing":{"prompt":"0.0000025","completion":"0.00001"}},
+			{"id":"meta/llama:free","name":"Llama (free)","pricing":{"prompt":"0","completion":"0"}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	models, err := client.FetchModels(context.Background(), "")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("expected two models, got %d", len(models))
+	}
+	if models[0].ID != "openai/gpt-4o" || models[0].PromptPrice != 0.0000025 || models[0].CompletionPrice != 0.00001 {
+		t.Fatalf("unexpected model: %+v", models[0])
+	}
+}
+
+func TestFetchGeneration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generation" || r.URL.Query().Get("id") != "gen-1" {
+			t.Errorf("unexpected request: %s", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"id":"gen-1","model":"openai/gpt-4o","provider_name":"OpenAI","tokens_prompt":10,"tokens_completion":20,"native_tokens_prompt":11,"native_tokens_completion":21,"native_tokens_reasoning":5,"latency":1500,"generation_time":1200,"total_cost":0.8}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL+"/", srv.Client(), nil)
+	gen, err := client.FetchGeneration(context.Background(), "token", " gen-1 ")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if gen.Model != "openai/gpt-4o" || gen.Provider != "OpenAI" || gen.TotalCost != 0.8 {
+		t.Fatalf("unexpected generation: %+v", gen)
+	}
+	if gen.NativeTokensReasoning != 5 || gen.Latency != 1500*time.Millisecond {
+		t.Fatalf("unexpected generation stats: %+v", gen)
+	}
+
+	if _, err := client.FetchGeneration(context.Background(), "token", ""); err == nil {
+		t.Fatalf("expected error for empty id")
+	}
+}
+
+func TestFetchCredits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/credits" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"total_credits":25,"total_usage":7.5}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, srv.Client(), nil)
+	credits, err := client.FetchCredits(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if credits.Total != 25 || credits.Usage != 7.5 || credits.Remaining() != 17.5 {
+		t.Fatalf("unexpected credits: %+v", credits)
+	}
+}
+
+func TestNetworkKind(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := closed.Addr().String()
+	closed.Close()
+	_, dialErr := NewClient("http://"+addr, nil, nil).FetchUsage(context.Background(), "token")
+
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	_, certErr := NewClient(tlsSrv.URL, nil, nil).FetchUsage(context.Background(), "token")
+
+	dnsErr := &url.Error{Op: "Get", URL: "https://openrouter.ai", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "openrouter.ai"}}}
+
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"dns", dnsErr, NetworkDNS},
+		{"refused", dialErr, NetworkDial},
+		{"untrusted certificate", certErr, ""},
+		{"non-TLS answer", &url.Error{Op: "Get", URL: "https://openrouter.ai", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, NetworkTLS},
+		{"api error", newAPIError(http.StatusBadGateway, nil), ""},
+		{"unauthorized", ErrUnauthorized, ""},
+		{"nil", nil, ""},
+	}
+	for _, tc := range cases {
+		if got := NetworkKind(tc.err); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q (%v)", tc.name, tc.want, got, tc.err)
+		}
+	}
+	if !IsNetworkError(dialErr) || IsNetworkError(ErrUnauthorized) {
+		t.Fatalf("unexpected IsNetworkError result")
+	}
+}
+
+func TestDisableFailsRequests(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"data":{"usage":1}}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, server.Client(), nil)
+
+	broken := errors.New("connection settings invalid")
+	client.Disable(broken)
+	if _, err := client.FetchUsage(context.Background(), "token"); !errors.Is(err, broken) {
+		t.Fatalf("expected the disable error, got %v", err)
+	}
+	if _, err := client.Ping(context.Background()); !errors.Is(err, broken) {
+		t.Fatalf("expected ping to fail too, got %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("expected no requests while disabled, got %d", requests)
+	}
+
+	client.Reconfigure(server.URL, server.Client())
+	if _, err := client.FetchUsage(context.Background(), "token"); err != nil {
+		t.Fatalf("expected requests after reconfigure: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Credits are the purchased credits and the total spent on the account.
+type Credits struct {
+	Total float64
+	Usage float64
+}
+
+// Remaining returns the unspent credit.
+func (c Credits) Remaining() float64 {
+	return c.Total - c.Usage
+}
+
+// FetchCredits returns the account credit balance.
+func (c *Client) FetchCredits(ctx context.Context, token string) (Credits, error) {
+	body, err := c.get(ctx, "/credits", token, nil)
+	if err != nil {
+		return Credits{}, err
+	}
+	var payload struct {
+		Data struct {
+			TotalCredits float64 `json:"total_credits"`
+			TotalUsage   float64 `json:"total_usage"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Credits{}, err
+	}
+	return Credits{Total: payload.Data.TotalCredits, Usage: payload.Data.TotalUsage}, nil
+}
@@ -0,0 +1,88 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Generation holds the stats of a single request as reported by the API.
+type Generation struct {
+	ID                     string
+	Model                  string
+	Provider               string
+	CreatedAt              time.Time
+	Streamed               bool
+	FinishReason           string
+	TokensPrompt           int
+	TokensCompletion       int
+	NativeTokensPrompt     int
+	NativeTokensCompletion int
+	NativeTokensReasoning  int
+	Latency                time.Duration
+	GenerationTime         time.Duration
+	TotalCost              float64
+}
+
+type generationPayload struct {
+	Data struct {
+		ID                     string  `json:"id"`
+		Model                  string  `json:"model"`
+		ProviderName           string  `json:"provider_name"`
+		CreatedAt              string  `json:"created_at"`
+		Streamed               bool    `json:"streamed"`
+		FinishReason           string  `json:"finish_reason"`
+		TokensPrompt           int     `json:"tokens_prompt"`
+		TokensCompletion       int     `json:"tokens_completion"`
+		NativeTokensPrompt     int     `json:"native_tokens_prompt"`
+		NativeTokensCompletion int     `json:"native_tokens_completion"`
+		NativeTokensReasoning  int     `json:"native_tokens_reasoning"`
+		Latency                float64 `json:"latency"`
+		GenerationTime         float64 `json:"generation_time"`
+		TotalCost              float64 `json:"total_cost"`
+	} `json:"data"`
+}
+
+// FetchGeneration returns the stats of one generation by its ID.
+func (c *Client) FetchGeneration(ctx context.Context, token, id string) (Generation, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return Generation{}, errors.New("generation id is empty")
+	}
+	body, err := c.get(ctx, "/generation", token, url.Values{"id": {id}})
+	if err != nil {
+		return Generation{}, err
+	}
+	return parseGeneration(body)
+}
+
+func parseGeneration(body []byte) (Generation, error) {
+	var payload generationPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Generation{}, err
+	}
+	data := payload.Data
+	if data.ID == "" {
+		return Generation{}, errors.New("generation not found in response")
+	}
+	created, _ := time.Parse(time.RFC3339Nano, data.CreatedAt)
+	return Generation{
+		ID:                     data.ID,
+		Model:                  data.Model,
+		Provider:               data.ProviderName,
+		CreatedAt:              created,
+		Streamed:               data.Streamed,
+		FinishReason:           data.FinishReason,
+		TokensPrompt:           data.TokensPrompt,
+		TokensCompletion:       data.TokensCompletion,
+		NativeTokensPrompt:     data.NativeTokensPrompt,
+		NativeTokensCompletion: data.NativeTokensCompletion,
+		NativeTokensReasoning:  data.NativeTokensReasoning,
+		Latency:                time.Duration(data.Latency * float64(time.Millisecond)),
+		GenerationTime:         time.Duration(data.GenerationTime * float64(time.Millisecond)),
+		TotalCost:              data.TotalCost,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// KeyInfo describes one API key as listed with a provisioning key.
+type KeyInfo struct {
+	Hash      string
+	Name      string
+	Label     string
+	Disabled  bool
+	CreatedAt time.Time
+	Usage     Usage
+}
+
+type keysPayload struct {
+	Data []struct {
+		Hash           string   `json:"hash"`
+		Name           string   `json:"name"`
+		Label          string   `json:"label"`
+		Disabled       bool     `json:"disabled"`
+		CreatedAt      string   `json:"created_at"`
+		Limit          *float64 `json:"limit"`
+		LimitRemaining *float64 `json:"limit_remaining"`
+		Usage          float64  `json:"usage"`
+		UsageDaily     *float64 `json:"usage_daily"`
+		UsageWeekly    *float64 `json:"usage_weekly"`
+		UsageMonthly   *float64 `json:"usage_monthly"`
+	} `json:"data"`
+}
+
+// ListKeys enumerates every API key on the account. It requires a provisioning key.
+func (c *Client) ListKeys(ctx context.Context, provisioningKey string) ([]KeyInfo, error) {
+	body, err := c.get(ctx, "/keys", provisioningKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeys(body)
+}
+
+func parseKeys(body []byte) ([]KeyInfo, error) {
+	var payload keysPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	keys := make([]KeyInfo, 0, len(payload.Data))
+	for _, row := range payload.Data {
+		created, _ := time.Parse(time.RFC3339Nano, row.CreatedAt)
+		keys = append(keys, KeyInfo{
+			Hash:      row.Hash,
+			Name:      row.Name,
+			Label:     row.Label,
+			Disabled:  row.Disabled,
+			CreatedAt: created,
+			Usage: Usage{
+				Total:          row.Usage,
+				Daily:          row.UsageDaily,
+				Weekly:         row.UsageWeekly,
+				Monthly:        row.UsageMonthly,
+				Limit:          row.Limit,
+				LimitRemaining: row.LimitRemaining,
+				KeyID:          row.Hash,
+				Label:          row.Name,
+			},
+		})
+	}
+	return keys, nil
+}
+
+// AccountUsage sums the usage of all keys into one account-wide total.
+// Periods are reported only when at least one key reports them.
+func AccountUsage(keys []KeyInfo) Usage {
+	var account Usage
+	for _, key := range keys {
+		account.Total += key.Usage.Total
+		account.Daily = addOptional(account.Daily, key.Usage.Daily)
+		account.Weekly = addOptional(account.Weekly, key.Usage.Weekly)
+		account.Monthly = addOptional(account.Monthly, key.Usage.Monthly)
+	}
+	return account
+}
+
+func addOptional(sum, value *float64) *float64 {
+	if value == nil {
+		return sum
+	}
+	total := *value
+	if sum != nil {
+		total += *sum
+	}
+	return &total
+}
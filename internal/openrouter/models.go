@@ -0,0 +1,54 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Model is one entry of the public model catalogue. Prices are USD per token.
+type Model struct {
+	ID              string
+	Name            string
+	PromptPrice     float64
+	CompletionPrice float64
+}
+
+type modelsPayload struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Pricing struct {
+			Prompt     json.Number `json:"prompt"`
+			Completion json.Number `json:"completion"`
+		} `json:"pricing"`
+	} `json:"data"`
+}
+
+// FetchModels returns the model catalogue. The endpoint is public, so the
+// token may be empty.
+func (c *Client) FetchModels(ctx context.Context, token string) ([]Model, error) {
+	body, err := c.fetch(ctx, "/models", token, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseModels(body)
+}
+
+func parseModels(body []byte) ([]Model, error) {
+	var payload modelsPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	models := make([]Model, 0, len(payload.Data))
+	for _, row := range payload.Data {
+		prompt, _ := toFloat(row.Pricing.Prompt)
+		completion, _ := toFloat(row.Pricing.Completion)
+		models = append(models, Model{
+			ID:              row.ID,
+			Name:            row.Name,
+			PromptPrice:     prompt,
+			CompletionPrice: completion,
+		})
+	}
+	return models, nil
+}
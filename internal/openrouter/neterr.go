@@ -0,0 +1,50 @@
+package openrouter
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// Network error kinds returned by NetworkKind.
+const (
+	NetworkDNS  = "dns"
+	NetworkDial = "dial"
+	NetworkTLS  = "tls"
+)
+
+// NetworkKind tells at which layer a request failed to reach the API: name
+// resolution, connecting or a TLS handshake answered by something else. It returns "" for API errors
+// and anything else that means the network itself works.
+func NetworkKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return NetworkDNS
+	}
+	// A non-TLS answer usually comes from a captive portal. Certificate
+	// verification failures are not listed: the network works, the trust
+	// settings are wrong, and the user has to see that error.
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
+		return NetworkTLS
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect") {
+		return NetworkDial
+	}
+	for _, errno := range []syscall.Errno{syscall.ECONNREFUSED, syscall.ENETUNREACH, syscall.EHOSTUNREACH, syscall.ENETDOWN} {
+		if errors.Is(err, errno) {
+			return NetworkDial
+		}
+	}
+	return ""
+}
+
+// IsNetworkError reports whether err means the API could not be reached.
+func IsNetworkError(err error) bool {
+	return NetworkKind(err) != ""
+}
@@ -0,0 +1,100 @@
+package openrouter
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Drift tells where a response deviated from the schema the client knows.
+// Issues are "JSON path: problem" lines; none means the response is back to
+// normal.
+type Drift struct {
+	Endpoint string
+	Issues   []string
+}
+
+type schemaField struct {
+	name     string
+	kind     string
+	nullable bool
+}
+
+// usageSchema is the documented data object of GET /auth/key. Fields not
+// listed here are ignored, so additions to the API are not drift.
+var usageSchema = []schemaField{
+	{name: "label", kind: "string"},
+	{name: "usage", kind: "number"},
+	{name: "usage_daily", kind: "number"},
+	{name: "usage_weekly", kind: "number"},
+	{name: "usage_monthly", kind: "number"},
+	{name: "limit", kind: "number", nullable: true},
+	{name: "limit_remaining", kind: "number", nullable: true},
+}
+
+// checkSchema reports missing fields and type changes in obj at path.
+func checkSchema(path string, obj map[string]any, schema []schemaField) []string {
+	var issues []string
+	for _, field := range schema {
+		value, ok := obj[field.name]
+		at := path + "." + field.name
+		switch got := jsonType(value); {
+		case !ok:
+			issues = append(issues, at+": missing")
+		case got == "null" && field.nullable:
+		case got != field.kind:
+			issues = append(issues, at+": expected "+field.kind+", got "+got)
+		}
+	}
+	return issues
+}
+
+// jsonType names the JSON type of a value decoded with UseNumber.
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// SetDriftHandler registers fn to be called whenever the drift of an
+// endpoint changes, including back to none.
+func (c *Client) SetDriftHandler(fn func(Drift)) {
+	c.mu.Lock()
+	c.onDrift = fn
+	c.mu.Unlock()
+}
+
+// reportDrift logs drift once per change and passes it to the handler.
+func (c *Client) reportDrift(endpoint string, issues []string) {
+	c.mu.Lock()
+	if slices.Equal(c.drift[endpoint], issues) {
+		c.mu.Unlock()
+		return
+	}
+	if c.drift == nil {
+		c.drift = map[string][]string{}
+	}
+	c.drift[endpoint] = issues
+	fn := c.onDrift
+	c.mu.Unlock()
+	if len(issues) > 0 {
+		c.logger.Warn("response schema drift", "endpoint", endpoint, "paths", issues)
+	} else {
+		c.logger.Info("response schema back to normal", "endpoint", endpoint)
+	}
+	if fn != nil {
+		fn(Drift{Endpoint: endpoint, Issues: issues})
+	}
+}
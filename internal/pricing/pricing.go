@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"sort"
+	"strings"
+
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/util"
+)
+
+// FreeSuffix marks the free variant of a model ID.
+const FreeSuffix = ":free"
+
+// Price holds USD per token for prompt and completion.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// String formats the price per million tokens.
+func (p Price) String() string {
+	return "in " + util.FormatUSD(p.Prompt*1e6) + "/M, out " + util.FormatUSD(p.Completion*1e6) + "/M"
+}
+
+type ChangeKind string
+
+const (
+	ChangeIncrease    ChangeKind = "increase"
+	ChangeFreeVariant ChangeKind = "free_variant"
+	ChangeRemoved     ChangeKind = "removed"
+)
+
+// Change is a price event on a watched model.
+type Change struct {
+	Kind  ChangeKind
+	Model string
+	Old   Price
+	New   Price
+}
+
+// Watched picks the prices of watched models and their free variants from the catalogue.
+func Watched(models []openrouter.Model, watch []string) map[string]Price {
+	wanted := map[string]bool{}
+	for _, id := range watch {
+		wanted[id] = true
+		wanted[freeVariant(id)] = true
+	}
+	out := map[string]Price{}
+	for _, model := range models {
+		if wanted[model.ID] {
+			out[model.ID] = Price{Prompt: model.PromptPrice, Completion: model.CompletionPrice}
+		}
+	}
+	return out
+}
+
+// Diff compares two watched-price snapshots. Without a previous snapshot
+// there is no baseline, so nothing is reported.
+func Diff(prev, curr map[string]Price, watch []string) []Change {
+	if prev == nil {
+		return nil
+	}
+	var changes []Change
+	for _, id := range watch {
+		old, had := prev[id]
+		current, has := curr[id]
+		switch {
+		case had && !has:
+			changes = append(changes, Change{Kind: ChangeRemoved, Model: id, Old: old})
+		case had && has && (current.Prompt > old.Prompt || current.Completion > old.Completion):
+			changes = append(changes, Change{Kind: ChangeIncrease, Model: id, Old: old, New: current})
+		}
+		free := freeVariant(id)
+		if free == id || !had {
+			// Only models already known last time can gain a new variant;
+			// newly watched ones just establish their baseline.
+			continue
+		}
+		if _, had := prev[free]; !had {
+			if price, has := curr[free]; has {
+				changes = append(changes, Change{Kind: ChangeFreeVariant, Model: free, New: price})
+			}
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Model < changes[j].Model
+	})
+	return changes
+}
+
+// Describe renders a change as a single notification line.
+func Describe(change Change) string {
+	switch change.Kind {
+	case ChangeIncrease:
+		return "Price increase: " + change.Model + " (" + change.Old.String() + " -> " + change.New.String() + ")"
+	case ChangeFreeVariant:
+		return "Free variant available: " + change.Model
+	case ChangeRemoved:
+		return "Model removed: " + change.Model
+	default:
+		return string(change.Kind) + ": " + change.Model
+	}
+}
+
+func freeVariant(id string) string {
+	if strings.HasSuffix(id, FreeSuffix) {
+		return id
+	}
+	return id + FreeSuffix
+}
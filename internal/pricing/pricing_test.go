@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"testing"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestWatched(t *testing.T) {
+	models := []openrouter.Model{
+		{ID: "a", PromptPrice: 1e-6, CompletionPrice: 2e-6},
+		{ID: "a:free"},
+		{ID: "b", PromptPrice: 1},
+	}
+	got := Watched(models, []string{"a"})
+	if len(got) != 2 {
+		t.Fatalf("expected watched model and its free variant, got %+v", got)
+	}
+	if got["a"].Completion != 2e-6 {
+		t.Fatalf("unexpected price: %+v", got["a"])
+	}
+}
+
+func TestDiff(t *testing.T) {
+	watch := []string{"a", "b", "c"}
+	prev := map[string]Price{
+		"a": {Prompt: 1, Completion: 2},
+		"b": {Prompt: 1, Completion: 1},
+		"c": {Prompt: 1, Completion: 1},
+	}
+	curr := map[string]Price{
+		"a":      {Prompt: 1, Completion: 3},
+		"b":      {Prompt: 0.5, Completion: 1},
+		"b:free": {},
+	}
+
+	changes := Diff(prev, curr, watch)
+	if len(changes) != 3 {
+		t.Fatalf("expected three changes, got %+v", changes)
+	}
+	want := []struct {
+		kind  ChangeKind
+		model string
+	}{
+		{ChangeIncrease, "a"},
+		{ChangeFreeVariant, "b:free"},
+		{ChangeRemoved, "c"},
+	}
+	for i, w := range want {
+		if changes[i].Kind != w.kind || changes[i].Model != w.model {
+			t.Fatalf("change %d: expected %s %s, got %+v", i, w.kind, w.model, changes[i])
+		}
+	}
+
+	if got := Diff(nil, curr, watch); got != nil {
+		t.Fatalf("expected no changes without baseline, got %+v", got)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	change := Change{Kind: ChangeIncrease, Model: "a", Old: Price{Prompt: 1e-6, Completion: 2e-6}, New: Price{Prompt: 2e-6, Completion: 2e-6}}
+	want := "Price increase: a (in $1.000/M, out $2.000/M -> in $2.000/M, out $2.000/M)"
+	if got := Describe(change); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxBufferedBody caps how much of a non-streaming body is kept for parsing.
+const maxBufferedBody = 4 << 20
+
+type usageResult struct {
+	ID               string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	HasUsage         bool
+	HasCost          bool
+}
+
+type responseChunk struct {
+	ID    string `json:"id"`
+	Model string `json:"model"`
+	Usage *struct {
+		PromptTokens     int      `json:"prompt_tokens"`
+		CompletionTokens int      `json:"completion_tokens"`
+		Cost             *float64 `json:"cost"`
+	} `json:"usage"`
+}
+
+// meterBody passes the upstream body through unchanged while extracting the
+// generation ID and usage from JSON or server-sent event payloads.
+type meterBody struct {
+	rc       io.ReadCloser
+	sse      bool
+	buf      bytes.Buffer
+	overflow bool
+	result   usageResult
+	once     sync.Once
+	done     func(usageResult)
+}
+
+func newMeterBody(rc io.ReadCloser, sse bool, done func(usageResult)) *meterBody {
+	return &meterBody{rc: rc, sse: sse, done: done}
+}
+
+func (m *meterBody) Read(p []byte) (int, error) {
+	n, err := m.rc.Read(p)
+	if n > 0 {
+		m.feed(p[:n])
+	}
+	return n, err
+}
+
+func (m *meterBody) Close() error {
+	err := m.rc.Close()
+	m.once.Do(func() {
+		if m.sse {
+			m.consumeLine(m.buf.Bytes())
+		} else if !m.overflow {
+			m.apply(m.buf.Bytes())
+		}
+		m.done(m.result)
+	})
+	return err
+}
+
+func (m *meterBody) feed(p []byte) {
+	if !m.sse {
+		if m.overflow || m.buf.Len()+len(p) > maxBufferedBody {
+			m.overflow = true
+			m.buf.Reset()
+			return
+		}
+		m.buf.Write(p)
+		return
+	}
+	m.buf.Write(p)
+	for {
+		line, rest, found := bytes.Cut(m.buf.Bytes(), []byte("\n"))
+		if !found {
+			break
+		}
+		m.consumeLine(line)
+		remaining := append([]byte(nil), rest...)
+		m.buf.Reset()
+		m.buf.Write(remaining)
+	}
+}
+
+func (m *meterBody) consumeLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("[DONE]")) {
+		return
+	}
+	m.apply(data)
+}
+
+func (m *meterBody) apply(data []byte) {
+	var chunk responseChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+	if chunk.ID != "" {
+		m.result.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		m.result.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		m.result.HasUsage = true
+		m.result.PromptTokens = chunk.Usage.PromptTokens
+		m.result.CompletionTokens = chunk.Usage.CompletionTokens
+		if chunk.Usage.Cost != nil {
+			m.result.HasCost = true
+			m.result.Cost = *chunk.Usage.Cost
+		}
+	}
+}
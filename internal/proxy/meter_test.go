@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func readMetered(t *testing.T, body string, sse bool) usageResult {
+	t.Helper()
+	var got usageResult
+	called := 0
+	m := newMeterBody(io.NopCloser(strings.NewReader(body)), sse, func(r usageResult) {
+		got = r
+		called++
+	})
+	out, err := io.ReadAll(m)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(out) != body {
+		t.Fatalf("expected body to pass through unchanged")
+	}
+	_ = m.Close()
+	_ = m.Close()
+	if called != 1 {
+		t.Fatalf("expected done to be called once, got %d", called)
+	}
+	return got
+}
+
+func TestMeterJSON(t *testing.T) {
+	got := readMetered(t, `{"id":"gen-1","model":"openai/gpt-4o","usage":{"prompt_tokens":10,"completion_tokens":5,"cost":0.002}}`, false)
+	if got.ID != "gen-1" || got.Model != "openai/gpt-4o" || !got.HasCost || got.Cost != 0.002 || got.PromptTokens != 10 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestMeterSSE(t *testing.T) {
+	body := ": OPENROUTER PROCESSING\n\n" +
+		"data: {\"id\":\"gen-2\",\"model\":\"m\",\"choices\":[]}\n\n" +
+		"data: {\"id\":\"gen-2\",\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4}}\r\n\r\n" +
+		"data: [DONE]"
+	got := readMetered(t, body, true)
+	if got.ID != "gen-2" || got.Model != "m" || !got.HasUsage || got.HasCost || got.CompletionTokens != 4 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestMeterIgnoresNonCompletion(t *testing.T) {
+	got := readMetered(t, `{"data":[{"id":"openai/gpt-4o"}]}`, false)
+	if got.ID != "" || got.HasUsage {
+		t.Fatalf("expected no usage, got %+v", got)
+	}
+}
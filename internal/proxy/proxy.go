@@ -0,0 +1,434 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/budget"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+const (
+	DefaultProjectHeader = "X-Project"
+	DefaultProject       = "default"
+	projectPrefix        = "/p/"
+	maxRequestBody       = 32 << 20
+	stopLookupTimeout    = 3 * time.Second
+)
+
+var defaultLookupDelays = []time.Duration{time.Second, 3 * time.Second, 10 * time.Second}
+
+// Options configures a Proxy. Upstream defaults to openrouter.DefaultBaseURL.
+type Options struct {
+	Upstream       string
+	Transport      http.RoundTripper
+	ProjectHeader  string
+	DefaultProject string
+	History        *history.Store
+	Bus            *events.Bus
+	// Client looks up the cost by generation ID when a response carries none.
+	Client *openrouter.Client
+	// Budget, when set, may reject requests or rewrite their model.
+	Budget       *budget.Enforcer
+	LookupDelays []time.Duration
+	Logger       *slog.Logger
+}
+
+// Proxy forwards OpenAI-compatible requests upstream and attributes the cost
+// of every response to a project.
+type Proxy struct {
+	opts     Options
+	upstream *url.URL
+	rp       *httputil.ReverseProxy
+	logger   *slog.Logger
+
+	// ctx is cancelled on Stop so pending cost lookups finish early.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	lookups sync.WaitGroup
+
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+}
+
+type requestMeta struct {
+	project string
+	token   string
+}
+
+type metaKey struct{}
+
+func New(opts Options) (*Proxy, error) {
+	if opts.Upstream == "" {
+		opts.Upstream = openrouter.DefaultBaseURL
+	}
+	upstream, err := url.Parse(strings.TrimRight(opts.Upstream, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if upstream.Scheme == "" || upstream.Host == "" {
+		return nil, errors.New("upstream must be an absolute URL")
+	}
+	if opts.ProjectHeader == "" {
+		opts.ProjectHeader = DefaultProjectHeader
+	}
+	if opts.DefaultProject == "" {
+		opts.DefaultProject = DefaultProject
+	}
+	if opts.LookupDelays == nil {
+		opts.LookupDelays = defaultLookupDelays
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	p := &Proxy{opts: opts, upstream: upstream, logger: opts.Logger}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.rp = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		Transport:      opts.Transport,
+		FlushInterval:  -1,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.errorHandler,
+	}
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	project, rest := p.route(r)
+	if rest == "" {
+		WriteError(w, http.StatusNotFound, "unknown path: use /v1/... or /p/<project>/v1/...")
+		return
+	}
+	meta := requestMeta{project: project, token: bearerToken(r)}
+	out := r.WithContext(context.WithValue(r.Context(), metaKey{}, meta))
+	routed := *r.URL
+	routed.Path = rest
+	routed.RawPath = ""
+	out.URL = &routed
+	if !p.enforce(w, out, meta) {
+		return
+	}
+	p.rp.ServeHTTP(w, out)
+}
+
+// enforce applies the budget decision to r. It returns false when the request
+// has been rejected.
+func (p *Proxy) enforce(w http.ResponseWriter, r *http.Request, meta requestMeta) bool {
+	if p.opts.Budget == nil {
+		return true
+	}
+	var body []byte
+	if r.Method == http.MethodPost && r.Body != nil {
+		read, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+		r.Body.Close()
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "failed to read request body")
+			return false
+		}
+		if len(read) > maxRequestBody {
+			WriteError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return false
+		}
+		body = read
+		setBody(r, body)
+	}
+	model := requestModel(body)
+
+	decision := p.opts.Budget.Check(meta.project, meta.token, model)
+	switch decision.Action {
+	case budget.Block:
+		p.logger.Warn("request blocked", "project", meta.project, "model", model, "reason", decision.Reason())
+		w.Header().Set("Retry-After", strconv.Itoa(secondsUntilUTCMidnight(time.Now())))
+		WriteError(w, http.StatusTooManyRequests, decision.Reason())
+		return false
+	case budget.Downgrade:
+		rewritten, err := replaceModel(body, decision.Model)
+		if err != nil {
+			p.logger.Warn("request downgrade failed", "project", meta.project, "model", model, "error", err)
+			return true
+		}
+		setBody(r, rewritten)
+		p.logger.Info("request downgraded", "project", meta.project, "model", model, "fallback", decision.Model, "reason", decision.Reason())
+	default:
+		p.logger.Info("request allowed", "project", meta.project, "model", model, "reason", decision.Reason())
+	}
+	return true
+}
+
+// route extracts the project from a /p/<project> prefix or the project header
+// and returns the API path relative to the upstream base URL.
+func (p *Proxy) route(r *http.Request) (string, string) {
+	path := r.URL.Path
+	project := strings.TrimSpace(r.Header.Get(p.opts.ProjectHeader))
+	if strings.HasPrefix(path, projectPrefix) {
+		rest := strings.TrimPrefix(path, projectPrefix)
+		name, remainder, _ := strings.Cut(rest, "/")
+		if name != "" {
+			project = name
+		}
+		path = "/" + remainder
+	}
+	if project == "" {
+		project = p.opts.DefaultProject
+	}
+	for _, prefix := range []string{"/api/v1", "/v1"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return project, strings.TrimPrefix(path, prefix)
+		}
+	}
+	return project, ""
+}
+
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.Out.URL.Scheme = p.upstream.Scheme
+	pr.Out.URL.Host = p.upstream.Host
+	pr.Out.URL.Path = p.upstream.Path + pr.In.URL.Path
+	pr.Out.URL.RawPath = ""
+	pr.Out.Host = p.upstream.Host
+	pr.Out.Header.Del(p.opts.ProjectHeader)
+	// Let the transport negotiate compression so the meter sees plain bodies.
+	pr.Out.Header.Del("Accept-Encoding")
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil
+	}
+	meta, _ := resp.Request.Context().Value(metaKey{}).(requestMeta)
+	sse := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	resp.Body = newMeterBody(resp.Body, sse, func(result usageResult) {
+		p.account(meta, result)
+	})
+	return nil
+}
+
+func (p *Proxy) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	p.logger.Warn("upstream request failed", "error", err, "path", r.URL.Path)
+	WriteError(w, http.StatusBadGateway, "upstream request failed")
+}
+
+// account records the cost of a finished response, looking it up by
+// generation ID when the response did not include it.
+func (p *Proxy) account(meta requestMeta, result usageResult) {
+	if result.ID == "" && !result.HasUsage {
+		return
+	}
+	rec := history.ProjectCost{
+		At:               time.Now().UTC(),
+		Project:          meta.project,
+		Model:            result.Model,
+		GenerationID:     result.ID,
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+		Cost:             result.Cost,
+	}
+	if result.HasCost || result.ID == "" || p.opts.Client == nil || meta.token == "" {
+		if !result.HasCost {
+			p.logger.Warn("response cost unavailable", "project", rec.Project, "generation_id", rec.GenerationID)
+		}
+		p.record(rec, meta.token)
+		return
+	}
+	p.lookups.Add(1)
+	go func() {
+		defer p.lookups.Done()
+		p.lookupAndRecord(rec, meta.token)
+	}()
+}
+
+// lookupAndRecord retries the generation lookup after each of the configured
+// delays. Once the proxy is stopping it makes one last attempt right away so
+// the cost is recorded before exit.
+func (p *Proxy) lookupAndRecord(rec history.ProjectCost, token string) {
+	var lastErr error
+	for _, delay := range p.opts.LookupDelays {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			ctx, cancel := context.WithTimeout(p.ctx, 15*time.Second)
+			lastErr = p.lookup(ctx, &rec, token)
+			cancel()
+		case <-p.ctx.Done():
+			timer.Stop()
+			ctx, cancel := context.WithTimeout(context.Background(), stopLookupTimeout)
+			lastErr = p.lookup(ctx, &rec, token)
+			cancel()
+			if lastErr != nil {
+				p.logger.Warn("generation cost lookup failed", "error", lastErr, "generation_id", rec.GenerationID)
+			}
+			p.record(rec, token)
+			return
+		}
+		if lastErr == nil {
+			p.record(rec, token)
+			return
+		}
+	}
+	p.logger.Warn("generation cost lookup failed", "error", lastErr, "generation_id", rec.GenerationID)
+	p.record(rec, token)
+}
+
+func (p *Proxy) lookup(ctx context.Context, rec *history.ProjectCost, token string) error {
+	gen, err := p.opts.Client.FetchGeneration(ctx, token, rec.GenerationID)
+	if err != nil {
+		return err
+	}
+	rec.Cost = gen.TotalCost
+	if rec.Model == "" {
+		rec.Model = gen.Model
+	}
+	return nil
+}
+
+func (p *Proxy) record(rec history.ProjectCost, token string) {
+	p.logger.Info("request metered", "project", rec.Project, "model", rec.Model, "cost", rec.Cost)
+	if p.opts.History != nil {
+		if err := p.opts.History.AppendProjectCost(rec); err != nil {
+			p.logger.Warn("project cost save failed", "error", err)
+		}
+	}
+	if p.opts.Budget != nil {
+		p.opts.Budget.Record(rec.Project, token, rec.Cost)
+	}
+	p.opts.Bus.Publish(events.ProjectCostRecorded{Cost: rec})
+}
+
+// Start listens on addr and serves in the background.
+func (p *Proxy) Start(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.server != nil {
+		return errors.New("proxy already running")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+	p.server = server
+	p.listener = listener
+	p.logger.Info("proxy started", "addr", listener.Addr().String(), "upstream", p.upstream.String())
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.logger.Error("proxy stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the listening address, or "" when stopped.
+func (p *Proxy) Addr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.listener == nil {
+		return ""
+	}
+	return p.listener.Addr().String()
+}
+
+// Stop shuts the server down and waits, bounded by ctx, for pending cost
+// lookups to be recorded.
+func (p *Proxy) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	server := p.server
+	p.server = nil
+	p.listener = nil
+	p.mu.Unlock()
+	var err error
+	if server != nil {
+		p.logger.Info("proxy stopped")
+		err = server.Shutdown(ctx)
+	}
+	p.cancel()
+	done := make(chan struct{})
+	go func() {
+		p.lookups.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		p.logger.Warn("pending cost lookups abandoned", "error", ctx.Err())
+	}
+	return err
+}
+
+// WriteError writes an OpenAI-style JSON error body.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	var payload struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	payload.Error.Code = status
+	payload.Error.Message = message
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func setBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Del("Content-Length")
+}
+
+// requestModel returns the model field of a JSON request body, if any.
+func requestModel(body []byte) string {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Model
+}
+
+func replaceModel(body []byte, model string) ([]byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if _, ok := payload["model"]; !ok {
+		return nil, errors.New("request has no model field")
+	}
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	payload["model"] = encoded
+	return json.Marshal(payload)
+}
+
+func secondsUntilUTCMidnight(now time.Time) int {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return int(midnight.Sub(now).Seconds()) + 1
+}
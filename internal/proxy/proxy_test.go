@@ -0,0 +1,277 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/budget"
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/state"
+)
+
+func newTestProxy(t *testing.T, upstream http.HandlerFunc, enforcer *budget.Enforcer) (*httptest.Server, *events.Subscription, *history.Store) {
+	t.Helper()
+	up := httptest.NewServer(upstream)
+	t.Cleanup(up.Close)
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(8)
+	store := history.NewStore(t.TempDir())
+	p, err := New(Options{
+		Upstream:     up.URL + "/api/v1",
+		History:      store,
+		Bus:          bus,
+		Client:       openrouter.NewClient(up.URL+"/api/v1", up.Client(), nil),
+		Budget:       enforcer,
+		LookupDelays: []time.Duration{0},
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	return srv, sub, store
+}
+
+func waitRecorded(t *testing.T, sub *events.Subscription) history.ProjectCost {
+	t.Helper()
+	for {
+		select {
+		case ev := <-sub.C():
+			if rec, ok := ev.(events.ProjectCostRecorded); ok {
+				return rec.Cost
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected cost to be recorded")
+		}
+	}
+}
+
+func TestProxyForwardsAndRecordsByHeader(t *testing.T) {
+	srv, sub, store := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/chat/completions" {
+			t.Errorf("unexpected upstream path: %s", r.URL.Path)
+		}
+		if r.Header.Get(DefaultProjectHeader) != "" {
+			t.Errorf("expected project header to be stripped")
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("expected authorization to be forwarded")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"gen-1","model":"openai/gpt-4o","usage":{"prompt_tokens":10,"completion_tokens":5,"cost":0.002}}`))
+	}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(`{"model":"openai/gpt-4o"}`))
+	req.Header.Set("Authorization", "Bearer sk-test")
+	req.Header.Set(DefaultProjectHeader, "billing")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "gen-1") {
+		t.Fatalf("expected upstream body, got %q", body)
+	}
+
+	rec := waitRecorded(t, sub)
+	if rec.Project != "billing" || rec.Cost != 0.002 || rec.Model != "openai/gpt-4o" {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	saved, err := store.ProjectCosts(time.Time{})
+	if err != nil || len(saved) != 1 {
+		t.Fatalf("expected record in history, got %+v %v", saved, err)
+	}
+}
+
+func TestProxyStreamingWithGenerationLookup(t *testing.T) {
+	srv, sub, _ := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/chat/completions":
+			w.Header().Set("Content-Type", "text/event-stream")
+			flusher := w.(http.Flusher)
+			_, _ = io.WriteString(w, "data: {\"id\":\"gen-9\",\"model\":\"m\"}\n\n")
+			flusher.Flush()
+			_, _ = io.WriteString(w, "data: {\"id\":\"gen-9\",\"usage\":{\"prompt_tokens\":1,\"completion_tokens\":2}}\n\ndata: [DONE]\n\n")
+		case "/api/v1/generation":
+			if r.URL.Query().Get("id") != "gen-9" {
+				t.Errorf("unexpected generation id: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"data":{"id":"gen-9","model":"m","total_cost":0.8}}`))
+		default:
+			t.Errorf("unexpected upstream path: %s", r.URL.Path)
+		}
+	}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/p/agent/api/v1/chat/completions", strings.NewReader(`{"stream":true}`))
+	req.Header.Set("Authorization", "Bearer sk-test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "[DONE]") {
+		t.Fatalf("expected streamed body, got %q", body)
+	}
+
+	rec := waitRecorded(t, sub)
+	if rec.Project != "agent" || rec.Cost != 0.8 || rec.GenerationID != "gen-9" || rec.CompletionTokens != 2 {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+}
+
+func TestStopRecordsPendingLookups(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"id":"gen-5","model":"m","total_cost":0.4}}`))
+	}))
+	defer up.Close()
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(8)
+	p, err := New(Options{
+		Upstream:     up.URL + "/api/v1",
+		Bus:          bus,
+		Client:       openrouter.NewClient(up.URL+"/api/v1", up.Client(), nil),
+		LookupDelays: []time.Duration{time.Hour},
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+	p.account(requestMeta{project: "agent", token: "sk-test"}, usageResult{ID: "gen-5", HasUsage: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := p.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected stop not to wait out the lookup delay, took %v", elapsed)
+	}
+	select {
+	case ev := <-sub.C():
+		rec := ev.(events.ProjectCostRecorded).Cost
+		if rec.Project != "agent" || rec.Cost != 0.4 {
+			t.Fatalf("unexpected record: %+v", rec)
+		}
+	default:
+		t.Fatalf("expected the pending lookup to be recorded before stop returned")
+	}
+}
+
+func TestProxyBudget(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Budgets.Projects = map[string]config.Budget{"agent": {DailyCap: 1, SoftCap: 0.5, FallbackModel: "cheap/model"}}
+	enforcer := budget.NewEnforcer(config.NewStore(filepath.Join(dir, "config.json"), cfg), state.New(), history.NewStore(dir), nil)
+
+	var models []string
+	srv, sub, _ := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode upstream body: %v", err)
+		}
+		model, _ := payload["model"].(string)
+		models = append(models, model)
+		if payload["temperature"] != 0.5 {
+			t.Errorf("expected other fields to be kept, got %v", payload)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"gen-1","model":"` + model + `","usage":{"cost":0.6}}`))
+	}, enforcer)
+
+	send := func() *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/p/agent/v1/chat/completions", strings.NewReader(`{"model":"openai/gpt-4o","temperature":0.5}`))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := send()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, resp.StatusCode)
+		}
+		waitRecorded(t, sub)
+	}
+	if len(models) != 2 || models[0] != "openai/gpt-4o" || models[1] != "cheap/model" {
+		t.Fatalf("expected second request to be downgraded, got %v", models)
+	}
+
+	resp := send()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	if body.Error.Code != http.StatusTooManyRequests || !strings.Contains(body.Error.Message, "project agent") {
+		t.Fatalf("unexpected error body: %+v", body)
+	}
+	if len(models) != 2 {
+		t.Fatalf("expected blocked request not to reach upstream")
+	}
+}
+
+func TestProxyUnknownPath(t *testing.T) {
+	srv, _, _ := newTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upstream request: %s", r.URL.Path)
+	}, nil)
+	resp, err := http.Get(srv.URL + "/other")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	p, err := New(Options{})
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+	cases := []struct {
+		path, header, project, rest string
+	}{
+		{"/v1/chat/completions", "", DefaultProject, "/chat/completions"},
+		{"/api/v1/models", "web", "web", "/models"},
+		{"/p/repo/v1/chat/completions", "web", "repo", "/chat/completions"},
+		{"/p/repo/other", "", "repo", ""},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			r.Header.Set(DefaultProjectHeader, tc.header)
+		}
+		project, rest := p.route(r)
+		if project != tc.project || rest != tc.rest {
+			t.Fatalf("%s: expected %q %q, got %q %q", tc.path, tc.project, tc.rest, project, rest)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package redact masks API keys and other secrets in text before it is
+// logged, stored in state or shown to the user.
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+// Mask replaces every secret.
+const Mask = "[REDACTED]"
+
+// minSecretLen keeps short config values from masking ordinary words.
+const minSecretLen = 8
+
+var patterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// OpenRouter keys, e.g. sk-or-v1-0123...
+	{regexp.MustCompile(`sk-or-[A-Za-z0-9_-]+`), Mask},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + Mask},
+}
+
+// ConfigSecrets returns the secrets stored in cfg.
+func ConfigSecrets(cfg config.Config) []string {
+	secrets := []string{
+		cfg.Connection.Token,
+		cfg.Connection.ProvisioningKey,
+		cfg.MQTT.Password,
+		cfg.Export.Influx.Token,
+	}
+	if u, err := url.Parse(cfg.Connection.Proxy); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+	}
+	// Telemetry headers usually carry collector credentials.
+	for _, value := range cfg.Telemetry.Headers {
+		secrets = append(secrets, value)
+	}
+	return secrets
+}
+
+// String masks secrets and anything that looks like an API key in s.
+func String(s string, secrets ...string) string {
+	for _, secret := range usable(secrets) {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+// Error returns err with a masked message. errors.Is and errors.As still see
+// the original error.
+func Error(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	masked := String(msg, secrets...)
+	if masked == msg {
+		return err
+	}
+	return &redactedError{msg: masked, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// usable drops values too short to be secrets and sorts the rest longest
+// first, so a key that contains another is masked whole.
+func usable(secrets []string) []string {
+	out := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if len(strings.TrimSpace(secret)) >= minSecretLen {
+			out = append(out, secret)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return len(out[i]) > len(out[j])
+	})
+	return out
+}
+
+// Redactor holds the current secrets, which change when the config is saved.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.SetSecrets(secrets...)
+	return r
+}
+
+// SetSecrets replaces the secrets to mask.
+func (r *Redactor) SetSecrets(secrets ...string) {
+	secrets = usable(secrets)
+	r.mu.Lock()
+	r.secrets = secrets
+	r.mu.Unlock()
+}
+
+// String masks the current secrets in s.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	secrets := r.secrets
+	r.mu.RUnlock()
+	return String(s, secrets...)
+}
+
+// Error masks the current secrets in err.
+func (r *Redactor) Error(err error) error {
+	r.mu.RLock()
+	secrets := r.secrets
+	r.mu.RUnlock()
+	return Error(err, secrets...)
+}
@@ -4,47 +4,50 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"openrouter-costs-tray/internal/cache"
 	"openrouter-costs-tray/internal/config"
-	"openrouter-costs-tray/internal/notify"
+	"openrouter-costs-tray/internal/events"
 	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
+	"openrouter-costs-tray/internal/redact"
 	"openrouter-costs-tray/internal/state"
+	"openrouter-costs-tray/internal/trace"
 	"openrouter-costs-tray/internal/util"
 )
 
 var ErrNotConfigured = errors.New("token not configured")
 
-// Refresher handles fetching usage, updating cache/state, and notifying.
+// Refresher handles fetching usage, updating cache/state, and publishing events.
 type Refresher struct {
-	client   *openrouter.Client
-	cache    *cache.Store
-	config   *config.Store
-	notifier *notify.Notifier
-	state    *state.State
-	logger   *slog.Logger
-	updateFn func()
+	client *openrouter.Client
+	cache  *cache.Store
+	config *config.Store
+	bus    *events.Bus
+	state  *state.State
+	tracer trace.Recorder
+	logger *slog.Logger
+
+	// creditsFailing is set after a credits fetch failure was logged at Warn.
+	creditsFailing atomic.Bool
 }
 
-func New(client *openrouter.Client, cacheStore *cache.Store, cfgStore *config.Store, notifier *notify.Notifier, stateStore *state.State, logger *slog.Logger) *Refresher {
+func New(client *openrouter.Client, cacheStore *cache.Store, cfgStore *config.Store, bus *events.Bus, stateStore *state.State, logger *slog.Logger) *Refresher {
 	if logger == nil {
 		logger = slog.Default()
 	}
 	return &Refresher{
-		client:   client,
-		cache:    cacheStore,
-		config:   cfgStore,
-		notifier: notifier,
-		state:    stateStore,
-		logger:   logger,
+		client: client,
+		cache:  cacheStore,
+		config: cfgStore,
+		bus:    bus,
+		state:  stateStore,
+		logger: logger,
 	}
 }
 
-func (r *Refresher) SetUpdateCallback(fn func()) {
-	r.updateFn = fn
-}
-
 func computeDelta(prev *cache.CostsCache, tokenHash string, currentTotal float64) float64 {
 	if prev == nil || prev.KeyHash == "" || prev.KeyHash != tokenHash {
 		return 0
@@ -56,26 +59,49 @@ func computeDelta(prev *cache.CostsCache, tokenHash string, currentTotal float64
 	return delta
 }
 
+// SetTracer makes every refresh a trace recorded by rec. Call it before the
+// first refresh.
+func (r *Refresher) SetTracer(rec trace.Recorder) {
+	r.tracer = rec
+}
+
+// Refresh fetches usage, updates cache and state and publishes the result.
 func (r *Refresher) Refresh(ctx context.Context) error {
+	ctx, span := trace.Root(ctx, r.tracer, "refresh", trace.KindInternal)
+	defer span.End()
+	err := r.refresh(ctx)
+	if !errors.Is(err, ErrNotConfigured) {
+		span.SetError(err)
+	}
+	return err
+}
+
+func (r *Refresher) refresh(ctx context.Context) error {
 	cfg := r.config.Get()
-	token := cfg.Connection.Token
-	if token == "" {
+	conn := cfg.Connection
+	if !conn.Configured() {
 		r.logger.Info("refresh skipped: not configured")
 		r.state.SetNotConfigured()
-		r.triggerUpdate()
+		r.bus.Publish(events.NotConfigured{At: time.Now().UTC()})
 		return ErrNotConfigured
 	}
 	r.state.ClearNotConfigured()
 
 	r.logger.Info("refresh started")
-	usage, err := r.client.FetchUsage(ctx, token)
+	r.bus.Publish(events.RefreshStarted{At: time.Now().UTC()})
+	usage, keys, err := r.fetch(ctx, conn)
 	if err != nil {
+		// API errors may echo the request; keep keys out of state and notifications.
+		err = redact.Error(err, redact.ConfigSecrets(cfg)...)
+		if kind := openrouter.NetworkKind(err); kind != "" {
+			r.logger.Warn("refresh failed: offline", "kind", kind, "error", err)
+			r.state.SetOffline()
+			r.bus.Publish(events.RefreshFailed{At: time.Now().UTC(), Err: err, Offline: true})
+			return err
+		}
 		r.logger.Error("refresh failed", "error", err)
 		r.state.SetError(err)
-		if r.notifier != nil {
-			r.notifier.NotifyError(err)
-		}
-		r.triggerUpdate()
+		r.bus.Publish(events.RefreshFailed{At: time.Now().UTC(), Err: err})
 		return err
 	}
 
@@ -90,13 +116,44 @@ func (r *Refresher) Refresh(ctx context.Context) error {
 		}
 	}
 
-	tokenHash := util.TokenHash(token)
+	credential := conn.Token
+	if credential == "" {
+		credential = conn.ProvisioningKey
+	}
+	tokenHash := util.TokenHash(credential)
 	delta := computeDelta(lastCache, tokenHash, usage.Total)
 	r.logger.Info("usage delta computed", "delta", delta)
 	if lastCache != nil && lastCache.KeyHash == tokenHash && delta == 0 && usage.Total < lastCache.TotalUsage {
 		r.logger.Warn("usage total decreased", "previous", lastCache.TotalUsage, "current", usage.Total)
 	}
 
+	var cachedActivity []cache.ModelActivity
+	var cachedKeys []cache.KeyUsage
+	keysListed := false
+	if lastCache != nil && lastCache.KeyHash == tokenHash {
+		cachedActivity = lastCache.Activity
+		cachedKeys = lastCache.Keys
+		keysListed = lastCache.KeysListed
+	}
+	accountToken := conn.ProvisioningKey
+	if accountToken == "" {
+		accountToken = conn.Token
+	}
+	activity := r.fetchActivity(ctx, cfg, accountToken, cachedActivity)
+
+	prices := r.fetchPrices(ctx, cfg, conn.Token, lastCache)
+	var credits *openrouter.Credits
+	if cfg.NeedsCredits() {
+		credits = r.fetchCredits(ctx, accountToken)
+	}
+
+	if keysListed && conn.ProvisioningKey != "" {
+		if change := diffKeys(cachedKeys, keys); len(change.Added) > 0 || len(change.Removed) > 0 || len(change.Disabled) > 0 {
+			r.logger.Info("account keys changed", "added", len(change.Added), "removed", len(change.Removed), "disabled", len(change.Disabled))
+			r.bus.Publish(change)
+		}
+	}
+
 	now := time.Now().UTC()
 	newCache := cache.CostsCache{
 		SchemaVersion: cache.SchemaVersion,
@@ -107,9 +164,19 @@ func (r *Refresher) Refresh(ctx context.Context) error {
 		MonthlyUsage:  usage.Monthly,
 		KeyHash:       tokenHash,
 		KeyID:         usage.KeyID,
+		Activity:      toCacheActivity(activity),
+		Keys:          toCacheKeys(keys),
+		KeysListed:    conn.ProvisioningKey != "",
+		ModelPrices:   toCachePrices(prices),
 	}
 
-	r.logger.Info("refresh succeeded", "total", usage.Total)
+	r.logger.Info("refresh succeeded", "total", usage.Total, "keys", len(keys))
+	trace.FromContext(ctx).SetAttributes(
+		trace.String("openrouter.key.label", usage.Label),
+		trace.Float("openrouter.usage.total", usage.Total),
+		trace.Float("openrouter.usage.delta", delta),
+		trace.Int("openrouter.keys", len(keys)),
+	)
 
 	if r.cache != nil {
 		if err := r.cache.Save(newCache); err != nil {
@@ -118,21 +185,254 @@ func (r *Refresher) Refresh(ctx context.Context) error {
 	}
 
 	r.state.SetSuccess(usage, now)
-	if delta > 0 {
-		if r.notifier != nil {
-			r.notifier.NotifyUpdateSpent(delta)
+	r.state.SetActivity(activity)
+	r.state.SetKeys(keys)
+	r.state.SetPrices(prices)
+	r.state.SetCredits(credits)
+	r.bus.Publish(events.RefreshSucceeded{At: now, Usage: usage, Delta: delta, Keys: keys, Credits: credits})
+	return nil
+}
+
+// fetch returns the usage shown in the tray together with the account keys
+// when a provisioning key is set. Without a regular token the account total
+// across all keys is used.
+func (r *Refresher) fetch(ctx context.Context, conn config.ConnectionConfig) (openrouter.Usage, []openrouter.KeyInfo, error) {
+	var keys []openrouter.KeyInfo
+	if conn.ProvisioningKey != "" {
+		listed, err := r.client.ListKeys(ctx, conn.ProvisioningKey)
+		if err != nil {
+			return openrouter.Usage{}, nil, err
 		}
+		keys = listed
 	}
-	r.triggerUpdate()
-	return nil
+	if conn.Token == "" {
+		return openrouter.AccountUsage(keys), keys, nil
+	}
+	usage, err := r.client.FetchUsage(ctx, conn.Token)
+	if err != nil {
+		return openrouter.Usage{}, nil, err
+	}
+	return usage, keys, nil
 }
 
-func (r *Refresher) TestToken(ctx context.Context, token string) (openrouter.Usage, error) {
-	return r.client.FetchUsage(ctx, token)
+// fetchActivity returns the per-model breakdown, falling back to the cached one
+// when the request fails. Failures never fail the refresh.
+func (r *Refresher) fetchActivity(ctx context.Context, cfg config.Config, token string, cached []cache.ModelActivity) []openrouter.ActivityItem {
+	if !cfg.Updates.FetchActivity {
+		return nil
+	}
+	items, err := r.client.FetchActivity(ctx, token, "")
+	if err != nil {
+		r.logger.Warn("activity fetch failed", "error", err)
+		return ActivityFromCache(cached)
+	}
+	r.logger.Debug("activity fetched", "items", len(items))
+	return items
+}
+
+// fetchCredits returns the account balance, or nil when the key may not read
+// it. Only the first of consecutive failures is a warning.
+func (r *Refresher) fetchCredits(ctx context.Context, token string) *openrouter.Credits {
+	credits, err := r.client.FetchCredits(ctx, token)
+	if err != nil {
+		if r.creditsFailing.Swap(true) {
+			r.logger.Debug("credits fetch failed", "error", err)
+		} else {
+			r.logger.Warn("credits fetch failed, credit values will be empty", "error", err)
+		}
+		return nil
+	}
+	r.creditsFailing.Store(false)
+	return &credits
+}
+
+// fetchPrices returns prices of watched models and publishes changes against
+// the cached ones. When the catalogue is unavailable the cached prices are kept.
+func (r *Refresher) fetchPrices(ctx context.Context, cfg config.Config, token string, lastCache *cache.CostsCache) map[string]pricing.Price {
+	if len(cfg.Models.Watch) == 0 {
+		return nil
+	}
+	var prev map[string]pricing.Price
+	if lastCache != nil {
+		prev = PricesFromCache(lastCache.ModelPrices)
+	}
+	models, err := r.client.FetchModels(ctx, token)
+	if err != nil {
+		r.logger.Warn("model catalogue fetch failed", "error", err)
+		return prev
+	}
+	curr := pricing.Watched(models, cfg.Models.Watch)
+	if changes := pricing.Diff(prev, curr, cfg.Models.Watch); len(changes) > 0 {
+		for _, change := range changes {
+			r.logger.Info("watched model price changed", "kind", change.Kind, "model", change.Model)
+		}
+		r.bus.Publish(events.PricesChanged{Changes: changes})
+	}
+	return curr
+}
+
+// diffKeys compares the cached key listing with the current one and reports
+// keys that were added, removed or became disabled. The caller skips it when
+// there is no previous listing to compare against.
+func diffKeys(prev []cache.KeyUsage, curr []openrouter.KeyInfo) events.KeysChanged {
+	var change events.KeysChanged
+	wasDisabled := make(map[string]bool, len(prev))
+	for _, key := range prev {
+		wasDisabled[key.Hash] = key.Disabled
+	}
+	seen := make(map[string]bool, len(curr))
+	for _, key := range curr {
+		seen[key.Hash] = true
+		known, ok := wasDisabled[key.Hash]
+		switch {
+		case !ok:
+			change.Added = append(change.Added, key)
+		case key.Disabled && !known:
+			change.Disabled = append(change.Disabled, key)
+		}
+	}
+	for _, key := range KeysFromCache(prev) {
+		if !seen[key.Hash] {
+			change.Removed = append(change.Removed, key)
+		}
+	}
+	return change
+}
+
+// SnapshotFromCache rebuilds the state last seen by a refresh.
+func SnapshotFromCache(cached *cache.CostsCache) state.Snapshot {
+	if cached == nil {
+		return state.Snapshot{}
+	}
+	return state.Snapshot{
+		LastSuccessAt: cached.LastSuccessAt,
+		Usage: openrouter.Usage{
+			Total:   cached.TotalUsage,
+			Daily:   cached.DailyUsage,
+			Weekly:  cached.WeeklyUsage,
+			Monthly: cached.MonthlyUsage,
+			KeyID:   cached.KeyID,
+		},
+		Activity: ActivityFromCache(cached.Activity),
+		Keys:     KeysFromCache(cached.Keys),
+		Prices:   PricesFromCache(cached.ModelPrices),
+	}
+}
+
+// ActivityFromCache converts the cached breakdown back into API items.
+func ActivityFromCache(items []cache.ModelActivity) []openrouter.ActivityItem {
+	if len(items) == 0 {
+		return nil
+	}
+	out := make([]openrouter.ActivityItem, 0, len(items))
+	for _, item := range items {
+		out = append(out, openrouter.ActivityItem{
+			Date:             item.Date,
+			Model:            item.Model,
+			Usage:            item.Usage,
+			Requests:         item.Requests,
+			PromptTokens:     item.PromptTokens,
+			CompletionTokens: item.CompletionTokens,
+		})
+	}
+	return out
+}
+
+func toCacheActivity(items []openrouter.ActivityItem) []cache.ModelActivity {
+	if len(items) == 0 {
+		return nil
+	}
+	out := make([]cache.ModelActivity, 0, len(items))
+	for _, item := range items {
+		out = append(out, cache.ModelActivity{
+			Date:             item.Date,
+			Model:            item.Model,
+			Usage:            item.Usage,
+			Requests:         item.Requests,
+			PromptTokens:     item.PromptTokens,
+			CompletionTokens: item.CompletionTokens,
+		})
+	}
+	return out
+}
+
+// KeysFromCache converts cached per-key usage back into API key info.
+func KeysFromCache(keys []cache.KeyUsage) []openrouter.KeyInfo {
+	if len(keys) == 0 {
+		return nil
+	}
+	out := make([]openrouter.KeyInfo, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, openrouter.KeyInfo{
+			Hash:     key.Hash,
+			Name:     key.Name,
+			Label:    key.Label,
+			Disabled: key.Disabled,
+			Usage: openrouter.Usage{
+				Total:   key.TotalUsage,
+				Daily:   key.DailyUsage,
+				Weekly:  key.WeeklyUsage,
+				Monthly: key.MonthlyUsage,
+				Limit:   key.Limit,
+				KeyID:   key.Hash,
+				Label:   key.Name,
+			},
+		})
+	}
+	return out
 }
 
-func (r *Refresher) triggerUpdate() {
-	if r.updateFn != nil {
-		r.updateFn()
+func toCacheKeys(keys []openrouter.KeyInfo) []cache.KeyUsage {
+	if len(keys) == 0 {
+		return nil
+	}
+	out := make([]cache.KeyUsage, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, cache.KeyUsage{
+			Hash:         key.Hash,
+			Name:         key.Name,
+			Label:        key.Label,
+			Disabled:     key.Disabled,
+			TotalUsage:   key.Usage.Total,
+			DailyUsage:   key.Usage.Daily,
+			WeeklyUsage:  key.Usage.Weekly,
+			MonthlyUsage: key.Usage.Monthly,
+			Limit:        key.Usage.Limit,
+		})
+	}
+	return out
+}
+
+// PricesFromCache converts cached watched-model prices.
+func PricesFromCache(prices map[string]cache.ModelPrice) map[string]pricing.Price {
+	if prices == nil {
+		return nil
+	}
+	out := make(map[string]pricing.Price, len(prices))
+	for id, price := range prices {
+		out[id] = pricing.Price{Prompt: price.Prompt, Completion: price.Completion}
 	}
+	return out
+}
+
+func toCachePrices(prices map[string]pricing.Price) map[string]cache.ModelPrice {
+	if prices == nil {
+		return nil
+	}
+	out := make(map[string]cache.ModelPrice, len(prices))
+	for id, price := range prices {
+		out[id] = cache.ModelPrice{Prompt: price.Prompt, Completion: price.Completion}
+	}
+	return out
+}
+
+func (r *Refresher) TestToken(ctx context.Context, token string) (openrouter.Usage, error) {
+	usage, err := r.client.FetchUsage(ctx, token)
+	return usage, redact.Error(err, token)
+}
+
+// TestProvisioningKey lists the account keys to validate a provisioning key.
+func (r *Refresher) TestProvisioningKey(ctx context.Context, key string) ([]openrouter.KeyInfo, error) {
+	keys, err := r.client.ListKeys(ctx, key)
+	return keys, redact.Error(err, key)
 }
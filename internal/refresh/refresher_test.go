@@ -5,17 +5,22 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"openrouter-costs-tray/internal/cache"
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
 	"openrouter-costs-tray/internal/openrouter"
 	"openrouter-costs-tray/internal/state"
+	"openrouter-costs-tray/internal/trace"
 	"openrouter-costs-tray/internal/util"
 )
 
@@ -52,16 +57,20 @@ func TestRefreshNotConfigured(t *testing.T) {
 	cfgStore := config.NewStore("unused", cfg)
 	stateStore := state.New()
 
-	refresher := New(nil, nil, cfgStore, nil, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
-	updates := 0
-	refresher.SetUpdateCallback(func() { updates++ })
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	refresher := New(nil, nil, cfgStore, bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
 
 	err := refresher.Refresh(context.Background())
 	if !errors.Is(err, ErrNotConfigured) {
 		t.Fatalf("expected not configured error, got %v", err)
 	}
-	if updates != 1 {
-		t.Fatalf("expected update callback to be called once, got %d", updates)
+	published := drainEvents(sub)
+	if len(published) != 1 {
+		t.Fatalf("expected one event, got %d", len(published))
+	}
+	if _, ok := published[0].(events.NotConfigured); !ok {
+		t.Fatalf("expected not configured event, got %T", published[0])
 	}
 	snap := stateStore.Snapshot()
 	if !snap.NotConfigured {
@@ -82,16 +91,27 @@ func TestRefreshSuccess(t *testing.T) {
 	cachePath := filepath.Join(t.TempDir(), cache.CacheFileName)
 	cacheStore := cache.NewStore(cachePath)
 
-	refresher := New(client, cacheStore, cfgStore, nil, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
-	updates := 0
-	refresher.SetUpdateCallback(func() { updates++ })
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	refresher := New(client, cacheStore, cfgStore, bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
 
 	before := time.Now().UTC()
 	if err := refresher.Refresh(context.Background()); err != nil {
 		t.Fatalf("refresh failed: %v", err)
 	}
-	if updates != 1 {
-		t.Fatalf("expected update callback to be called once, got %d", updates)
+	published := drainEvents(sub)
+	if len(published) != 2 {
+		t.Fatalf("expected two events, got %d", len(published))
+	}
+	if _, ok := published[0].(events.RefreshStarted); !ok {
+		t.Fatalf("expected refresh started event, got %T", published[0])
+	}
+	succeeded, ok := published[1].(events.RefreshSucceeded)
+	if !ok {
+		t.Fatalf("expected refresh succeeded event, got %T", published[1])
+	}
+	if succeeded.Usage.Total != 12.34 || succeeded.Delta != 0 {
+		t.Fatalf("unexpected succeeded event: %+v", succeeded)
 	}
 
 	snap := stateStore.Snapshot()
@@ -136,16 +156,21 @@ func TestRefreshUnauthorized(t *testing.T) {
 	cachePath := filepath.Join(t.TempDir(), cache.CacheFileName)
 	cacheStore := cache.NewStore(cachePath)
 
-	refresher := New(client, cacheStore, cfgStore, nil, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
-	updates := 0
-	refresher.SetUpdateCallback(func() { updates++ })
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	refresher := New(client, cacheStore, cfgStore, bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
 
 	err := refresher.Refresh(context.Background())
 	if !errors.Is(err, openrouter.ErrUnauthorized) {
 		t.Fatalf("expected unauthorized error, got %v", err)
 	}
-	if updates != 1 {
-		t.Fatalf("expected update callback to be called once, got %d", updates)
+	published := drainEvents(sub)
+	if len(published) != 2 {
+		t.Fatalf("expected two events, got %d", len(published))
+	}
+	failed, ok := published[1].(events.RefreshFailed)
+	if !ok || !errors.Is(failed.Err, openrouter.ErrUnauthorized) {
+		t.Fatalf("expected refresh failed event, got %+v", published[1])
 	}
 	snap := stateStore.Snapshot()
 	if snap.LastError == "" {
@@ -156,9 +181,272 @@ func TestRefreshUnauthorized(t *testing.T) {
 	}
 }
 
+func TestRefreshStoresActivity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/key":
+			_, _ = w.Write([]byte(`{"data":{"usage":5,"id":"key-id"}}`))
+		case "/activity":
+			_, _ = w.Write([]byte(`{"data":[{"date":"2025-02-03","model":"openai/gpt-4o","usage":0.25,"requests":2}]}`))
+		case "/credits":
+			_, _ = w.Write([]byte(`{"data":{"total_credits":10,"total_usage":5}}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := openrouter.NewClient(server.URL, server.Client(), nil)
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	cfg.Updates.FetchActivity = true
+	cfg.MQTT.Enabled = true
+	cfgStore := config.NewStore("unused", cfg)
+	stateStore := state.New()
+	cachePath := filepath.Join(t.TempDir(), cache.CacheFileName)
+	cacheStore := cache.NewStore(cachePath)
+
+	refresher := New(client, cacheStore, cfgStore, nil, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	snap := stateStore.Snapshot()
+	if len(snap.Activity) != 1 || snap.Activity[0].Model != "openai/gpt-4o" {
+		t.Fatalf("expected activity in state, got %+v", snap.Activity)
+	}
+	if snap.Credits == nil || snap.Credits.Remaining() != 5 {
+		t.Fatalf("expected credits in state, got %+v", snap.Credits)
+	}
+	loaded, err := cache.LoadFromPath(cachePath)
+	if err != nil || loaded == nil {
+		t.Fatalf("cache load failed: %v", err)
+	}
+	if len(loaded.Activity) != 1 || loaded.Activity[0].Usage != 0.25 || loaded.Activity[0].Requests != 2 {
+		t.Fatalf("expected activity in cache, got %+v", loaded.Activity)
+	}
+}
+
+func TestRefreshCreditsOnlyWhenNeeded(t *testing.T) {
+	var creditRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/key":
+			_, _ = w.Write([]byte(`{"data":{"usage":5}}`))
+		case "/credits":
+			creditRequests++
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := openrouter.NewClient(server.URL, server.Client(), nil)
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	cfgStore := config.NewStore("unused", cfg)
+	logs := &strings.Builder{}
+	refresher := New(client, nil, cfgStore, nil, state.New(), slog.New(slog.NewTextHandler(logs, nil)))
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if creditRequests != 0 {
+		t.Fatalf("expected no credits request without a consumer, got %d", creditRequests)
+	}
+
+	cfg.Dashboard.Enabled = true
+	cfgStore.Set(cfg)
+	for i := 0; i < 2; i++ {
+		if err := refresher.Refresh(context.Background()); err != nil {
+			t.Fatalf("refresh failed: %v", err)
+		}
+	}
+	if creditRequests != 2 {
+		t.Fatalf("expected credits on every refresh once needed, got %d", creditRequests)
+	}
+	if warnings := strings.Count(logs.String(), "level=WARN msg=\"credits fetch failed"); warnings != 1 {
+		t.Fatalf("expected one credits warning, got %d:\n%s", warnings, logs)
+	}
+}
+
+func TestRefreshProvisioningKeys(t *testing.T) {
+	keysBody := `{"data":[{"hash":"h1","name":"ci","usage":2,"usage_daily":0.5}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys":
+			_, _ = w.Write([]byte(keysBody))
+		case "/credits":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := openrouter.NewClient(server.URL, server.Client(), nil)
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.ProvisioningKey = "prov"
+	cfgStore := config.NewStore("unused", cfg)
+	stateStore := state.New()
+	cacheStore := cache.NewStore(filepath.Join(t.TempDir(), cache.CacheFileName))
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(8)
+
+	refresher := New(client, cacheStore, cfgStore, bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	snap := stateStore.Snapshot()
+	if snap.Usage.Total != 2 || len(snap.Keys) != 1 {
+		t.Fatalf("expected account usage from keys, got %+v", snap)
+	}
+	for _, ev := range drainEvents(sub) {
+		if _, ok := ev.(events.KeysChanged); ok {
+			t.Fatalf("expected no key changes on first listing")
+		}
+	}
+
+	keysBody = `{"data":[{"hash":"h1","name":"ci","disabled":true,"usage":2},{"hash":"h2","name":"new","usage":1}]}`
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	var changed *events.KeysChanged
+	for _, ev := range drainEvents(sub) {
+		if e, ok := ev.(events.KeysChanged); ok {
+			changed = &e
+		}
+	}
+	if changed == nil {
+		t.Fatalf("expected keys changed event")
+	}
+	if len(changed.Added) != 1 || changed.Added[0].Hash != "h2" {
+		t.Fatalf("unexpected added keys: %+v", changed.Added)
+	}
+	if len(changed.Disabled) != 1 || changed.Disabled[0].Hash != "h1" {
+		t.Fatalf("unexpected disabled keys: %+v", changed.Disabled)
+	}
+	if got := stateStore.Snapshot().Usage.Total; got != 3 {
+		t.Fatalf("expected account total 3, got %v", got)
+	}
+
+	keysChanged := func() *events.KeysChanged {
+		t.Helper()
+		if err := refresher.Refresh(context.Background()); err != nil {
+			t.Fatalf("refresh failed: %v", err)
+		}
+		for _, ev := range drainEvents(sub) {
+			if e, ok := ev.(events.KeysChanged); ok {
+				return &e
+			}
+		}
+		return nil
+	}
+	keysBody = `{"data":[]}`
+	if changed := keysChanged(); changed == nil || len(changed.Removed) != 2 {
+		t.Fatalf("expected both keys removed, got %+v", changed)
+	}
+	keysBody = `{"data":[{"hash":"h4","name":"fresh","usage":0}]}`
+	if changed := keysChanged(); changed == nil || len(changed.Added) != 1 || changed.Added[0].Hash != "h4" {
+		t.Fatalf("expected a key added after an empty listing, got %+v", changed)
+	}
+}
+
+func TestDiffKeys(t *testing.T) {
+	prev := []cache.KeyUsage{{Hash: "h1", Name: "ci"}, {Hash: "h2", Name: "old"}}
+	change := diffKeys(prev, []openrouter.KeyInfo{
+		{Hash: "h1", Name: "ci", Disabled: true},
+		{Hash: "h3", Name: "new"},
+	})
+	if len(change.Added) != 1 || change.Added[0].Hash != "h3" {
+		t.Fatalf("unexpected added keys: %+v", change.Added)
+	}
+	if len(change.Removed) != 1 || change.Removed[0].Hash != "h2" || change.Removed[0].Name != "old" {
+		t.Fatalf("unexpected removed keys: %+v", change.Removed)
+	}
+	if len(change.Disabled) != 1 || change.Disabled[0].Hash != "h1" {
+		t.Fatalf("unexpected disabled keys: %+v", change.Disabled)
+	}
+
+	change = diffKeys(nil, []openrouter.KeyInfo{{Hash: "h1"}})
+	if len(change.Added) != 1 || len(change.Removed) != 0 {
+		t.Fatalf("expected the first key on an empty account to be added, got %+v", change)
+	}
+	change = diffKeys(prev, nil)
+	if len(change.Removed) != 2 || len(change.Added) != 0 {
+		t.Fatalf("expected every key removed, got %+v", change)
+	}
+}
+
+func TestRefreshWatchedPrices(t *testing.T) {
+	modelsBody := `{"data":[{"id":"a","pricing":{"prompt":"0.000001","completion":"0.000002"}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/key":
+			_, _ = w.Write([]byte(`{"data":{"usage":1}}`))
+		case "/models":
+			_, _ = w.Write([]byte(modelsBody))
+		case "/credits":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := openrouter.NewClient(server.URL, server.Client(), nil)
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	cfg.Models.Watch = []string{"a"}
+	cfgStore := config.NewStore("unused", cfg)
+	stateStore := state.New()
+	cacheStore := cache.NewStore(filepath.Join(t.TempDir(), cache.CacheFileName))
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(8)
+
+	refresher := New(client, cacheStore, cfgStore, bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if price, ok := stateStore.Snapshot().Prices["a"]; !ok || price.Completion != 0.000002 {
+		t.Fatalf("expected watched price in state")
+	}
+	drainEvents(sub)
+
+	modelsBody = `{"data":[{"id":"a","pricing":{"prompt":"0.000001","completion":"0.000003"}},{"id":"a:free","pricing":{"prompt":"0","completion":"0"}}]}`
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	var changed *events.PricesChanged
+	for _, ev := range drainEvents(sub) {
+		if e, ok := ev.(events.PricesChanged); ok {
+			changed = &e
+		}
+	}
+	if changed == nil || len(changed.Changes) != 2 {
+		t.Fatalf("expected increase and free variant changes, got %+v", changed)
+	}
+}
+
+func drainEvents(sub *events.Subscription) []events.Event {
+	var out []events.Event
+	for {
+		select {
+		case ev := <-sub.C():
+			out = append(out, ev)
+		default:
+			return out
+		}
+	}
+}
+
 func newTestClient(t *testing.T, status int, body string) *openrouter.Client {
 	t.Helper()
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/credits" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		if r.URL.Path != "/auth/key" {
 			t.Errorf("unexpected path: %s", r.URL.Path)
 		}
@@ -171,3 +459,117 @@ func newTestClient(t *testing.T, status int, body string) *openrouter.Client {
 	t.Cleanup(server.Close)
 	return openrouter.NewClient(server.URL, server.Client(), nil)
 }
+
+func TestRefreshErrorRedactsSecrets(t *testing.T) {
+	const secret = "custom-secret-token"
+	client := newTestClient(t, http.StatusBadRequest, "bad key "+secret+", did you mean sk-or-v1-feedface?")
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = secret
+	stateStore := state.New()
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	logs := &strings.Builder{}
+	refresher := New(client, nil, config.NewStore("unused", cfg), bus, stateStore, slog.New(slog.NewTextHandler(logs, nil)))
+
+	err := refresher.Refresh(context.Background())
+	var apiErr *openrouter.APIError
+	if err == nil || !errors.As(err, &apiErr) {
+		t.Fatalf("expected API error to stay inspectable, got %v", err)
+	}
+	published := drainEvents(sub)
+	failed, _ := published[len(published)-1].(events.RefreshFailed)
+	for name, text := range map[string]string{
+		"error": err.Error(),
+		"event": failed.Err.Error(),
+		"state": stateStore.Snapshot().LastError,
+		"logs":  logs.String(),
+	} {
+		if strings.Contains(text, secret) || strings.Contains(text, "sk-or-") {
+			t.Fatalf("%s leaks a key: %s", name, text)
+		}
+	}
+
+	if _, err := refresher.TestToken(context.Background(), secret); err == nil || strings.Contains(err.Error(), secret) {
+		t.Fatalf("expected token masked in test error, got %v", err)
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Enabled() bool { return true }
+
+func (r *spanRecorder) RecordSpan(s trace.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func TestRefreshTraced(t *testing.T) {
+	client := newTestClient(t, http.StatusOK, `{"data":{"label":"laptop","usage":4.5}}`)
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	cfg.Telemetry.Enabled = true
+	refresher := New(client, nil, config.NewStore("unused", cfg), events.NewBus(nil), state.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	rec := &spanRecorder{}
+	refresher.SetTracer(rec)
+
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	root := rec.spans[len(rec.spans)-1]
+	if root.Name != "refresh" || !root.ParentID.IsZero() || root.Status != trace.StatusUnset {
+		t.Fatalf("unexpected root span %+v", root)
+	}
+	names := map[string]bool{}
+	for _, span := range rec.spans[:len(rec.spans)-1] {
+		if span.TraceID != root.TraceID {
+			t.Fatalf("span %q is in another trace", span.Name)
+		}
+		names[span.Name] = true
+	}
+	if !names["openrouter.FetchUsage"] || !names["GET /auth/key"] || !names["GET /credits"] {
+		t.Fatalf("missing child spans, got %v", names)
+	}
+	var label any
+	for _, attr := range root.Attrs {
+		if attr.Key == "openrouter.key.label" {
+			label = attr.Value
+		}
+	}
+	if label != "laptop" {
+		t.Fatalf("expected key label on root span, got %+v", root.Attrs)
+	}
+}
+
+func TestRefreshOffline(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := closed.Addr().String()
+	closed.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	stateStore := state.New()
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	client := openrouter.NewClient("http://"+addr, nil, nil)
+	refresher := New(client, nil, config.NewStore("unused", cfg), bus, stateStore, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := refresher.Refresh(context.Background()); !openrouter.IsNetworkError(err) {
+		t.Fatalf("expected network error, got %v", err)
+	}
+	published := drainEvents(sub)
+	failed, ok := published[len(published)-1].(events.RefreshFailed)
+	if !ok || !failed.Offline {
+		t.Fatalf("expected offline refresh failure, got %+v", published)
+	}
+	if snap := stateStore.Snapshot(); !snap.Offline || snap.LastError != "" {
+		t.Fatalf("expected offline state without error, got %+v", snap)
+	}
+}
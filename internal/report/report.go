@@ -0,0 +1,294 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+)
+
+// Period is the aggregation bucket of an export.
+type Period string
+
+const (
+	None  Period = "none"
+	Day   Period = "day"
+	Week  Period = "week"
+	Month Period = "month"
+)
+
+var (
+	Periods = []string{string(None), string(Day), string(Week), string(Month)}
+	Formats = []string{"csv", "json", "jsonl"}
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	sampleColumns = []string{"at", "key_id", "key_label", "total", "daily", "weekly", "monthly"}
+	totalColumns  = []string{"period_start", "period_end", "key_id", "key_label", "spend", "samples"}
+)
+
+// Options selects what Write exports. Samples in [From, To) are exported;
+// earlier ones only serve as the baseline of the first period.
+type Options struct {
+	From      time.Time
+	To        time.Time
+	Location  *time.Location
+	Aggregate Period
+	Format    string
+}
+
+// SamplesFromRefresh returns one sample per key seen by a refresh. In
+// provisioning mode the summed account usage is skipped in favour of the keys,
+// and the configured key is not recorded twice.
+func SamplesFromRefresh(ev events.RefreshSucceeded) []history.SpendSample {
+	var out []history.SpendSample
+	usage := ev.Usage
+	if len(ev.Keys) == 0 || usage.KeyID != "" || usage.Label != "" {
+		out = append(out, history.SpendSample{
+			At: ev.At, KeyID: usage.KeyID, KeyLabel: usage.Label,
+			Total: usage.Total, Daily: usage.Daily, Weekly: usage.Weekly, Monthly: usage.Monthly,
+		})
+	}
+	for _, key := range ev.Keys {
+		if usage.Label != "" && key.Label == usage.Label {
+			continue
+		}
+		out = append(out, history.SpendSample{
+			At: ev.At, KeyID: key.Usage.KeyID, KeyLabel: key.Usage.Label,
+			Total: key.Usage.Total, Daily: key.Usage.Daily, Weekly: key.Usage.Weekly, Monthly: key.Usage.Monthly,
+		})
+	}
+	return out
+}
+
+// Range turns inclusive YYYY-MM-DD dates into a [from, to) range in loc.
+// An empty from means the first day of the current month, an empty to today.
+func Range(fromDate, toDate string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	var err error
+	if fromDate != "" {
+		if from, err = time.ParseInLocation(dateLayout, fromDate, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q", fromDate)
+		}
+	}
+	if toDate != "" {
+		if to, err = time.ParseInLocation(dateLayout, toDate, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q", toDate)
+		}
+	}
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from date is after to date")
+	}
+	return from, to, nil
+}
+
+// Total is the spend of one key within one period.
+type Total struct {
+	Start    time.Time
+	End      time.Time
+	KeyID    string
+	KeyLabel string
+	Spend    float64
+	Samples  int
+}
+
+// Aggregate sums the spend of every key per period. Spend is the sum of
+// increases between consecutive samples of a key, so a reset key or a gap in
+// refreshes never produces negative spend.
+func Aggregate(samples []history.SpendSample, from, to time.Time, loc *time.Location, period Period) []Total {
+	sorted := sortSamples(samples)
+	last := map[string]float64{}
+	totals := map[string]*Total{}
+	for _, sample := range sorted {
+		id := sample.Key()
+		prev, seen := last[id]
+		last[id] = sample.Total
+		if sample.At.Before(from) || !sample.At.Before(to) {
+			continue
+		}
+		start, end := bucket(sample.At.In(loc), period)
+		// Partial periods at the edges are clipped to the range.
+		if start.Before(from) {
+			start = from.In(loc)
+		}
+		if end.After(to) {
+			end = to.In(loc)
+		}
+		slot := start.Format(dateLayout) + "\x00" + id
+		total, ok := totals[slot]
+		if !ok {
+			total = &Total{Start: start, End: end, KeyID: sample.KeyID}
+			totals[slot] = total
+		}
+		if sample.KeyLabel != "" {
+			total.KeyLabel = sample.KeyLabel
+		}
+		total.Samples++
+		if seen && sample.Total > prev {
+			total.Spend += sample.Total - prev
+		}
+	}
+	out := make([]Total, 0, len(totals))
+	for _, total := range totals {
+		out = append(out, *total)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		if out[i].KeyID != out[j].KeyID {
+			return out[i].KeyID < out[j].KeyID
+		}
+		return out[i].KeyLabel < out[j].KeyLabel
+	})
+	return out
+}
+
+func sortSamples(samples []history.SpendSample) []history.SpendSample {
+	sorted := append([]history.SpendSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].At.Equal(sorted[j].At) {
+			return sorted[i].At.Before(sorted[j].At)
+		}
+		return sorted[i].KeyID < sorted[j].KeyID
+	})
+	return sorted
+}
+
+// bucket returns the [start, end) period containing t, in t's location.
+// Weeks start on Monday.
+func bucket(t time.Time, period Period) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case Week:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case Month:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+// Write exports samples, or per-period totals when opts.Aggregate is set.
+func Write(w io.Writer, samples []history.SpendSample, opts Options) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	var columns []string
+	var rows [][]any
+	if opts.Aggregate == "" || opts.Aggregate == None {
+		columns = sampleColumns
+		for _, s := range sortSamples(samples) {
+			if s.At.Before(opts.From) || !s.At.Before(opts.To) {
+				continue
+			}
+			rows = append(rows, []any{s.At.In(loc).Format(time.RFC3339), s.KeyID, s.KeyLabel, s.Total, s.Daily, s.Weekly, s.Monthly})
+		}
+	} else {
+		columns = totalColumns
+		for _, t := range Aggregate(samples, opts.From, opts.To, loc, opts.Aggregate) {
+			rows = append(rows, []any{t.Start.Format(dateLayout), t.End.AddDate(0, 0, -1).Format(dateLayout), t.KeyID, t.KeyLabel, t.Spend, t.Samples})
+		}
+	}
+
+	switch opts.Format {
+	case "", "csv":
+		return writeCSV(w, columns, rows)
+	case "json":
+		return writeJSON(w, columns, rows, false)
+	case "jsonl":
+		return writeJSON(w, columns, rows, true)
+	default:
+		return fmt.Errorf("unknown format %q", opts.Format)
+	}
+}
+
+func writeCSV(w io.Writer, columns []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, value := range row {
+			record[i] = csvValue(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeJSON writes rows as objects whose keys keep the column order.
+func writeJSON(w io.Writer, columns []string, rows [][]any, lines bool) error {
+	if !lines {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+	}
+	for n, row := range rows {
+		buf := []byte{'{'}
+		for i, value := range row {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			key, _ := json.Marshal(columns[i])
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf = append(buf, key...)
+			buf = append(buf, ':')
+			buf = append(buf, encoded...)
+		}
+		buf = append(buf, '}')
+		switch {
+		case lines:
+			buf = append(buf, '\n')
+		case n > 0:
+			buf = append([]byte{','}, buf...)
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	if !lines {
+		_, err := io.WriteString(w, "]\n")
+		return err
+	}
+	return nil
+}
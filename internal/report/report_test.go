@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func f(v float64) *float64 {
+	return &v
+}
+
+func testSamples() []history.SpendSample {
+	day := func(d, h int) time.Time { return time.Date(2024, 4, d, h, 0, 0, 0, time.UTC) }
+	return []history.SpendSample{
+		{At: day(28, 12), KeyID: "k1", KeyLabel: "ci", Total: 1},
+		{At: day(29, 12), KeyID: "k1", KeyLabel: "ci", Total: 2, Daily: f(1)},
+		{At: day(29, 18), KeyID: "k2", KeyLabel: "web", Total: 5},
+		{At: day(30, 12), KeyID: "k1", KeyLabel: "ci", Total: 4.5},
+		{At: day(30, 13), KeyID: "k2", KeyLabel: "web", Total: 4},
+		{At: day(30, 14), KeyID: "k2", KeyLabel: "web", Total: 4.25},
+	}
+}
+
+func TestSamplesFromRefresh(t *testing.T) {
+	at := time.Now()
+	keys := []openrouter.KeyInfo{
+		{Label: "sk-or-v1-aaa", Usage: openrouter.Usage{KeyID: "h1", Label: "ci", Total: 1}},
+		{Label: "sk-or-v1-bbb", Usage: openrouter.Usage{KeyID: "h2", Label: "web", Total: 2}},
+	}
+
+	got := SamplesFromRefresh(events.RefreshSucceeded{At: at, Usage: openrouter.AccountUsage(keys), Keys: keys})
+	if len(got) != 2 || got[0].KeyID != "h1" || got[1].KeyLabel != "web" {
+		t.Fatalf("expected per-key samples without the account sum, got %+v", got)
+	}
+
+	token := openrouter.Usage{Label: "sk-or-v1-aaa", Total: 1}
+	got = SamplesFromRefresh(events.RefreshSucceeded{At: at, Usage: token, Keys: keys})
+	if len(got) != 2 || got[0].KeyLabel != "sk-or-v1-aaa" || got[1].KeyID != "h2" {
+		t.Fatalf("expected configured key once, got %+v", got)
+	}
+}
+
+func TestRange(t *testing.T) {
+	now := time.Date(2024, 5, 17, 15, 0, 0, 0, time.UTC)
+	from, to, err := Range("", "", time.UTC, now)
+	if err != nil || !from.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected default range %v %v %v", from, to, err)
+	}
+	if _, _, err := Range("2024-05-10", "2024-05-01", time.UTC, now); err == nil {
+		t.Fatalf("expected inverted range to fail")
+	}
+	if _, _, err := Range("May 1", "", time.UTC, now); err == nil {
+		t.Fatalf("expected invalid date to fail")
+	}
+}
+
+func TestAggregateByDay(t *testing.T) {
+	from := time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	got := Aggregate(testSamples(), from, to, time.UTC, Day)
+	if len(got) != 4 {
+		t.Fatalf("expected four day/key totals, got %+v", got)
+	}
+	// The sample before the range is the baseline of the first day.
+	if got[0].KeyID != "k1" || got[0].Spend != 1 || got[0].Samples != 1 {
+		t.Fatalf("unexpected first total: %+v", got[0])
+	}
+	if got[1].KeyID != "k2" || got[1].Spend != 0 {
+		t.Fatalf("expected first sample of a key to have no spend, got %+v", got[1])
+	}
+	if got[2].Spend != 2.5 {
+		t.Fatalf("unexpected k1 spend on 30th: %+v", got[2])
+	}
+	// A decreasing total is a reset, not negative spend.
+	if got[3].Spend != 0.25 || got[3].Samples != 2 {
+		t.Fatalf("unexpected k2 spend on 30th: %+v", got[3])
+	}
+}
+
+func TestAggregateByWeekAndMonth(t *testing.T) {
+	from := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	weeks := Aggregate(testSamples(), from, to, time.UTC, Week)
+	if len(weeks) != 3 || weeks[0].Start.Format(dateLayout) != "2024-04-22" || weeks[1].Start.Format(dateLayout) != "2024-04-29" {
+		t.Fatalf("expected Monday-based weeks, got %+v", weeks)
+	}
+	if !weeks[1].End.Equal(to) {
+		t.Fatalf("expected last week to be clipped to the range, got %v", weeks[1].End)
+	}
+	months := Aggregate(testSamples(), from, to, time.UTC, Month)
+	if len(months) != 2 || months[0].Spend != 3.5 || months[1].Spend != 0.25 {
+		t.Fatalf("unexpected monthly totals: %+v", months)
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	opts := Options{
+		From:     time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		Location: time.FixedZone("CEST", 2*3600),
+		Format:   "csv",
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, testSamples(), opts); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	want := "at,key_id,key_label,total,daily,weekly,monthly\n" +
+		"2024-04-29T14:00:00+02:00,k1,ci,2,1,,\n" +
+		"2024-04-29T20:00:00+02:00,k2,web,5,,,\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	opts.Format = "json"
+	opts.Aggregate = Day
+	opts.Location = time.UTC
+	if err := Write(&buf, testSamples(), opts); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `[{"period_start":"2024-04-29","period_end":"2024-04-29","key_id":"k1","key_label":"ci","spend":1,"samples":1}`) {
+		t.Fatalf("unexpected json: %s", buf.String())
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil || len(rows) != 2 {
+		t.Fatalf("expected valid json array of two rows: %v", err)
+	}
+
+	buf.Reset()
+	opts.Format = "jsonl"
+	if err := Write(&buf, testSamples(), opts); err != nil {
+		t.Fatalf("write jsonl: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Fatalf("expected two json lines, got %q", buf.String())
+	}
+
+	if err := Write(&buf, nil, Options{Format: "xml"}); err == nil {
+		t.Fatalf("expected unknown format error")
+	}
+}
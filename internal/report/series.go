@@ -0,0 +1,114 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"openrouter-costs-tray/internal/history"
+)
+
+// Key is a key seen in the recorded samples.
+type Key struct {
+	ID    string
+	Label string
+}
+
+// Name is the label of the key, or its ID when it has none.
+func (k Key) Name() string {
+	if k.Label != "" {
+		return k.Label
+	}
+	return k.ID
+}
+
+// Increase is spend recorded between two consecutive samples of a key.
+type Increase struct {
+	At    time.Time
+	Key   string
+	Spend float64
+}
+
+// Keys lists the keys in samples by name.
+func Keys(samples []history.SpendSample) []Key {
+	seen := map[string]int{}
+	var out []Key
+	for _, sample := range sortSamples(samples) {
+		id := sample.Key()
+		i, ok := seen[id]
+		if !ok {
+			seen[id] = len(out)
+			out = append(out, Key{ID: id})
+			i = len(out) - 1
+		}
+		if sample.KeyLabel != "" {
+			out[i].Label = sample.KeyLabel
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name() < out[j].Name()
+	})
+	return out
+}
+
+// Increases returns the spend between consecutive samples of every key, using
+// the same rules as Aggregate.
+func Increases(samples []history.SpendSample) []Increase {
+	last := map[string]float64{}
+	var out []Increase
+	for _, sample := range sortSamples(samples) {
+		id := sample.Key()
+		prev, seen := last[id]
+		last[id] = sample.Total
+		if seen && sample.Total > prev {
+			out = append(out, Increase{At: sample.At, Key: id, Spend: sample.Total - prev})
+		}
+	}
+	return out
+}
+
+// DailySpend sums the increases of key, or of all keys when key is empty, per
+// day for days days starting on the day of from in loc.
+func DailySpend(increases []Increase, key string, from time.Time, days int, loc *time.Location) []float64 {
+	out := make([]float64, max(days, 0))
+	from = from.In(loc)
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for _, inc := range increases {
+		if key != "" && inc.Key != key {
+			continue
+		}
+		at := inc.At.In(loc)
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)
+		// Round so DST days, which are not 24h long, land in the right slot.
+		i := int(day.Sub(start).Round(24*time.Hour) / (24 * time.Hour))
+		if day.Before(start) || i >= len(out) {
+			continue
+		}
+		out[i] += inc.Spend
+	}
+	return out
+}
+
+// HourlySpend sums the increases of key in [from, to) by weekday and hour of
+// day in loc. Rows start on Monday.
+func HourlySpend(increases []Increase, key string, from, to time.Time, loc *time.Location) [7][24]float64 {
+	var out [7][24]float64
+	for _, inc := range increases {
+		if (key != "" && inc.Key != key) || inc.At.Before(from) || !inc.At.Before(to) {
+			continue
+		}
+		at := inc.At.In(loc)
+		out[(int(at.Weekday())+6)%7][at.Hour()] += inc.Spend
+	}
+	return out
+}
+
+// Cumulative returns the running sum of values.
+func Cumulative(values []float64) []float64 {
+	out := make([]float64, len(values))
+	var sum float64
+	for i, v := range values {
+		sum += v
+		out[i] = sum
+	}
+	return out
+}
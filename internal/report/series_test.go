@@ -0,0 +1,65 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/history"
+)
+
+func seriesSamples() []history.SpendSample {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+	}
+	return []history.SpendSample{
+		{At: at(1, 9), KeyID: "k1", KeyLabel: "dev", Total: 1},
+		{At: at(1, 10), KeyID: "k1", KeyLabel: "dev", Total: 1.5},
+		{At: at(2, 10), KeyID: "k1", KeyLabel: "dev", Total: 2.5},
+		{At: at(3, 14), KeyID: "k1", KeyLabel: "dev", Total: 0.5}, // reset
+		{At: at(3, 15), KeyID: "k1", KeyLabel: "dev", Total: 0.75},
+		{At: at(1, 9), KeyID: "k2", Total: 5},
+		{At: at(2, 10), KeyID: "k2", Total: 6},
+	}
+}
+
+func TestKeysAndIncreases(t *testing.T) {
+	samples := seriesSamples()
+	keys := Keys(samples)
+	if len(keys) != 2 || keys[0].Name() != "dev" || keys[1].Name() != "k2" {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+	incs := Increases(samples)
+	var sum float64
+	for _, inc := range incs {
+		sum += inc.Spend
+	}
+	if len(incs) != 4 || sum != 2.75 {
+		t.Fatalf("unexpected increases %+v", incs)
+	}
+}
+
+func TestDailySpend(t *testing.T) {
+	incs := Increases(seriesSamples())
+	from := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	all := DailySpend(incs, "", from, 4, time.UTC)
+	if len(all) != 4 || all[0] != 0 || all[1] != 0.5 || all[2] != 2 || all[3] != 0.25 {
+		t.Fatalf("unexpected daily spend %v", all)
+	}
+	dev := DailySpend(incs, "k1", from, 3, time.UTC)
+	if dev[2] != 1 {
+		t.Fatalf("expected only dev spend, got %v", dev)
+	}
+	if got := Cumulative(all); got[3] != 2.75 || got[1] != 0.5 {
+		t.Fatalf("unexpected cumulative %v", got)
+	}
+}
+
+func TestHourlySpend(t *testing.T) {
+	incs := Increases(seriesSamples())
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	grid := HourlySpend(incs, "", from, from.AddDate(0, 0, 2), time.UTC)
+	// 2024-05-01 is a Wednesday, the 2nd a Thursday; the 3rd is outside the range.
+	if grid[2][10] != 0.5 || grid[3][10] != 2 || grid[4][15] != 0 {
+		t.Fatalf("unexpected grid wed=%v thu=%v fri=%v", grid[2][10], grid[3][10], grid[4][15])
+	}
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import "time"
+
+// Clock is the time source of the scheduler. Wall and monotonic time are
+// separate readings so that a suspend, which stops the monotonic clock on
+// most systems, shows up as a difference between them.
+type Clock interface {
+	// Now returns the wall clock without a monotonic reading.
+	Now() time.Time
+	// Monotonic returns the time elapsed on the monotonic clock since an
+	// arbitrary origin.
+	Monotonic() time.Duration
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+type realClock struct {
+	origin time.Time
+}
+
+func newRealClock() realClock {
+	return realClock{origin: time.Now()}
+}
+
+func (realClock) Now() time.Time { return time.Now().Round(0) }
+
+func (c realClock) Monotonic() time.Duration { return time.Since(c.origin) }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when told to. Advance moves wall and monotonic
+// time together and fires due timers; Suspend moves only the wall clock, as
+// a sleeping laptop does.
+type fakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	wall   time.Time
+	mono   time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	next   time.Duration
+	period time.Duration // zero for one-shot timers
+}
+
+func newFakeClock(wall time.Time) *fakeClock {
+	f := &fakeClock{wall: wall}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.wall
+}
+
+func (f *fakeClock) Monotonic() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mono
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	return f.add(d, d)
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	return f.add(d, 0).c
+}
+
+func (f *fakeClock) add(d, period time.Duration) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), next: f.mono + d, period: period}
+	f.timers = append(f.timers, t)
+	f.cond.Broadcast()
+	return t
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mono += d
+	f.wall = f.wall.Add(d)
+	kept := f.timers[:0]
+	for _, t := range f.timers {
+		if t.next <= f.mono {
+			select {
+			case t.c <- f.wall:
+			default:
+			}
+			if t.period == 0 {
+				continue
+			}
+			for t.next <= f.mono {
+				t.next += t.period
+			}
+		}
+		kept = append(kept, t)
+	}
+	f.timers = kept
+	f.cond.Broadcast()
+}
+
+func (f *fakeClock) Suspend(d time.Duration) {
+	f.mu.Lock()
+	f.wall = f.wall.Add(d)
+	f.mu.Unlock()
+}
+
+// SetWall moves the wall clock to t, e.g. backwards.
+func (f *fakeClock) SetWall(t time.Time) {
+	f.mu.Lock()
+	f.wall = t
+	f.mu.Unlock()
+}
+
+// BlockUntil waits until n timers are pending, so the code under test has
+// reached its next select before the clock moves.
+func (f *fakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) != n {
+		f.cond.Wait()
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	t.next = t.clock.mono + d
+	t.period = d
+	t.clock.mu.Unlock()
+}
+
+func (t *fakeTimer) Stop() {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			break
+		}
+	}
+	f.cond.Broadcast()
+}
+
+func TestRealClockWallHasNoMonotonicReading(t *testing.T) {
+	c := newRealClock()
+	now := c.Now()
+	if now != now.Round(0) {
+		t.Fatalf("expected wall time without monotonic reading")
+	}
+	if c.Monotonic() < 0 {
+		t.Fatalf("expected non-negative monotonic time")
+	}
+}
@@ -7,13 +7,51 @@ import (
 	"time"
 )
 
+const (
+	// HeartbeatInterval is how often wall and monotonic time are compared.
+	HeartbeatInterval = 30 * time.Second
+	// JumpThreshold is the drift between heartbeats that counts as a
+	// resume or clock jump rather than scheduling noise.
+	JumpThreshold = time.Minute
+	// ResumeGrace gives the network time to come back before refreshing.
+	ResumeGrace = 15 * time.Second
+
+	refreshTimeout = 20 * time.Second
+)
+
+// Jump classifies the time between two heartbeats.
+type Jump int
+
+const (
+	NoJump Jump = iota
+	// Resume means more time passed than the heartbeat should take: the
+	// machine slept or the clock was set forward.
+	Resume
+	// ClockBack means the wall clock was set backwards.
+	ClockBack
+)
+
+// DetectJump compares the wall and monotonic time elapsed over one heartbeat
+// of length expected. On Linux and macOS the monotonic clock stops during
+// suspend, so a sleep shows as wall > mono; on Windows both advance and the
+// heartbeat itself arrives late. The returned gap is the unexpected time.
+func DetectJump(wall, mono, expected time.Duration) (Jump, time.Duration) {
+	if wall < mono-JumpThreshold {
+		return ClockBack, mono - wall
+	}
+	if gap := max(wall, mono) - expected; gap > JumpThreshold {
+		return Resume, gap
+	}
+	return NoJump, 0
+}
+
 type Scheduler struct {
 	mu       sync.Mutex
 	interval time.Duration
-	ticker   *time.Ticker
 	stopCh   chan struct{}
 	running  bool
 	refresh  func(context.Context) error
+	clock    Clock
 	logger   *slog.Logger
 }
 
@@ -21,7 +59,7 @@ func New(interval time.Duration, refresh func(context.Context) error, logger *sl
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Scheduler{interval: interval, refresh: refresh, logger: logger}
+	return &Scheduler{interval: interval, refresh: refresh, clock: newRealClock(), logger: logger}
 }
 
 func (s *Scheduler) Start() {
@@ -32,26 +70,58 @@ func (s *Scheduler) Start() {
 	}
 	s.running = true
 	s.stopCh = make(chan struct{})
-	s.ticker = time.NewTicker(s.interval)
-	s.logger.Info("scheduler started", "interval", s.interval)
+	interval := s.interval
 	stopCh := s.stopCh
-	ticker := s.ticker
 	s.mu.Unlock()
+	s.logger.Info("scheduler started", "interval", interval)
+
+	go s.loop(interval, stopCh)
+}
 
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-				if err := s.refresh(ctx); err != nil {
-					s.logger.Warn("scheduled refresh failed", "error", err)
-				}
-				cancel()
-			case <-stopCh:
-				return
+func (s *Scheduler) loop(interval time.Duration, stopCh chan struct{}) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	// The heartbeat is re-armed after each check rather than ticking, so a
+	// long gap always shows up in a single comparison.
+	heartbeat := s.clock.After(HeartbeatInterval)
+	lastWall, lastMono := s.clock.Now(), s.clock.Monotonic()
+	var resumeC <-chan time.Time
+	for {
+		select {
+		case <-ticker.C():
+			s.run()
+		case <-heartbeat:
+			wall, mono := s.clock.Now(), s.clock.Monotonic()
+			jump, gap := DetectJump(wall.Sub(lastWall), mono-lastMono, HeartbeatInterval)
+			lastWall, lastMono = wall, mono
+			switch jump {
+			case Resume:
+				s.logger.Info("resume detected, refreshing soon", "gap", gap.Round(time.Second), "grace", ResumeGrace)
+			case ClockBack:
+				s.logger.Warn("wall clock jumped backwards, refreshing soon", "gap", gap.Round(time.Second), "grace", ResumeGrace)
 			}
+			if jump != NoJump && resumeC == nil {
+				resumeC = s.clock.After(ResumeGrace)
+			}
+			heartbeat = s.clock.After(HeartbeatInterval)
+		case <-resumeC:
+			resumeC = nil
+			// The refresh below stands in for the missed tick; count the
+			// next period from here.
+			ticker.Reset(interval)
+			s.run()
+		case <-stopCh:
+			return
 		}
-	}()
+	}
+}
+
+func (s *Scheduler) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	if err := s.refresh(ctx); err != nil {
+		s.logger.Warn("scheduled refresh failed", "error", err)
+	}
 }
 
 func (s *Scheduler) Stop() {
@@ -61,13 +131,9 @@ func (s *Scheduler) Stop() {
 		return
 	}
 	s.running = false
-	if s.ticker != nil {
-		s.ticker.Stop()
-	}
 	if s.stopCh != nil {
 		close(s.stopCh)
 	}
-	s.ticker = nil
 	s.stopCh = nil
 	s.mu.Unlock()
 	s.logger.Info("scheduler stopped")
@@ -2,6 +2,8 @@ package scheduler
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -31,3 +33,116 @@ func TestSchedulerReschedule(t *testing.T) {
 		t.Fatalf("expected rescheduled interval")
 	}
 }
+
+func TestDetectJump(t *testing.T) {
+	cases := []struct {
+		name       string
+		wall, mono time.Duration
+		want       Jump
+		gap        time.Duration
+	}{
+		{"regular", 30 * time.Second, 30 * time.Second, NoJump, 0},
+		{"late tick", 70 * time.Second, 70 * time.Second, NoJump, 0},
+		{"suspend", 2 * time.Hour, 30 * time.Second, Resume, 2*time.Hour - 30*time.Second},
+		{"frozen process", time.Hour, time.Hour, Resume, time.Hour - 30*time.Second},
+		{"clock back", -time.Hour, 30 * time.Second, ClockBack, time.Hour + 30*time.Second},
+	}
+	for _, tc := range cases {
+		jump, gap := DetectJump(tc.wall, tc.mono, 30*time.Second)
+		if jump != tc.want || gap != tc.gap {
+			t.Fatalf("%s: expected %v %v, got %v %v", tc.name, tc.want, tc.gap, jump, gap)
+		}
+	}
+}
+
+// newFakeScheduler returns a started scheduler on a fake clock and a channel
+// that receives one value per refresh.
+func newFakeScheduler(t *testing.T, interval time.Duration, logs *strings.Builder) (*fakeClock, chan struct{}) {
+	t.Helper()
+	clock := newFakeClock(time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	refreshed := make(chan struct{}, 4)
+	s := New(interval, func(context.Context) error {
+		refreshed <- struct{}{}
+		return nil
+	}, slog.New(slog.NewTextHandler(logs, nil)))
+	s.clock = clock
+	s.Start()
+	t.Cleanup(s.Stop)
+	// Interval ticker and heartbeat.
+	clock.BlockUntil(2)
+	return clock, refreshed
+}
+
+// advanceBy moves both clocks in steps shorter than the heartbeat and lets
+// the scheduler re-arm it after each step, as a machine that stays awake.
+func advanceBy(clock *fakeClock, d time.Duration) {
+	const step = HeartbeatInterval / 2
+	for ; d > 0; d -= step {
+		clock.Advance(min(d, step))
+		clock.BlockUntil(2)
+	}
+}
+
+func expectRefreshes(t *testing.T, refreshed chan struct{}, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-refreshed:
+		case <-time.After(time.Second):
+			t.Fatalf("expected refresh %d of %d", i+1, n)
+		}
+	}
+	select {
+	case <-refreshed:
+		t.Fatalf("unexpected extra refresh")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSchedulerRefreshesAfterResume(t *testing.T) {
+	logs := &strings.Builder{}
+	clock, refreshed := newFakeScheduler(t, 30*time.Minute, logs)
+
+	clock.Suspend(3 * time.Hour)
+	clock.Advance(HeartbeatInterval)
+	// The heartbeat noticed the gap and armed the grace timer.
+	clock.BlockUntil(3)
+	expectRefreshes(t, refreshed, 0)
+
+	clock.Advance(ResumeGrace)
+	expectRefreshes(t, refreshed, 1)
+	if !strings.Contains(logs.String(), "resume detected") {
+		t.Fatalf("expected resume to be logged, got %q", logs.String())
+	}
+
+	// The interval restarts at the resume refresh.
+	advanceBy(clock, 30*time.Minute-time.Second)
+	expectRefreshes(t, refreshed, 0)
+	advanceBy(clock, time.Second)
+	expectRefreshes(t, refreshed, 1)
+}
+
+func TestSchedulerRegularHeartbeats(t *testing.T) {
+	logs := &strings.Builder{}
+	clock, refreshed := newFakeScheduler(t, 2*time.Minute, logs)
+
+	advanceBy(clock, 2*time.Minute)
+	expectRefreshes(t, refreshed, 1)
+	if strings.Contains(logs.String(), "resume detected") {
+		t.Fatalf("unexpected resume on regular heartbeats: %q", logs.String())
+	}
+}
+
+func TestSchedulerClockBack(t *testing.T) {
+	logs := &strings.Builder{}
+	clock, refreshed := newFakeScheduler(t, 30*time.Minute, logs)
+
+	clock.SetWall(clock.Now().Add(-2 * time.Hour))
+	clock.Advance(HeartbeatInterval)
+	clock.BlockUntil(3)
+	clock.Advance(ResumeGrace)
+	expectRefreshes(t, refreshed, 1)
+	if !strings.Contains(logs.String(), "wall clock jumped backwards") {
+		t.Fatalf("expected clock jump to be logged, got %q", logs.String())
+	}
+}
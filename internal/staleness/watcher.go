@@ -0,0 +1,85 @@
+// Package staleness re-rates the age of the shown data on a timer. A stopped
+// scheduler or a sleeping machine publishes no events of its own, so without
+// this the tray would keep presenting old numbers as current.
+package staleness
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/state"
+)
+
+// CheckInterval keeps the "updated ... ago" text accurate to the minute.
+const CheckInterval = time.Minute
+
+type Watcher struct {
+	state  *state.State
+	bus    *events.Bus
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	level  state.Staleness
+	stopCh chan struct{}
+}
+
+func New(stateStore *state.State, bus *events.Bus, logger *slog.Logger) *Watcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Watcher{state: stateStore, bus: bus, logger: logger}
+}
+
+// Start checks every interval and calls onTick after each check, e.g. to
+// redraw the tooltip. It is a no-op while running.
+func (w *Watcher) Start(interval time.Duration, onTick func()) {
+	w.mu.Lock()
+	if w.stopCh != nil {
+		w.mu.Unlock()
+		return
+	}
+	stopCh := make(chan struct{})
+	w.stopCh = stopCh
+	w.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+				if onTick != nil {
+					onTick()
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.stopCh != nil {
+		close(w.stopCh)
+		w.stopCh = nil
+	}
+	w.mu.Unlock()
+}
+
+// Check publishes StalenessChanged when the level differs from the last check.
+func (w *Watcher) Check() {
+	snap := w.state.Snapshot()
+	w.mu.Lock()
+	changed := snap.Staleness != w.level
+	w.level = snap.Staleness
+	w.mu.Unlock()
+	if !changed {
+		return
+	}
+	w.logger.Info("data staleness changed", "level", snap.Staleness.String(), "last_success_at", snap.LastSuccessAt)
+	w.bus.Publish(events.StalenessChanged{At: time.Now().UTC(), Level: snap.Staleness, LastSuccessAt: snap.LastSuccessAt})
+}
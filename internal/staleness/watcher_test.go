@@ -0,0 +1,74 @@
+package staleness
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/state"
+)
+
+func drainEvents(sub *events.Subscription) []events.Event {
+	var out []events.Event
+	for {
+		select {
+		case ev := <-sub.C():
+			out = append(out, ev)
+		default:
+			return out
+		}
+	}
+}
+
+func TestCheckPublishesLevelChanges(t *testing.T) {
+	stateStore := state.New()
+	stateStore.SetPeriod(time.Minute)
+	bus := events.NewBus(nil)
+	sub := bus.Subscribe(4)
+	w := New(stateStore, bus, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	w.Check()
+	if got := drainEvents(sub); len(got) != 0 {
+		t.Fatalf("expected no event without data, got %v", got)
+	}
+
+	last := time.Now().Add(-10 * time.Minute)
+	stateStore.SetSuccess(openrouter.Usage{Total: 1}, last)
+	w.Check()
+	w.Check()
+	got := drainEvents(sub)
+	if len(got) != 1 {
+		t.Fatalf("expected one event, got %v", got)
+	}
+	changed, ok := got[0].(events.StalenessChanged)
+	if !ok || changed.Level != state.Stale || !changed.LastSuccessAt.Equal(last) {
+		t.Fatalf("unexpected event %+v", got[0])
+	}
+
+	stateStore.SetSuccess(openrouter.Usage{Total: 2}, time.Now())
+	w.Check()
+	got = drainEvents(sub)
+	if len(got) != 1 || got[0].(events.StalenessChanged).Level != state.Fresh {
+		t.Fatalf("expected return to fresh, got %v", got)
+	}
+}
+
+func TestStartTicks(t *testing.T) {
+	w := New(state.New(), events.NewBus(nil), nil)
+	ticks := make(chan struct{}, 1)
+	w.Start(time.Millisecond, func() {
+		select {
+		case ticks <- struct{}{}:
+		default:
+		}
+	})
+	defer w.Stop()
+	select {
+	case <-ticks:
+	case <-time.After(time.Second):
+		t.Fatalf("expected onTick to be called")
+	}
+}
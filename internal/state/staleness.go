@@ -0,0 +1,54 @@
+package state
+
+import "time"
+
+// Staleness tells how far the shown numbers lag behind the refresh period.
+type Staleness int
+
+const (
+	// Fresh data is from the last scheduled refresh, or there is none yet.
+	Fresh Staleness = iota
+	// Late data missed a scheduled refresh, or the clock went backwards.
+	Late
+	// Stale data missed several refreshes and may be far off.
+	Stale
+)
+
+const (
+	// clockSkew tolerates small clock adjustments before a future
+	// LastSuccessAt counts as a clock jump.
+	clockSkew = time.Minute
+	// staleRefreshes is the number of periods after which data is stale.
+	staleRefreshes = 3
+)
+
+func (s Staleness) String() string {
+	switch s {
+	case Late:
+		return "late"
+	case Stale:
+		return "stale"
+	default:
+		return "fresh"
+	}
+}
+
+// StalenessAt rates data last refreshed at lastSuccess. Ages use the wall
+// clock: the monotonic clock stops while the machine sleeps, which would hide
+// exactly the gaps this is meant to show.
+func StalenessAt(lastSuccess, now time.Time, period time.Duration) Staleness {
+	if lastSuccess.IsZero() || period <= 0 {
+		return Fresh
+	}
+	age := now.Round(0).Sub(lastSuccess.Round(0))
+	switch {
+	case age < -clockSkew:
+		return Late
+	case age > staleRefreshes*period:
+		return Stale
+	case age > period+period/2:
+		return Late
+	default:
+		return Fresh
+	}
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestStalenessAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	period := 30 * time.Minute
+	cases := []struct {
+		name string
+		last time.Time
+		want Staleness
+	}{
+		{"no data", time.Time{}, Fresh},
+		{"within period", now.Add(-20 * time.Minute), Fresh},
+		{"slightly late tick", now.Add(-40 * time.Minute), Fresh},
+		{"missed refresh", now.Add(-50 * time.Minute), Late},
+		{"missed several", now.Add(-91 * time.Minute), Stale},
+		{"small skew", now.Add(30 * time.Second), Fresh},
+		{"clock went back", now.Add(2 * time.Hour), Late},
+	}
+	for _, tc := range cases {
+		if got := StalenessAt(tc.last, now, period); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+	if got := StalenessAt(now.Add(-time.Hour), now, 0); got != Fresh {
+		t.Fatalf("expected fresh without a period, got %s", got)
+	}
+}
+
+func TestSnapshotStaleness(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.SetPeriod(time.Hour)
+	s.SetSuccess(openrouter.Usage{Total: 1}, now.Add(-4*time.Hour))
+	if got := s.Snapshot().Staleness; got != Stale {
+		t.Fatalf("expected stale, got %s", got)
+	}
+	s.SetPeriod(2 * time.Hour)
+	if got := s.Snapshot().Staleness; got != Late {
+		t.Fatalf("expected late after longer period, got %s", got)
+	}
+	s.SetSuccess(openrouter.Usage{Total: 2}, now)
+	if got := s.Snapshot().Staleness; got != Fresh {
+		t.Fatalf("expected fresh after success, got %s", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
+	"openrouter-costs-tray/internal/redact"
 )
 
 type Snapshot struct {
@@ -12,6 +14,18 @@ type Snapshot struct {
 	Usage         openrouter.Usage
 	LastError     string
 	NotConfigured bool
+	// Offline is set while the API cannot be reached; LastError stays empty.
+	Offline  bool
+	Activity []openrouter.ActivityItem
+	Keys     []openrouter.KeyInfo
+	Prices   map[string]pricing.Price
+	Projects map[string]float64
+	Credits  *openrouter.Credits
+	// Staleness rates LastSuccessAt against the refresh period at the time
+	// of the snapshot.
+	Staleness Staleness
+	// Drift holds the schema deviations per API endpoint.
+	Drift map[string][]string
 }
 
 type State struct {
@@ -20,10 +34,26 @@ type State struct {
 	usage         openrouter.Usage
 	lastError     string
 	notConfigured bool
+	offline       bool
+	activity      []openrouter.ActivityItem
+	keys          []openrouter.KeyInfo
+	prices        map[string]pricing.Price
+	projects      map[string]float64
+	credits       *openrouter.Credits
+	drift         map[string][]string
+	period        time.Duration
+	now           func() time.Time
 }
 
 func New() *State {
-	return &State{}
+	return &State{now: time.Now}
+}
+
+// SetPeriod sets the refresh period that staleness is measured against.
+func (s *State) SetPeriod(period time.Duration) {
+	s.mu.Lock()
+	s.period = period
+	s.mu.Unlock()
 }
 
 func (s *State) SetNotConfigured() {
@@ -45,14 +75,81 @@ func (s *State) SetSuccess(usage openrouter.Usage, at time.Time) {
 	s.usage = usage
 	s.lastSuccessAt = at
 	s.lastError = ""
+	s.offline = false
+	s.mu.Unlock()
+}
+
+// SetActivity replaces the per-model breakdown.
+func (s *State) SetActivity(items []openrouter.ActivityItem) {
+	s.mu.Lock()
+	s.activity = items
+	s.mu.Unlock()
+}
+
+// SetKeys replaces the per-key usage listed with a provisioning key.
+func (s *State) SetKeys(keys []openrouter.KeyInfo) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+// SetPrices replaces the prices of watched models.
+func (s *State) SetPrices(prices map[string]pricing.Price) {
+	s.mu.Lock()
+	s.prices = prices
+	s.mu.Unlock()
+}
+
+// SetCredits stores the account credit balance, nil when unavailable.
+func (s *State) SetCredits(credits *openrouter.Credits) {
+	s.mu.Lock()
+	s.credits = credits
+	s.mu.Unlock()
+}
+
+// SetProjects replaces today's (UTC) spend per proxy project.
+func (s *State) SetProjects(projects map[string]float64) {
+	s.mu.Lock()
+	s.projects = projects
+	s.mu.Unlock()
+}
+
+// SetDrift records where responses of endpoint deviate from the known
+// schema; no issues clears it.
+func (s *State) SetDrift(endpoint string, issues []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drift := make(map[string][]string, len(s.drift)+1)
+	for k, v := range s.drift {
+		drift[k] = v
+	}
+	if len(issues) > 0 {
+		drift[endpoint] = issues
+	} else {
+		delete(drift, endpoint)
+	}
+	if len(drift) == 0 {
+		drift = nil
+	}
+	s.drift = drift
+}
+
+// SetOffline marks the API as unreachable. A network failure is not shown as
+// an error.
+func (s *State) SetOffline() {
+	s.mu.Lock()
+	s.notConfigured = false
+	s.offline = true
+	s.lastError = ""
 	s.mu.Unlock()
 }
 
 func (s *State) SetError(err error) {
 	s.mu.Lock()
 	s.notConfigured = false
+	s.offline = false
 	if err != nil {
-		s.lastError = err.Error()
+		s.lastError = redact.String(err.Error())
 	}
 	s.mu.Unlock()
 }
@@ -65,5 +162,13 @@ func (s *State) Snapshot() Snapshot {
 		Usage:         s.usage,
 		LastError:     s.lastError,
 		NotConfigured: s.notConfigured,
+		Offline:       s.offline,
+		Activity:      s.activity,
+		Keys:          s.keys,
+		Prices:        s.prices,
+		Projects:      s.projects,
+		Credits:       s.credits,
+		Staleness:     StalenessAt(s.lastSuccessAt, s.now(), s.period),
+		Drift:         s.drift,
 	}
 }
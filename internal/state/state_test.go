@@ -60,3 +60,46 @@ func TestStateTransitions(t *testing.T) {
 		t.Fatalf("expected last success time set")
 	}
 }
+
+func TestSetErrorRedactsKeys(t *testing.T) {
+	s := New()
+	s.SetError(errors.New("unexpected status 400: Bearer sk-or-v1-abc123 rejected"))
+	if got := s.Snapshot().LastError; got != "unexpected status 400: Bearer [REDACTED] rejected" {
+		t.Fatalf("unexpected stored error %q", got)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	s := New()
+	s.SetError(errors.New("boom"))
+	s.SetOffline()
+	snap := s.Snapshot()
+	if !snap.Offline || snap.LastError != "" {
+		t.Fatalf("expected offline without error, got %+v", snap)
+	}
+	s.SetError(errors.New("unexpected status 500"))
+	if snap := s.Snapshot(); snap.Offline {
+		t.Fatalf("expected API error to end offline state")
+	}
+	s.SetOffline()
+	s.SetSuccess(openrouter.Usage{Total: 1}, time.Now())
+	if snap := s.Snapshot(); snap.Offline {
+		t.Fatalf("expected success to end offline state")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	s := New()
+	s.SetDrift("/auth/key", []string{"$.data.usage: missing"})
+	snap := s.Snapshot()
+	if len(snap.Drift["/auth/key"]) != 1 {
+		t.Fatalf("expected drift, got %v", snap.Drift)
+	}
+	s.SetDrift("/auth/key", nil)
+	if s.Snapshot().Drift != nil {
+		t.Fatalf("expected drift to clear")
+	}
+	if len(snap.Drift) != 1 {
+		t.Fatalf("expected earlier snapshot to stay unchanged")
+	}
+}
@@ -0,0 +1,29 @@
+package summary
+
+import (
+	"strconv"
+
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/util"
+)
+
+// Detail is one labelled value of a lookup result.
+type Detail struct {
+	Label string
+	Value string
+}
+
+// GenerationDetails lists the generation stats shown by the CLI and the lookup window.
+func GenerationDetails(gen openrouter.Generation) []Detail {
+	return []Detail{
+		{"ID", gen.ID},
+		{"Model", gen.Model},
+		{"Provider", gen.Provider},
+		{"Created", util.FormatTime(gen.CreatedAt)},
+		{"Tokens (prompt/completion)", strconv.Itoa(gen.TokensPrompt) + " / " + strconv.Itoa(gen.TokensCompletion)},
+		{"Native tokens (prompt/completion/reasoning)", strconv.Itoa(gen.NativeTokensPrompt) + " / " + strconv.Itoa(gen.NativeTokensCompletion) + " / " + strconv.Itoa(gen.NativeTokensReasoning)},
+		{"Latency", gen.Latency.String()},
+		{"Generation time", gen.GenerationTime.String()},
+		{"Total cost", util.FormatUSD(gen.TotalCost)},
+	}
+}
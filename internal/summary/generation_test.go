@@ -0,0 +1,36 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestGenerationDetails(t *testing.T) {
+	details := GenerationDetails(openrouter.Generation{
+		ID:                    "gen-1",
+		Model:                 "openai/gpt-4o",
+		TokensPrompt:          10,
+		TokensCompletion:      20,
+		NativeTokensReasoning: 5,
+		Latency:               1500 * time.Millisecond,
+		TotalCost:             0.8,
+	})
+	values := map[string]string{}
+	for _, d := range details {
+		values[d.Label] = d.Value
+	}
+	if values["Model"] != "openai/gpt-4o" {
+		t.Fatalf("unexpected model: %q", values["Model"])
+	}
+	if values["Tokens (prompt/completion)"] != "10 / 20" {
+		t.Fatalf("unexpected tokens: %q", values["Tokens (prompt/completion)"])
+	}
+	if values["Native tokens (prompt/completion/reasoning)"] != "0 / 0 / 5" {
+		t.Fatalf("unexpected native tokens: %q", values["Native tokens (prompt/completion/reasoning)"])
+	}
+	if values["Latency"] != "1.5s" || values["Total cost"] != "$0.8000" {
+		t.Fatalf("unexpected latency or cost: %q %q", values["Latency"], values["Total cost"])
+	}
+}
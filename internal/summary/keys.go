@@ -0,0 +1,40 @@
+package summary
+
+import (
+	"sort"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+// KeySpend returns the figure used to rank and show keys: the lifetime total,
+// which every key reports and which adds up to the account total.
+func KeySpend(key openrouter.KeyInfo) float64 {
+	return key.Usage.Total
+}
+
+// TopKeys returns up to limit keys ordered by KeySpend.
+func TopKeys(keys []openrouter.KeyInfo, limit int) []openrouter.KeyInfo {
+	if limit <= 0 {
+		return nil
+	}
+	out := append([]openrouter.KeyInfo(nil), keys...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return KeySpend(out[i]) > KeySpend(out[j])
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// KeyName returns a human-readable name for a key.
+func KeyName(key openrouter.KeyInfo) string {
+	switch {
+	case key.Name != "":
+		return key.Name
+	case key.Label != "":
+		return key.Label
+	default:
+		return key.Hash
+	}
+}
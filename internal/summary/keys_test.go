@@ -0,0 +1,27 @@
+package summary
+
+import (
+	"testing"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestTopKeys(t *testing.T) {
+	monthly := 4.0
+	keys := []openrouter.KeyInfo{
+		{Name: "a", Usage: openrouter.Usage{Total: 3}},
+		{Name: "b", Usage: openrouter.Usage{Total: 10, Monthly: &monthly}},
+		{Label: "sk-or-v1-c", Usage: openrouter.Usage{Total: 5}},
+	}
+
+	top := TopKeys(keys, 2)
+	if len(top) != 2 || KeyName(top[0]) != "b" || KeyName(top[1]) != "sk-or-v1-c" {
+		t.Fatalf("unexpected top keys: %+v", top)
+	}
+	if len(keys) != 3 || keys[0].Name != "a" {
+		t.Fatalf("expected input to be left untouched")
+	}
+	if got := TopKeys(keys, 0); got != nil {
+		t.Fatalf("expected nil for zero limit")
+	}
+}
@@ -0,0 +1,47 @@
+package summary
+
+import (
+	"sort"
+	"time"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+// ModelCost is the aggregated spend of one model over a period.
+type ModelCost struct {
+	Model string
+	Usage float64
+}
+
+// TopModels sums activity over the last days completed UTC days before now
+// and returns up to limit models ordered by spend. The activity endpoint only
+// reports completed days, so the current day is never included.
+func TopModels(items []openrouter.ActivityItem, now time.Time, days, limit int) []ModelCost {
+	if days <= 0 || limit <= 0 {
+		return nil
+	}
+	today := now.UTC()
+	to := today.AddDate(0, 0, -1).Format(time.DateOnly)
+	from := today.AddDate(0, 0, -days).Format(time.DateOnly)
+	totals := map[string]float64{}
+	for _, item := range items {
+		if item.Date < from || item.Date > to || item.Usage <= 0 {
+			continue
+		}
+		totals[item.Model] += item.Usage
+	}
+	out := make([]ModelCost, 0, len(totals))
+	for model, usage := range totals {
+		out = append(out, ModelCost{Model: model, Usage: usage})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Usage != out[j].Usage {
+			return out[i].Usage > out[j].Usage
+		}
+		return out[i].Model < out[j].Model
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
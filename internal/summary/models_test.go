@@ -0,0 +1,34 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestTopModels(t *testing.T) {
+	now := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	items := []openrouter.ActivityItem{
+		{Date: "2025-02-10", Model: "e", Usage: 7},
+		{Date: "2025-02-09", Model: "a", Usage: 1},
+		{Date: "2025-02-09", Model: "b", Usage: 2},
+		{Date: "2025-02-09", Model: "a", Usage: 0.5},
+		{Date: "2025-02-05", Model: "c", Usage: 5},
+		{Date: "2025-02-02", Model: "d", Usage: 9},
+	}
+
+	yesterday := TopModels(items, now, 1, 5)
+	if len(yesterday) != 2 || yesterday[0].Model != "b" || yesterday[1].Model != "a" || yesterday[1].Usage != 1.5 {
+		t.Fatalf("unexpected top for the last completed day: %+v", yesterday)
+	}
+
+	week := TopModels(items, now, 7, 2)
+	if len(week) != 2 || week[0].Model != "c" || week[1].Model != "b" {
+		t.Fatalf("unexpected week top: %+v", week)
+	}
+
+	if got := TopModels(items, now, 0, 5); got != nil {
+		t.Fatalf("expected nil for zero days, got %+v", got)
+	}
+}
@@ -0,0 +1,24 @@
+package summary
+
+import "sort"
+
+// ProjectCost is the spend attributed to one proxy project.
+type ProjectCost struct {
+	Project string
+	Spend   float64
+}
+
+// RankProjects returns the project totals ordered by spend, then by name.
+func RankProjects(totals map[string]float64) []ProjectCost {
+	out := make([]ProjectCost, 0, len(totals))
+	for project, spend := range totals {
+		out = append(out, ProjectCost{Project: project, Spend: spend})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Spend != out[j].Spend {
+			return out[i].Spend > out[j].Spend
+		}
+		return out[i].Project < out[j].Project
+	})
+	return out
+}
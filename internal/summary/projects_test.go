@@ -0,0 +1,19 @@
+package summary
+
+import "testing"
+
+func TestRankProjects(t *testing.T) {
+	got := RankProjects(map[string]float64{"b": 1, "a": 1, "c": 3})
+	want := []string{"c", "a", "b"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected projects: %+v", got)
+	}
+	for i, project := range want {
+		if got[i].Project != project {
+			t.Fatalf("expected %q at %d, got %+v", project, i, got)
+		}
+	}
+	if got := RankProjects(nil); len(got) != 0 {
+		t.Fatalf("expected no projects, got %+v", got)
+	}
+}
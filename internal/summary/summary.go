@@ -1,7 +1,9 @@
 package summary
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"openrouter-costs-tray/internal/config"
 	"openrouter-costs-tray/internal/state"
@@ -9,7 +11,11 @@ import (
 )
 
 func Tooltip(cfg config.Config, snap state.Snapshot) string {
-	if cfg.Connection.Token == "" || snap.NotConfigured {
+	return tooltipAt(cfg, snap, time.Now())
+}
+
+func tooltipAt(cfg config.Config, snap state.Snapshot, now time.Time) string {
+	if !cfg.Connection.Configured() || snap.NotConfigured {
 		return "Set token in Settings"
 	}
 	lines := []string{
@@ -17,14 +23,40 @@ func Tooltip(cfg config.Config, snap state.Snapshot) string {
 		"Weekly: " + formatUsage(snap.Usage.Weekly),
 		"Monthly: " + formatUsage(snap.Usage.Monthly),
 		"Total: " + util.FormatUSD(snap.Usage.Total),
-		"Updated: " + util.FormatTime(snap.LastSuccessAt),
+		"Updated: " + updated(snap, now),
+	}
+	if len(snap.Keys) > 0 {
+		top := TopKeys(snap.Keys, 1)[0]
+		lines = append(lines, fmt.Sprintf("Keys: %d, top (total): %s %s", len(snap.Keys), KeyName(top), util.FormatUSD(KeySpend(top))))
+	}
+	if cfg.Updates.FetchActivity {
+		if top := TopModels(snap.Activity, now, 7, 1); len(top) > 0 {
+			lines = append(lines, "Top model (7 days): "+top[0].Model+" "+util.FormatUSD(top[0].Usage))
+		}
+	}
+	if snap.Offline {
+		lines = append(lines, "Offline, waiting for network")
+	} else if snap.LastError != "" {
+		lines = append(lines, "ERROR: "+snap.LastError)
 	}
-	if snap.LastError != "" {
-		lines = append(lines, "ERROR: "+snap.LastError+" (stale)")
+	if len(snap.Drift) > 0 {
+		lines = append(lines, "WARNING: API response changed, numbers may be wrong")
 	}
 	return strings.Join(lines, "\n")
 }
 
+// updated renders the data age, marked when refreshes were missed.
+func updated(snap state.Snapshot, now time.Time) string {
+	if snap.LastSuccessAt.IsZero() {
+		return "never"
+	}
+	text := util.FormatAge(now.Round(0).Sub(snap.LastSuccessAt.Round(0)))
+	if snap.Staleness != state.Fresh {
+		text += " (" + snap.Staleness.String() + ")"
+	}
+	return text
+}
+
 func formatUsage(value *float64) string {
 	if value == nil {
 		return "N/A"
@@ -41,6 +41,7 @@ func TestTooltipContent(t *testing.T) {
 			Monthly: &monthly,
 		},
 		LastError: "boom",
+		Staleness: state.Stale,
 	}
 
 	lines := []string{
@@ -48,18 +49,87 @@ func TestTooltipContent(t *testing.T) {
 		"Weekly: " + util.FormatUSD(weekly),
 		"Monthly: " + util.FormatUSD(monthly),
 		"Total: " + util.FormatUSD(10.5),
-		"Updated: " + util.FormatTime(when),
-		"ERROR: boom (stale)",
+		"Updated: 3h ago (stale)",
+		"ERROR: boom",
 	}
 	want := strings.Join(lines, "\n")
 
-	if got := Tooltip(cfg, snap); got != want {
+	if got := tooltipAt(cfg, snap, when.Add(3*time.Hour+10*time.Minute)); got != want {
 		t.Fatalf("expected %q, got %q", want, got)
 	}
 }
 
+func TestTooltipTopModel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	now := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	snap := state.Snapshot{Activity: []openrouter.ActivityItem{{Date: "2025-02-09", Model: "a", Usage: 2}}}
+	line := "Top model (7 days): a " + util.FormatUSD(2)
+
+	cfg.Updates.FetchActivity = false
+	if got := tooltipAt(cfg, snap, now); strings.Contains(got, "Top model") {
+		t.Fatalf("expected no top model with activity fetching off, got %q", got)
+	}
+	cfg.Updates.FetchActivity = true
+	if got := tooltipAt(cfg, snap, now); !strings.Contains(got, line) {
+		t.Fatalf("expected %q in %q", line, got)
+	}
+}
+
+func TestTooltipUpdatedAge(t *testing.T) {
+	now := time.Date(2025, 2, 3, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		snap state.Snapshot
+		want string
+	}{
+		{state.Snapshot{}, "never"},
+		{state.Snapshot{LastSuccessAt: now.Add(-5 * time.Minute)}, "5m ago"},
+		{state.Snapshot{LastSuccessAt: now.Add(-50 * time.Minute), Staleness: state.Late}, "50m ago (late)"},
+	} {
+		if got := updated(tc.snap, now); got != tc.want {
+			t.Fatalf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
+
+func TestTooltipOffline(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	got := Tooltip(cfg, state.Snapshot{Offline: true})
+	if !strings.HasSuffix(got, "\nOffline, waiting for network") || strings.Contains(got, "ERROR") {
+		t.Fatalf("expected offline line, got %q", got)
+	}
+}
+
+func TestTooltipDrift(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Connection.Token = "token"
+	got := Tooltip(cfg, state.Snapshot{Drift: map[string][]string{"/auth/key": {"$.data: missing"}}})
+	if !strings.HasSuffix(got, "\nWARNING: API response changed, numbers may be wrong") {
+		t.Fatalf("expected drift warning, got %q", got)
+	}
+}
+
 func TestFormatUsageNil(t *testing.T) {
 	if got := formatUsage(nil); got != "N/A" {
 		t.Fatalf("expected N/A, got %q", got)
 	}
 }
+
+func TestTooltipProvisioningKeys(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Connection.ProvisioningKey = "prov"
+	keys := []openrouter.KeyInfo{
+		{Name: "ci", Usage: openrouter.Usage{Total: 2}},
+		{Name: "dev", Usage: openrouter.Usage{Total: 1}},
+	}
+	snap := state.Snapshot{Usage: openrouter.AccountUsage(keys), Keys: keys}
+
+	got := Tooltip(cfg, snap)
+	if !strings.Contains(got, "Total: "+util.FormatUSD(3)) {
+		t.Fatalf("expected account total, got %q", got)
+	}
+	if !strings.Contains(got, "Keys: 2, top (total): ci "+util.FormatUSD(2)) {
+		t.Fatalf("expected keys line, got %q", got)
+	}
+}
@@ -0,0 +1,268 @@
+// Package telemetry exports refresh traces and spend metrics to an
+// OpenTelemetry collector over OTLP/HTTP with JSON encoding.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/export"
+	"openrouter-costs-tray/internal/redact"
+	"openrouter-costs-tray/internal/trace"
+)
+
+const (
+	scopeName = "openrouter-costs-tray"
+	// maxQueuedSpans bounds memory while the collector is unreachable.
+	maxQueuedSpans = 512
+	postTimeout    = 10 * time.Second
+)
+
+// Exporter is a trace.Recorder that sends spans once their trace's root span
+// ends, and pushes metrics after every refresh.
+type Exporter struct {
+	http     *http.Client
+	redactor *redact.Redactor
+	logger   *slog.Logger
+	host     string
+	start    time.Time
+
+	mu       sync.Mutex
+	cfg      config.TelemetryConfig
+	spans    []trace.SpanData
+	keyLabel string
+	last     *export.Sample
+	success  int64
+	failure  int64
+
+	wg sync.WaitGroup
+}
+
+// NewExporter masks span messages and attributes with redactor, which should
+// hold the configured secrets; nil masks only key-like patterns.
+func NewExporter(cfg config.TelemetryConfig, httpClient *http.Client, redactor *redact.Redactor, logger *slog.Logger) *Exporter {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: postTimeout}
+	}
+	if redactor == nil {
+		redactor = redact.New()
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	host, _ := os.Hostname()
+	return &Exporter{http: httpClient, redactor: redactor, logger: logger, host: host, start: time.Now(), cfg: cfg}
+}
+
+func (e *Exporter) UpdateConfig(cfg config.TelemetryConfig) {
+	e.mu.Lock()
+	e.cfg = cfg
+	if !cfg.Enabled {
+		e.spans = nil
+	}
+	e.mu.Unlock()
+}
+
+// Enabled reports whether spans should be recorded at all.
+func (e *Exporter) Enabled() bool {
+	if e == nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg.Enabled
+}
+
+// RecordSpan queues s and flushes the queue in the background when s is a
+// root span.
+func (e *Exporter) RecordSpan(s trace.SpanData) {
+	e.mu.Lock()
+	if !e.cfg.Enabled {
+		e.mu.Unlock()
+		return
+	}
+	if len(e.spans) >= maxQueuedSpans {
+		e.spans = e.spans[1:]
+	}
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+	if s.ParentID.IsZero() {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.flushTraces()
+		}()
+	}
+}
+
+// HandleEvent updates the metrics on refresh results and applies config
+// changes.
+func (e *Exporter) HandleEvent(ev events.Event) {
+	switch ev := ev.(type) {
+	case events.RefreshSucceeded:
+		sample := export.SampleFromEvent(ev)
+		e.mu.Lock()
+		e.success++
+		e.last = &sample
+		e.keyLabel = ev.Usage.Label
+		e.mu.Unlock()
+		e.exportMetrics()
+	case events.RefreshFailed:
+		e.mu.Lock()
+		e.failure++
+		e.mu.Unlock()
+		e.exportMetrics()
+	case events.ConfigChanged:
+		e.UpdateConfig(ev.Config.Telemetry)
+	}
+}
+
+// Close waits for background exports and sends any spans still queued.
+func (e *Exporter) Close() {
+	e.wg.Wait()
+	e.flushTraces()
+}
+
+func (e *Exporter) flushTraces() {
+	e.mu.Lock()
+	cfg := e.cfg
+	queued := e.spans
+	e.spans = nil
+	res := e.resourceLocked()
+	e.mu.Unlock()
+	if !cfg.Enabled || len(queued) == 0 {
+		return
+	}
+	spans := make([]span, 0, len(queued))
+	for _, s := range queued {
+		spans = append(spans, toSpan(e.redactSpan(s)))
+	}
+	payload := tracesRequest{ResourceSpans: []resourceSpans{{
+		Resource:   res,
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}, Spans: spans}},
+	}}}
+	if err := e.post(cfg, "/v1/traces", payload); err != nil {
+		e.logger.Warn("otlp trace export failed", "error", err, "spans", len(spans))
+		return
+	}
+	e.logger.Debug("otlp traces exported", "spans", len(spans))
+}
+
+func (e *Exporter) exportMetrics() {
+	now := time.Now()
+	e.mu.Lock()
+	cfg := e.cfg
+	res := e.resourceLocked()
+	metrics := e.metricsLocked(now)
+	e.mu.Unlock()
+	if !cfg.Enabled {
+		return
+	}
+	payload := metricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource:     res,
+		ScopeMetrics: []scopeMetrics{{Scope: scope{Name: scopeName}, Metrics: metrics}},
+	}}}
+	if err := e.post(cfg, "/v1/metrics", payload); err != nil {
+		e.logger.Warn("otlp metric export failed", "error", err)
+		return
+	}
+	e.logger.Debug("otlp metrics exported", "metrics", len(metrics))
+}
+
+// redactSpan masks secrets that errors or attributes picked up, e.g. an API
+// error echoing the key.
+func (e *Exporter) redactSpan(s trace.SpanData) trace.SpanData {
+	s.StatusMessage = e.redactor.String(s.StatusMessage)
+	for i, attr := range s.Attrs {
+		if v, ok := attr.Value.(string); ok {
+			s.Attrs[i].Value = e.redactor.String(v)
+		}
+	}
+	return s
+}
+
+func (e *Exporter) resourceLocked() resource {
+	attrs := map[string]string{"service.name": e.cfg.ServiceName}
+	if e.host != "" {
+		attrs["host.name"] = e.host
+	}
+	if e.keyLabel != "" {
+		attrs["openrouter.key.label"] = e.keyLabel
+	}
+	return resource{Attributes: stringAttributes(attrs)}
+}
+
+// metricsLocked lists the spend gauges of the last successful refresh and
+// the refresh counters since start.
+func (e *Exporter) metricsLocked(now time.Time) []metric {
+	var out []metric
+	if s := e.last; s != nil {
+		out = append(out, gaugeMetric("openrouter.usage.total", "Total key usage", s.Total, s.At))
+		for _, opt := range []struct {
+			name, description string
+			value             *float64
+		}{
+			{"openrouter.usage.daily", "Key usage today (UTC)", s.Daily},
+			{"openrouter.usage.weekly", "Key usage this week (UTC)", s.Weekly},
+			{"openrouter.usage.monthly", "Key usage this month (UTC)", s.Monthly},
+			{"openrouter.limit.remaining", "Remaining key limit", s.LimitRemaining},
+			{"openrouter.credits.remaining", "Remaining account credit", s.RemainingCredit},
+		} {
+			if opt.value != nil {
+				out = append(out, gaugeMetric(opt.name, opt.description, *opt.value, s.At))
+			}
+		}
+	}
+	out = append(out, metric{
+		Name:        "openrouter.refresh.count",
+		Description: "Refreshes by result",
+		Unit:        "1",
+		Sum: &sum{
+			DataPoints: []dataPoint{
+				counterPoint(e.start, now, e.success, trace.String("result", "success")),
+				counterPoint(e.start, now, e.failure, trace.String("result", "failure")),
+			},
+			AggregationTemporality: temporalityCumulative,
+			IsMonotonic:            true,
+		},
+	})
+	return out
+}
+
+func (e *Exporter) post(cfg config.TelemetryConfig, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,187 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/events"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/redact"
+	"openrouter-costs-tray/internal/trace"
+)
+
+// receiver stands in for an OTLP collector and keeps every request body.
+type receiver struct {
+	*httptest.Server
+	mu      sync.Mutex
+	traces  []tracesRequest
+	metrics []metricsRequest
+	headers []http.Header
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.headers = append(r.headers, req.Header.Clone())
+		var err error
+		switch req.URL.Path {
+		case "/v1/traces":
+			var body tracesRequest
+			err = json.NewDecoder(req.Body).Decode(&body)
+			r.traces = append(r.traces, body)
+		case "/v1/metrics":
+			var body metricsRequest
+			err = json.NewDecoder(req.Body).Decode(&body)
+			r.metrics = append(r.metrics, body)
+		default:
+			http.NotFound(w, req)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func testExporter(endpoint string) *Exporter {
+	cfg := config.DefaultConfig().Telemetry
+	cfg.Enabled = true
+	cfg.Endpoint = endpoint
+	cfg.Headers = map[string]string{"Authorization": "Bearer collector"}
+	return NewExporter(cfg, nil, redact.New("custom-secret-token"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func attr(attrs []keyValue, key string) (anyValue, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return anyValue{}, false
+}
+
+func TestExporterSendsTraceOnRootEnd(t *testing.T) {
+	recv := newReceiver(t)
+	exporter := testExporter(recv.URL)
+
+	ctx, root := trace.Root(context.Background(), exporter, "refresh", trace.KindInternal)
+	_, child := trace.Start(ctx, "GET /auth/key", trace.KindClient)
+	child.SetAttributes(trace.Int("http.response.status_code", 502))
+	child.SetError(errors.New("bad gateway for custom-secret-token"))
+	child.End()
+	root.End()
+	exporter.Close()
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if len(recv.traces) != 1 {
+		t.Fatalf("expected one trace export, got %d", len(recv.traces))
+	}
+	if got := recv.headers[0].Get("Authorization"); got != "Bearer collector" {
+		t.Fatalf("expected configured header, got %q", got)
+	}
+	rs := recv.traces[0].ResourceSpans[0]
+	if v, ok := attr(rs.Resource.Attributes, "service.name"); !ok || *v.StringValue != "openrouter-costs-tray" {
+		t.Fatalf("missing service.name in %+v", rs.Resource.Attributes)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if len(r.TraceID) != 32 || len(r.SpanID) != 16 || c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || r.ParentSpanID != "" {
+		t.Fatalf("unexpected ids: child %+v root %+v", c, r)
+	}
+	if c.Kind != int(trace.KindClient) || c.Status.Code != int(trace.StatusError) || c.Status.Message != "bad gateway for [REDACTED]" {
+		t.Fatalf("unexpected child span %+v", c)
+	}
+	if v, ok := attr(c.Attributes, "http.response.status_code"); !ok || *v.IntValue != "502" {
+		t.Fatalf("unexpected child attributes %+v", c.Attributes)
+	}
+}
+
+func TestExporterMetrics(t *testing.T) {
+	recv := newReceiver(t)
+	exporter := testExporter(recv.URL)
+	daily := 1.5
+	at := time.Unix(1714644000, 0)
+
+	exporter.HandleEvent(events.RefreshSucceeded{
+		At:      at,
+		Usage:   openrouter.Usage{Label: "laptop", Total: 12, Daily: &daily},
+		Credits: &openrouter.Credits{Total: 20, Usage: 12},
+	})
+	exporter.HandleEvent(events.RefreshFailed{At: at, Err: errors.New("timeout")})
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if len(recv.metrics) != 2 {
+		t.Fatalf("expected two metric exports, got %d", len(recv.metrics))
+	}
+	rm := recv.metrics[1].ResourceMetrics[0]
+	if v, ok := attr(rm.Resource.Attributes, "openrouter.key.label"); !ok || *v.StringValue != "laptop" {
+		t.Fatalf("missing key label in %+v", rm.Resource.Attributes)
+	}
+	got := map[string]metric{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected total, daily, credits and refresh count, got %v", got)
+	}
+	if m := got["openrouter.usage.daily"]; m.Gauge == nil || *m.Gauge.DataPoints[0].AsDouble != 1.5 {
+		t.Fatalf("unexpected daily gauge %+v", m)
+	}
+	if m := got["openrouter.credits.remaining"]; m.Gauge == nil || *m.Gauge.DataPoints[0].AsDouble != 8 {
+		t.Fatalf("unexpected credits gauge %+v", m)
+	}
+	count := got["openrouter.refresh.count"].Sum
+	if count == nil || !count.IsMonotonic || count.AggregationTemporality != temporalityCumulative {
+		t.Fatalf("unexpected refresh counter %+v", count)
+	}
+	for _, dp := range count.DataPoints {
+		result, _ := attr(dp.Attributes, "result")
+		if *dp.AsInt != "1" {
+			t.Fatalf("expected one %s refresh, got %s", *result.StringValue, *dp.AsInt)
+		}
+	}
+}
+
+func TestExporterDisabled(t *testing.T) {
+	recv := newReceiver(t)
+	exporter := testExporter(recv.URL)
+	cfg := config.DefaultConfig()
+	cfg.Telemetry.Endpoint = recv.URL
+	exporter.HandleEvent(events.ConfigChanged{Config: cfg})
+
+	if _, span := trace.Root(context.Background(), exporter, "refresh", trace.KindInternal); span != nil {
+		t.Fatalf("expected no span while disabled")
+	}
+	exporter.HandleEvent(events.RefreshFailed{Err: errors.New("timeout")})
+	exporter.Close()
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if len(recv.traces)+len(recv.metrics) != 0 {
+		t.Fatalf("expected nothing exported while disabled")
+	}
+}
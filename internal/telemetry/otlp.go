@@ -0,0 +1,191 @@
+package telemetry
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"openrouter-costs-tray/internal/trace"
+)
+
+// OTLP/HTTP JSON payloads, limited to the fields written here. See
+// opentelemetry-proto's JSON mapping: IDs are hex, 64-bit integers strings.
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type tracesRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+	AsInt             *string    `json:"asInt,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+// temporalityCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+const temporalityCumulative = 2
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Gauge       *gauge `json:"gauge,omitempty"`
+	Sum         *sum   `json:"sum,omitempty"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func toValue(v any) anyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	default:
+		return stringValue("")
+	}
+}
+
+func toAttributes(attrs []trace.Attr) []keyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, keyValue{Key: attr.Key, Value: toValue(attr.Value)})
+	}
+	return out
+}
+
+// stringAttributes converts a map in key order, so payloads are stable.
+func stringAttributes(attrs map[string]string) []keyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make([]keyValue, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, keyValue{Key: key, Value: stringValue(attrs[key])})
+	}
+	return out
+}
+
+func toSpan(s trace.SpanData) span {
+	out := span{
+		TraceID:           s.TraceID.String(),
+		SpanID:            s.SpanID.String(),
+		Name:              s.Name,
+		Kind:              int(s.Kind),
+		StartTimeUnixNano: unixNano(s.Start),
+		EndTimeUnixNano:   unixNano(s.End),
+		Attributes:        toAttributes(s.Attrs),
+		Status:            status{Code: int(s.Status), Message: s.StatusMessage},
+	}
+	if !s.ParentID.IsZero() {
+		out.ParentSpanID = s.ParentID.String()
+	}
+	return out
+}
+
+func gaugeMetric(name, description string, value float64, at time.Time) metric {
+	return metric{
+		Name:        name,
+		Description: description,
+		Unit:        "USD",
+		Gauge:       &gauge{DataPoints: []dataPoint{{TimeUnixNano: unixNano(at), AsDouble: &value}}},
+	}
+}
+
+func counterPoint(start, at time.Time, value int64, attrs ...trace.Attr) dataPoint {
+	s := strconv.FormatInt(value, 10)
+	return dataPoint{
+		Attributes:        toAttributes(attrs),
+		StartTimeUnixNano: unixNano(start),
+		TimeUnixNano:      unixNano(at),
+		AsInt:             &s,
+	}
+}
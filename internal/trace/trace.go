@@ -0,0 +1,175 @@
+// Package trace records spans for the telemetry exporter. Spans travel in the
+// context: Root starts a trace for a Recorder, Start adds a child to whatever
+// span the context carries. Without a span every call is a no-op, so
+// instrumented code needs no telemetry checks.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"openrouter-costs-tray/internal/redact"
+)
+
+// Kind follows the OTLP span kinds that are used here.
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindClient   Kind = 3
+)
+
+// StatusCode follows the OTLP status codes.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsZero reports whether the span has no parent.
+func (id SpanID) IsZero() bool { return id == SpanID{} }
+
+// Attr is a span attribute. Value is a string, int64, float64 or bool.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr        { return Attr{Key: key, Value: value} }
+func Int(key string, value int) Attr       { return Attr{Key: key, Value: int64(value)} }
+func Float(key string, value float64) Attr { return Attr{Key: key, Value: value} }
+func Bool(key string, value bool) Attr     { return Attr{Key: key, Value: value} }
+
+// SpanData is a finished span as handed to the Recorder.
+type SpanData struct {
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentID      SpanID
+	Name          string
+	Kind          Kind
+	Start         time.Time
+	End           time.Time
+	Attrs         []Attr
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Recorder receives finished spans.
+type Recorder interface {
+	Enabled() bool
+	RecordSpan(SpanData)
+}
+
+// Span is an open span. A nil *Span ignores every call.
+type Span struct {
+	recorder Recorder
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+}
+
+type spanKey struct{}
+
+// Root starts a new trace. It returns a nil span when rec is nil or disabled.
+func Root(ctx context.Context, rec Recorder, name string, kind Kind) (context.Context, *Span) {
+	if rec == nil || !rec.Enabled() {
+		return ctx, nil
+	}
+	span := &Span{recorder: rec, data: SpanData{
+		TraceID: newTraceID(),
+		SpanID:  newSpanID(),
+		Name:    name,
+		Kind:    kind,
+		Start:   time.Now(),
+	}}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Start opens a child of the span in ctx, or returns a nil span if there is
+// none.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := &Span{recorder: parent.recorder, data: SpanData{
+		TraceID:  parent.data.TraceID,
+		SpanID:   newSpanID(),
+		ParentID: parent.data.SpanID,
+		Name:     name,
+		Kind:     kind,
+		Start:    time.Now(),
+	}}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the current span in ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed. The message is redacted; a nil err is
+// ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Status = StatusError
+	s.data.StatusMessage = redact.String(err.Error())
+	s.mu.Unlock()
+}
+
+// End finishes the span and hands it to the recorder. Later calls are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attrs = append([]Attr(nil), s.data.Attrs...)
+	s.mu.Unlock()
+	s.recorder.RecordSpan(data)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
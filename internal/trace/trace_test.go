@@ -0,0 +1,64 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type memRecorder struct {
+	enabled bool
+	mu      sync.Mutex
+	spans   []SpanData
+}
+
+func (r *memRecorder) Enabled() bool { return r.enabled }
+
+func (r *memRecorder) RecordSpan(s SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func TestRootAndChild(t *testing.T) {
+	rec := &memRecorder{enabled: true}
+	ctx, root := Root(context.Background(), rec, "refresh", KindInternal)
+	_, child := Start(ctx, "GET /auth/key", KindClient)
+	child.SetAttributes(Int("http.response.status_code", 500))
+	child.SetError(errors.New("bad key sk-or-v1-0123456789abcdef"))
+	child.End()
+	child.End()
+	root.End()
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(rec.spans))
+	}
+	c, r := rec.spans[0], rec.spans[1]
+	if c.TraceID != r.TraceID || c.ParentID != r.SpanID || !r.ParentID.IsZero() {
+		t.Fatalf("unexpected linkage: child %+v root %+v", c, r)
+	}
+	if c.Kind != KindClient || c.Status != StatusError || c.StatusMessage != "bad key [REDACTED]" {
+		t.Fatalf("unexpected child span %+v", c)
+	}
+	if len(c.Attrs) != 1 || c.Attrs[0].Value != int64(500) {
+		t.Fatalf("unexpected attrs %+v", c.Attrs)
+	}
+	if c.End.Before(c.Start) {
+		t.Fatalf("end before start")
+	}
+}
+
+func TestNoopWithoutRecorder(t *testing.T) {
+	ctx, root := Root(context.Background(), &memRecorder{}, "refresh", KindInternal)
+	if root != nil {
+		t.Fatalf("expected nil span for disabled recorder")
+	}
+	_, child := Start(ctx, "child", KindInternal)
+	if child != nil {
+		t.Fatalf("expected nil child without parent")
+	}
+	child.SetAttributes(String("k", "v"))
+	child.SetError(errors.New("x"))
+	child.End()
+}
@@ -0,0 +1,89 @@
+package transport
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"openrouter-costs-tray/internal/config"
+)
+
+func TestNewUsesProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL.
+		proxied = r.URL.String()
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer proxy.Close()
+
+	client, err := New(config.ConnectionConfig{Proxy: proxy.URL, TimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	resp, err := client.Get("http://api.example.invalid/v1/auth/key")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.invalid/v1/auth/key" {
+		t.Fatalf("expected request through proxy, got %q", proxied)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout %v", client.Timeout)
+	}
+}
+
+func TestNewTrustsCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	plain, err := New(config.ConnectionConfig{})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, err := plain.Get(srv.URL); err == nil {
+		t.Fatalf("expected unknown authority without the CA file")
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, block, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	client, err := New(config.ConnectionConfig{CAFile: path})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get with CA file: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewRejectsBadSettings(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cases := map[string]config.ConnectionConfig{
+		"proxy scheme": {Proxy: "ftp://proxy:21"},
+		"proxy host":   {Proxy: "http://"},
+		"base URL":     {BaseURL: "gateway.local/api"},
+		"missing CA":   {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"empty CA":     {CAFile: empty},
+	}
+	for name, conn := range cases {
+		if _, err := New(conn); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
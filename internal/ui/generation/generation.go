@@ -0,0 +1,111 @@
+package generation
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/summary"
+)
+
+type Deps struct {
+	Lookup func(ctx context.Context, id string) (openrouter.Generation, error)
+	Logger *slog.Logger
+}
+
+var window fyne.Window
+
+func Show(app fyne.App, deps Deps) {
+	if window != nil {
+		window.Show()
+		window.RequestFocus()
+		return
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	window = app.NewWindow("Generation lookup")
+	window.Resize(fyne.NewSize(480, 320))
+
+	idEntry := widget.NewEntry()
+	idEntry.SetPlaceHolder("Generation ID (gen-...)")
+	statusLabel := widget.NewLabel("")
+	details := container.NewGridWithColumns(2)
+
+	var lookupButton *widget.Button
+	lookupButton = widget.NewButton("Look up", func() {
+		id := strings.TrimSpace(idEntry.Text)
+		if id == "" {
+			statusLabel.SetText("Generation ID is empty")
+			return
+		}
+		statusLabel.SetText("Looking up...")
+		lookupButton.Disable()
+		go func() {
+			rows, status := lookup(deps, id)
+			if status != "" {
+				logger.Warn("generation lookup failed", "id", id, "error", status)
+			}
+			runOnMain(func() {
+				setDetails(details, rows)
+				statusLabel.SetText(status)
+				lookupButton.Enable()
+			})
+		}()
+	})
+	idEntry.OnSubmitted = func(string) {
+		lookupButton.OnTapped()
+	}
+
+	content := container.NewVBox(
+		container.NewBorder(nil, nil, nil, lookupButton, idEntry),
+		statusLabel,
+		details,
+	)
+	window.SetContent(container.NewPadded(content))
+	window.SetOnClosed(func() {
+		window = nil
+	})
+	window.Show()
+}
+
+// lookup fetches one generation and returns its details or an error status.
+func lookup(deps Deps, id string) ([]summary.Detail, string) {
+	if deps.Lookup == nil {
+		return nil, "Lookup unavailable"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	gen, err := deps.Lookup(ctx, id)
+	if err != nil {
+		return nil, "Lookup failed: " + err.Error()
+	}
+	return summary.GenerationDetails(gen), ""
+}
+
+func setDetails(grid *fyne.Container, rows []summary.Detail) {
+	objects := make([]fyne.CanvasObject, 0, len(rows)*2)
+	for _, row := range rows {
+		objects = append(objects,
+			widget.NewLabelWithStyle(row.Label, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(row.Value),
+		)
+	}
+	grid.Objects = objects
+	grid.Refresh()
+}
+
+func runOnMain(fn func()) {
+	if fn == nil {
+		return
+	}
+	fn()
+}
@@ -0,0 +1,47 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/openrouter"
+)
+
+func TestLookup(t *testing.T) {
+	deps := Deps{Lookup: func(_ context.Context, id string) (openrouter.Generation, error) {
+		if id != "gen-1" {
+			return openrouter.Generation{}, errors.New("not found")
+		}
+		return openrouter.Generation{ID: id, Model: "openai/gpt-4o", TotalCost: 0.8}, nil
+	}}
+
+	rows, status := lookup(deps, "gen-1")
+	if status != "" || len(rows) == 0 || rows[1].Value != "openai/gpt-4o" {
+		t.Fatalf("unexpected lookup result: %+v %q", rows, status)
+	}
+
+	rows, status = lookup(deps, "gen-2")
+	if rows != nil || status != "Lookup failed: not found" {
+		t.Fatalf("unexpected failure result: %+v %q", rows, status)
+	}
+}
+
+func TestSetDetails(t *testing.T) {
+	test.NewApp()
+	grid := container.NewGridWithColumns(2)
+	rows, _ := lookup(Deps{Lookup: func(context.Context, string) (openrouter.Generation, error) {
+		return openrouter.Generation{ID: "gen-1"}, nil
+	}}, "gen-1")
+	setDetails(grid, rows)
+	if len(grid.Objects) != len(rows)*2 {
+		t.Fatalf("expected label/value pairs, got %d objects", len(grid.Objects))
+	}
+	if label, ok := grid.Objects[1].(*widget.Label); !ok || label.Text != "gen-1" {
+		t.Fatalf("expected id value label")
+	}
+}
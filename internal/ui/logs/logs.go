@@ -0,0 +1,224 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/logging"
+)
+
+const (
+	// tailBytes is how much of the log file the viewer reads.
+	tailBytes      = 1 << 20
+	followInterval = 2 * time.Second
+	timeLayout     = "2006-01-02 15:04:05"
+)
+
+var (
+	levels  = []string{"debug", "info", "warn", "error"}
+	headers = []string{"Time", "Level", "Component", "Message", "Details"}
+	widths  = []float32{150, 60, 90, 220, 360}
+)
+
+type Deps struct {
+	ConfigStore *config.Store
+	LogPath     string
+	Logger      *slog.Logger
+}
+
+var window fyne.Window
+
+// filter returns the entries at or above level that contain query.
+func filter(entries []logging.Entry, level, query string) []logging.Entry {
+	var min slog.Level
+	if err := min.UnmarshalText([]byte(level)); err != nil {
+		min = slog.LevelDebug
+	}
+	out := make([]logging.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Matches(min, query) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func cell(entry logging.Entry, col int) string {
+	switch col {
+	case 0:
+		if entry.Time.IsZero() {
+			return ""
+		}
+		return entry.Time.Local().Format(timeLayout)
+	case 1:
+		return entry.Level
+	case 2:
+		return entry.Component
+	case 3:
+		return entry.Message
+	default:
+		return entry.Attrs
+	}
+}
+
+// Show opens the log viewer on the current log file.
+func Show(app fyne.App, deps Deps) {
+	if window != nil {
+		window.Show()
+		window.RequestFocus()
+		return
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	window = app.NewWindow("Logs")
+	window.Resize(fyne.NewSize(900, 520))
+
+	// mu guards the entries, which the follow loop replaces off the UI thread.
+	var (
+		mu         sync.Mutex
+		all, shown []logging.Entry
+	)
+	levelSelect := widget.NewSelect(levels, nil)
+	levelSelect.SetSelected("info")
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search")
+	statusLabel := widget.NewLabel("")
+	detail := widget.NewMultiLineEntry()
+	detail.Wrapping = fyne.TextWrapBreak
+	detail.SetMinRowsVisible(3)
+
+	table := widget.NewTableWithHeaders(func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(shown), len(headers)
+	}, func() fyne.CanvasObject {
+		label := widget.NewLabel("")
+		label.Truncation = fyne.TextTruncateEllipsis
+		return label
+	}, func(id widget.TableCellID, obj fyne.CanvasObject) {
+		mu.Lock()
+		text := ""
+		if id.Row >= 0 && id.Row < len(shown) {
+			text = cell(shown[id.Row], id.Col)
+		}
+		mu.Unlock()
+		obj.(*widget.Label).SetText(text)
+	})
+	table.ShowHeaderColumn = false
+	table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		if id.Col >= 0 && id.Col < len(headers) {
+			obj.(*widget.Label).SetText(headers[id.Col])
+		}
+	}
+	for i, w := range widths {
+		table.SetColumnWidth(i, w)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		mu.Lock()
+		text := ""
+		if id.Row >= 0 && id.Row < len(shown) {
+			text = shown[id.Row].Raw
+		}
+		mu.Unlock()
+		detail.SetText(text)
+	}
+
+	apply := func() {
+		mu.Lock()
+		shown = filter(all, levelSelect.Selected, searchEntry.Text)
+		count, total := len(shown), len(all)
+		mu.Unlock()
+		table.UnselectAll()
+		table.Refresh()
+		table.ScrollToBottom()
+		statusLabel.SetText(status(count, total, deps))
+	}
+	// load reads the file and applies it. With onlyNew it leaves the table,
+	// and so the selection, alone unless records were added.
+	load := func(onlyNew bool) {
+		entries, err := logging.ReadTail(deps.LogPath, tailBytes)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("log file read failed", "error", err, "path", deps.LogPath)
+		}
+		mu.Lock()
+		unchanged := sameTail(all, entries)
+		all = entries
+		mu.Unlock()
+		if onlyNew && unchanged {
+			return
+		}
+		apply()
+	}
+	reload := func() {
+		load(false)
+	}
+	levelSelect.OnChanged = func(string) { apply() }
+	searchEntry.OnChanged = func(string) { apply() }
+
+	stop := make(chan struct{})
+	var following atomic.Bool
+	following.Store(true)
+	follow := widget.NewCheck("Follow", func(on bool) {
+		following.Store(on)
+	})
+	follow.SetChecked(true)
+	go func() {
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if following.Load() {
+					load(true)
+				}
+			}
+		}
+	}()
+
+	toolbar := container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewLabel("Level"), levelSelect),
+		container.NewHBox(follow, widget.NewButton("Reload", reload)),
+		searchEntry,
+	)
+	bottom := container.NewVBox(detail, statusLabel)
+	window.SetContent(container.NewPadded(container.NewBorder(toolbar, bottom, nil, nil, table)))
+	window.SetOnClosed(func() {
+		close(stop)
+		window = nil
+	})
+	reload()
+	window.Show()
+}
+
+func status(shown, total int, deps Deps) string {
+	if deps.ConfigStore != nil && !deps.ConfigStore.Get().Logging.ToFile {
+		return "Logging to file is off; enable \"Log to file\" in Settings."
+	}
+	if total == 0 {
+		return "No log records yet in " + deps.LogPath
+	}
+	return fmt.Sprintf("Showing %d of %d records from %s", shown, total, deps.LogPath)
+}
+
+// sameTail reports whether two reads of the log tail hold the same records.
+func sameTail(a, b []logging.Entry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || a[len(a)-1].Raw == b[len(b)-1].Raw && a[0].Raw == b[0].Raw
+}
@@ -0,0 +1,73 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+
+	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/logging"
+)
+
+func TestFilterAndCells(t *testing.T) {
+	entries := []logging.Entry{
+		logging.ParseEntry(`{"time":"2024-05-01T10:00:00Z","level":"DEBUG","msg":"tick","component":"scheduler"}`),
+		logging.ParseEntry(`{"time":"2024-05-01T10:00:01Z","level":"WARN","msg":"refresh failed","component":"refresher","error":"timeout"}`),
+		logging.ParseEntry(`{"time":"2024-05-01T10:00:02Z","level":"ERROR","msg":"save failed","component":"settings"}`),
+	}
+	if got := filter(entries, "debug", ""); len(got) != 3 {
+		t.Fatalf("expected all entries at debug, got %d", len(got))
+	}
+	if got := filter(entries, "warn", "REFRESH"); len(got) != 1 || got[0].Message != "refresh failed" {
+		t.Fatalf("unexpected filtered entries %+v", got)
+	}
+	warn := entries[1]
+	if cell(warn, 0) != warn.Time.Local().Format(timeLayout) || cell(warn, 1) != "WARN" || cell(warn, 2) != "refresher" || cell(warn, 3) != "refresh failed" || cell(warn, 4) != "error=timeout" {
+		t.Fatalf("unexpected cells for %+v", warn)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	cfg := config.DefaultConfig()
+	store := config.NewStore("unused", cfg)
+	if got := status(0, 0, Deps{ConfigStore: store, LogPath: "app.log"}); !strings.Contains(got, "Log to file") {
+		t.Fatalf("expected hint to enable file logging, got %q", got)
+	}
+	cfg.Logging.ToFile = true
+	store.Set(cfg)
+	if got := status(2, 5, Deps{ConfigStore: store, LogPath: "app.log"}); got != "Showing 2 of 5 records from app.log" {
+		t.Fatalf("unexpected status %q", got)
+	}
+}
+
+func TestShowReadsLogFile(t *testing.T) {
+	app := test.NewApp()
+	window = nil
+	path := filepath.Join(t.TempDir(), "app.log")
+	line := `{"time":"` + time.Now().Format(time.RFC3339) + `","level":"INFO","msg":"hello"}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	Show(app, Deps{LogPath: path})
+	if window == nil {
+		t.Fatalf("expected log window")
+	}
+	window.Close()
+	if window != nil {
+		t.Fatalf("expected window reset on close")
+	}
+}
+
+func TestSameTail(t *testing.T) {
+	a := []logging.Entry{{Raw: "one"}, {Raw: "two"}}
+	if !sameTail(nil, nil) || !sameTail(a, []logging.Entry{{Raw: "one"}, {Raw: "two"}}) {
+		t.Fatalf("expected equal reads to match")
+	}
+	if sameTail(a, append(a[:2:2], logging.Entry{Raw: "three"})) || sameTail(a, []logging.Entry{{Raw: "two"}, {Raw: "three"}}) {
+		t.Fatalf("expected new records to be noticed")
+	}
+}
@@ -2,7 +2,12 @@ package settings
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,23 +17,26 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	"openrouter-costs-tray/internal/autostart"
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/dashboard"
+	"openrouter-costs-tray/internal/events"
 	"openrouter-costs-tray/internal/logging"
-	"openrouter-costs-tray/internal/notify"
+	"openrouter-costs-tray/internal/openrouter"
 	"openrouter-costs-tray/internal/refresh"
 	"openrouter-costs-tray/internal/scheduler"
+	"openrouter-costs-tray/internal/transport"
 )
 
 type Deps struct {
-	ConfigStore     *config.Store
-	Refresher       *refresh.Refresher
-	Scheduler       *scheduler.Scheduler
-	Notifier        *notify.Notifier
-	LevelVar        *slog.LevelVar
-	LogOutput       *logging.Output
-	LogPath         string
-	Logger          *slog.Logger
-	OnConfigApplied func(config.Config)
+	ConfigStore *config.Store
+	Autostart   autostart.Manager
+	Refresher   *refresh.Refresher
+	Scheduler   *scheduler.Scheduler
+	Bus         *events.Bus
+	LogOutput   *logging.Output
+	LogPath     string
+	Logger      *slog.Logger
 }
 
 var window fyne.Window
@@ -45,7 +53,7 @@ func Show(app fyne.App, deps Deps) {
 	}
 
 	window = app.NewWindow("Settings")
-	window.Resize(fyne.NewSize(420, 420))
+	window.Resize(fyne.NewSize(460, 640))
 
 	cfg := deps.ConfigStore.Get()
 
@@ -79,10 +87,98 @@ func Show(app fyne.App, deps Deps) {
 
 	connectionRow := container.NewBorder(nil, nil, nil, testButton, tokenEntry)
 
+	provisioningEntry := widget.NewPasswordEntry()
+	provisioningEntry.SetPlaceHolder("Provisioning key (optional, lists all keys)")
+	provisioningEntry.SetText(cfg.Connection.ProvisioningKey)
+	testProvisioningButton := widget.NewButton("Test", func() {
+		key := strings.TrimSpace(provisioningEntry.Text)
+		if key == "" {
+			dialog.ShowInformation("Test", "Provisioning key is empty", window)
+			return
+		}
+		statusLabel.SetText("Testing...")
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			keys, err := deps.Refresher.TestProvisioningKey(ctx, key)
+			cancel()
+			result := fmt.Sprintf("Test OK (%d keys)", len(keys))
+			if err != nil {
+				result = "Test failed: " + err.Error()
+			}
+			runOnMain(func() {
+				statusLabel.SetText(result)
+			})
+		}()
+	})
+	provisioningRow := container.NewBorder(nil, nil, nil, testProvisioningButton, provisioningEntry)
+
+	baseURLEntry := widget.NewEntry()
+	baseURLEntry.SetPlaceHolder(openrouter.DefaultBaseURL)
+	baseURLEntry.SetText(cfg.Connection.BaseURL)
+	httpProxyEntry := widget.NewEntry()
+	httpProxyEntry.SetPlaceHolder("http://proxy:3128 or socks5://proxy:1080")
+	httpProxyEntry.SetText(cfg.Connection.Proxy)
+	caFileEntry := widget.NewEntry()
+	caFileEntry.SetPlaceHolder("PEM file with extra CA certificates")
+	caFileEntry.SetText(cfg.Connection.CAFile)
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText(strconv.Itoa(cfg.Connection.TimeoutSeconds))
+	connectTimeoutEntry := widget.NewEntry()
+	connectTimeoutEntry.SetText(strconv.Itoa(cfg.Connection.ConnectTimeoutSeconds))
+	readConnection := func(conn config.ConnectionConfig) (config.ConnectionConfig, *http.Client, error) {
+		conn, err := connectionFromForm(conn, baseURLEntry.Text, httpProxyEntry.Text, caFileEntry.Text,
+			timeoutEntry.Text, connectTimeoutEntry.Text)
+		if err != nil {
+			return conn, nil, err
+		}
+		httpClient, err := transport.New(conn)
+		return conn, httpClient, err
+	}
+	testConnectionButton := widget.NewButton("Test connection", func() {
+		conn, httpClient, err := readConnection(deps.ConfigStore.Get().Connection)
+		if err != nil {
+			statusLabel.SetText("Connection settings invalid: " + err.Error())
+			return
+		}
+		statusLabel.SetText("Testing connection...")
+		go func() {
+			client := openrouter.NewClient(conn.BaseURL, httpClient, settingsLogger)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			start := time.Now()
+			status, err := client.Ping(ctx)
+			cancel()
+			result := fmt.Sprintf("Connection OK: HTTP %d from %s in %s",
+				status, client.BaseURL(), time.Since(start).Round(time.Millisecond))
+			if err != nil {
+				result = "Connection failed: " + err.Error()
+				if kind := openrouter.NetworkKind(err); kind != "" {
+					result = fmt.Sprintf("Connection failed (%s): %v", kind, err)
+				}
+			}
+			runOnMain(func() {
+				statusLabel.SetText(result)
+			})
+		}()
+	})
+
 	periodSelect := widget.NewSelect(config.PeriodOptions, nil)
 	periodSelect.SetSelected(cfg.Updates.Period)
+	// Start at login lives in the OS, not in the config file.
+	startAtLogin := widget.NewCheck("Start at login", nil)
+	autostartEnabled := false
+	if deps.Autostart == nil {
+		startAtLogin.Disable()
+	} else if enabled, err := deps.Autostart.Enabled(); err != nil {
+		settingsLogger.Warn("autostart state unavailable", "error", err)
+		startAtLogin.Disable()
+	} else {
+		autostartEnabled = enabled
+		startAtLogin.SetChecked(enabled)
+	}
 	updateOnStart := widget.NewCheck("Update on start", nil)
 	updateOnStart.SetChecked(cfg.Updates.UpdateOnStart)
+	fetchActivity := widget.NewCheck("Fetch per-model activity", nil)
+	fetchActivity.SetChecked(cfg.Updates.FetchActivity)
 
 	notifyEnabled := widget.NewCheck("Enable notifications", nil)
 	notifyEnabled.SetChecked(cfg.Notifications.Enabled)
@@ -92,6 +188,14 @@ func Show(app fyne.App, deps Deps) {
 	notifyError.SetChecked(cfg.Notifications.OnError)
 	notifyStartSummary := widget.NewCheck("On start: spends summary", nil)
 	notifyStartSummary.SetChecked(cfg.Notifications.OnStartSummary)
+	notifyKeyChange := widget.NewCheck("On key added/removed/disabled", nil)
+	notifyKeyChange.SetChecked(cfg.Notifications.OnKeyChange)
+	notifyPriceChange := widget.NewCheck("On watched model price change", nil)
+	notifyPriceChange.SetChecked(cfg.Notifications.OnPriceChange)
+	notifyStale := widget.NewCheck("On stale data (refreshes missed)", nil)
+	notifyStale.SetChecked(cfg.Notifications.OnStale)
+	notifySchemaDrift := widget.NewCheck("On API response changes (once per version)", nil)
+	notifySchemaDrift.SetChecked(cfg.Notifications.OnSchemaDrift)
 	testNotifyButton := widget.NewButton("Test notification", func() {
 		app.SendNotification(&fyne.Notification{
 			Title:   "OpenRouter Costs",
@@ -104,10 +208,18 @@ func Show(app fyne.App, deps Deps) {
 			notifyUpdate.Enable()
 			notifyError.Enable()
 			notifyStartSummary.Enable()
+			notifyKeyChange.Enable()
+			notifyPriceChange.Enable()
+			notifyStale.Enable()
+			notifySchemaDrift.Enable()
 		} else {
 			notifyUpdate.Disable()
 			notifyError.Disable()
 			notifyStartSummary.Disable()
+			notifyKeyChange.Disable()
+			notifyPriceChange.Disable()
+			notifyStale.Disable()
+			notifySchemaDrift.Disable()
 		}
 	}
 	setNotificationsEnabled(cfg.Notifications.Enabled)
@@ -115,29 +227,103 @@ func Show(app fyne.App, deps Deps) {
 		setNotificationsEnabled(value)
 	}
 
-	logLevelSelect := widget.NewSelect([]string{"debug", "info", "warn", "error"}, nil)
+	watchEntry := widget.NewMultiLineEntry()
+	watchEntry.SetPlaceHolder("Model IDs to watch, one per line")
+	watchEntry.SetText(strings.Join(cfg.Models.Watch, "\n"))
+	watchEntry.SetMinRowsVisible(3)
+
+	proxyEnabled := widget.NewCheck("Enable local metering proxy", nil)
+	proxyEnabled.SetChecked(cfg.Proxy.Enabled)
+	proxyListen := widget.NewEntry()
+	proxyListen.SetPlaceHolder("127.0.0.1:8787")
+	proxyListen.SetText(cfg.Proxy.Listen)
+
+	dashboardEnabled := widget.NewCheck("Serve web dashboard", nil)
+	dashboardEnabled.SetChecked(cfg.Dashboard.Enabled)
+	dashboardListen := widget.NewEntry()
+	dashboardListen.SetPlaceHolder("127.0.0.1:8790")
+	dashboardListen.SetText(cfg.Dashboard.Listen)
+	openDashboardButton := widget.NewButton("Open in browser", func() {
+		u, err := url.Parse("http://" + deps.ConfigStore.Get().Dashboard.Listen + "/")
+		if err == nil {
+			err = app.OpenURL(u)
+		}
+		if err != nil {
+			settingsLogger.Warn("open dashboard failed", "error", err)
+		}
+	})
+
+	mqttEnabled := widget.NewCheck("Publish to MQTT", nil)
+	mqttEnabled.SetChecked(cfg.MQTT.Enabled)
+	mqttBroker := widget.NewEntry()
+	mqttBroker.SetPlaceHolder("tcp://localhost:1883 or ssl://host:8883")
+	mqttBroker.SetText(cfg.MQTT.Broker)
+	mqttUsername := widget.NewEntry()
+	mqttUsername.SetText(cfg.MQTT.Username)
+	mqttPassword := widget.NewPasswordEntry()
+	mqttPassword.SetText(cfg.MQTT.Password)
+	mqttPrefix := widget.NewEntry()
+	mqttPrefix.SetText(cfg.MQTT.TopicPrefix)
+	mqttDiscovery := widget.NewCheck("Home Assistant discovery", nil)
+	mqttDiscovery.SetChecked(cfg.MQTT.Discovery)
+
+	logLevelSelect := widget.NewSelect(config.LogLevels, nil)
 	logLevelSelect.SetSelected(cfg.Logging.Level)
+	logFormatSelect := widget.NewSelect(config.LogFormats, nil)
+	logFormatSelect.SetSelected(cfg.Logging.Format)
+	logComponents := widget.NewMultiLineEntry()
+	logComponents.SetPlaceHolder("Per-component levels, e.g. client=debug")
+	logComponents.SetText(formatComponentLevels(cfg.Logging.Components))
+	logComponents.SetMinRowsVisible(2)
 	logToFile := widget.NewCheck("Log to file", nil)
 	logToFile.SetChecked(cfg.Logging.ToFile)
+	logCompress := widget.NewCheck("Compress rotated log files", nil)
+	logCompress.SetChecked(cfg.Logging.Compress)
 
 	saveButton := widget.NewButton("Save", func() {
-		newCfg := config.Config{
-			Connection: config.ConnectionConfig{Token: strings.TrimSpace(tokenEntry.Text)},
-			Updates: config.UpdatesConfig{
-				Period:        periodSelect.Selected,
-				UpdateOnStart: updateOnStart.Checked,
-			},
-			Notifications: config.NotificationsConfig{
-				Enabled:        notifyEnabled.Checked,
-				OnUpdateSpent:  notifyUpdate.Checked,
-				OnError:        notifyError.Checked,
-				OnStartSummary: notifyStartSummary.Checked,
-			},
-			Logging: config.LoggingConfig{
-				Level:  logLevelSelect.Selected,
-				ToFile: logToFile.Checked,
-			},
+		// Start from the stored config so options without a widget survive saving.
+		newCfg := deps.ConfigStore.Get()
+		newCfg.Connection.Token = strings.TrimSpace(tokenEntry.Text)
+		newCfg.Connection.ProvisioningKey = strings.TrimSpace(provisioningEntry.Text)
+		conn, _, err := readConnection(newCfg.Connection)
+		if err != nil {
+			statusLabel.SetText("Save failed: " + err.Error())
+			return
 		}
+		newCfg.Connection = conn
+		newCfg.Updates.Period = periodSelect.Selected
+		newCfg.Updates.UpdateOnStart = updateOnStart.Checked
+		newCfg.Updates.FetchActivity = fetchActivity.Checked
+		newCfg.Notifications.Enabled = notifyEnabled.Checked
+		newCfg.Notifications.OnUpdateSpent = notifyUpdate.Checked
+		newCfg.Notifications.OnError = notifyError.Checked
+		newCfg.Notifications.OnStartSummary = notifyStartSummary.Checked
+		newCfg.Notifications.OnKeyChange = notifyKeyChange.Checked
+		newCfg.Notifications.OnPriceChange = notifyPriceChange.Checked
+		newCfg.Notifications.OnStale = notifyStale.Checked
+		newCfg.Notifications.OnSchemaDrift = notifySchemaDrift.Checked
+		newCfg.Models.Watch = strings.Split(watchEntry.Text, "\n")
+		newCfg.Proxy.Enabled = proxyEnabled.Checked
+		newCfg.Proxy.Listen = strings.TrimSpace(proxyListen.Text)
+		newCfg.Dashboard.Enabled = dashboardEnabled.Checked
+		newCfg.Dashboard.Listen = strings.TrimSpace(dashboardListen.Text)
+		if newCfg.Dashboard.Enabled {
+			if err := dashboard.CheckListen(newCfg.Dashboard.Listen); err != nil {
+				statusLabel.SetText("Save failed: " + err.Error())
+				return
+			}
+		}
+		newCfg.MQTT.Enabled = mqttEnabled.Checked
+		newCfg.MQTT.Broker = mqttBroker.Text
+		newCfg.MQTT.Username = strings.TrimSpace(mqttUsername.Text)
+		newCfg.MQTT.Password = mqttPassword.Text
+		newCfg.MQTT.TopicPrefix = mqttPrefix.Text
+		newCfg.MQTT.Discovery = mqttDiscovery.Checked
+		newCfg.Logging.Level = logLevelSelect.Selected
+		newCfg.Logging.Format = logFormatSelect.Selected
+		newCfg.Logging.Components = parseComponentLevels(logComponents.Text)
+		newCfg.Logging.ToFile = logToFile.Checked
+		newCfg.Logging.Compress = logCompress.Checked
 		config.Normalize(&newCfg)
 		deps.ConfigStore.Set(newCfg)
 		if err := deps.ConfigStore.Save(); err != nil {
@@ -145,29 +331,31 @@ func Show(app fyne.App, deps Deps) {
 			return
 		}
 		settingsLogger.Info("config saved")
-		if deps.LevelVar != nil {
-			logging.SetLevel(deps.LevelVar, newCfg.Logging.Level)
+		if deps.Autostart != nil && startAtLogin.Checked != autostartEnabled {
+			if err := applyAutostart(deps.Autostart, startAtLogin.Checked); err != nil {
+				settingsLogger.Warn("autostart change failed", "error", err)
+				dialog.ShowError(fmt.Errorf("start at login: %w", err), window)
+			} else {
+				autostartEnabled = startAtLogin.Checked
+				settingsLogger.Info("autostart changed", "enabled", autostartEnabled)
+			}
 		}
 		if deps.LogOutput != nil {
+			deps.LogOutput.Apply(newCfg.Logging)
 			if newCfg.Logging.ToFile {
-				if err := deps.LogOutput.EnableFile(deps.LogPath); err != nil {
+				if err := deps.LogOutput.EnableFile(deps.LogPath, logging.RotationFromConfig(newCfg.Logging)); err != nil {
 					settingsLogger.Warn("log file enable failed", "error", err, "path", deps.LogPath)
 				}
 			} else if err := deps.LogOutput.DisableFile(); err != nil {
 				settingsLogger.Warn("log file disable failed", "error", err)
 			}
 		}
-		if deps.Notifier != nil {
-			deps.Notifier.UpdateConfig(newCfg.Notifications)
-		}
 		if deps.Scheduler != nil {
 			if dur, ok := config.ParsePeriod(newCfg.Updates.Period); ok {
 				deps.Scheduler.Reschedule(dur)
 			}
 		}
-		if deps.OnConfigApplied != nil {
-			deps.OnConfigApplied(newCfg)
-		}
+		deps.Bus.Publish(events.ConfigChanged{Config: newCfg})
 		statusLabel.SetText("Saved. Updating...")
 		go func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -186,33 +374,135 @@ func Show(app fyne.App, deps Deps) {
 	form := container.NewVBox(
 		widget.NewLabelWithStyle("Connection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		connectionRow,
+		provisioningRow,
+		container.NewGridWithColumns(2, widget.NewLabel("Base URL"), baseURLEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("HTTP proxy"), httpProxyEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("CA bundle"), caFileEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("Timeout (seconds)"), timeoutEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("Connect timeout (seconds)"), connectTimeoutEntry),
+		container.NewHBox(testConnectionButton),
 		widget.NewSeparator(),
 		widget.NewLabelWithStyle("Update settings", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		container.NewGridWithColumns(2, widget.NewLabel("Period"), periodSelect),
+		startAtLogin,
 		updateOnStart,
+		fetchActivity,
 		widget.NewSeparator(),
 		widget.NewLabelWithStyle("Notifications", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		notifyEnabled,
 		indentCheck(notifyUpdate),
 		indentCheck(notifyStartSummary),
 		indentCheck(notifyError),
+		indentCheck(notifyKeyChange),
+		indentCheck(notifyPriceChange),
+		indentCheck(notifyStale),
+		indentCheck(notifySchemaDrift),
 		testNotifyButton,
 		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Watched model prices", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		watchEntry,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Project proxy", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		proxyEnabled,
+		container.NewGridWithColumns(2, widget.NewLabel("Listen address"), proxyListen),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Web dashboard", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		dashboardEnabled,
+		container.NewGridWithColumns(2, widget.NewLabel("Listen address"), dashboardListen),
+		container.NewHBox(openDashboardButton),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("MQTT", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		mqttEnabled,
+		container.NewGridWithColumns(2, widget.NewLabel("Broker"), mqttBroker),
+		container.NewGridWithColumns(2, widget.NewLabel("Username"), mqttUsername),
+		container.NewGridWithColumns(2, widget.NewLabel("Password"), mqttPassword),
+		container.NewGridWithColumns(2, widget.NewLabel("Topic prefix"), mqttPrefix),
+		mqttDiscovery,
+		widget.NewSeparator(),
 		widget.NewLabelWithStyle("Logging", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		container.NewGridWithColumns(2, widget.NewLabel("Level"), logLevelSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Stdout format"), logFormatSelect),
+		logComponents,
 		logToFile,
+		indentCheck(logCompress),
 		layout.NewSpacer(),
 		container.NewHBox(saveButton),
 		statusLabel,
 	)
 
-	window.SetContent(container.NewPadded(form))
+	// The form outgrew small screens; keep it scrollable.
+	window.SetContent(container.NewVScroll(container.NewPadded(form)))
 	window.SetOnClosed(func() {
 		window = nil
 	})
 	window.Show()
 }
 
+// connectionFromForm copies the connection form fields into conn. Empty
+// timeouts fall back to the defaults in config.Normalize.
+func connectionFromForm(conn config.ConnectionConfig, baseURL, proxyURL, caFile, timeout, connectTimeout string) (config.ConnectionConfig, error) {
+	conn.BaseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	conn.Proxy = strings.TrimSpace(proxyURL)
+	conn.CAFile = strings.TrimSpace(caFile)
+	var err error
+	if conn.TimeoutSeconds, err = parseSeconds("timeout", timeout); err != nil {
+		return conn, err
+	}
+	if conn.ConnectTimeoutSeconds, err = parseSeconds("connect timeout", connectTimeout); err != nil {
+		return conn, err
+	}
+	return conn, nil
+}
+
+func parseSeconds(name, text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: %q is not a number of seconds", name, text)
+	}
+	return n, nil
+}
+
+// parseComponentLevels reads "component=level" pairs, one per line or comma
+// separated. Invalid levels are dropped by config.Normalize.
+func parseComponentLevels(text string) map[string]string {
+	var out map[string]string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ',' }) {
+		name, level, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[strings.TrimSpace(name)] = strings.TrimSpace(level)
+	}
+	return out
+}
+
+func formatComponentLevels(levels map[string]string) string {
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + "=" + levels[name]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func applyAutostart(manager autostart.Manager, enabled bool) error {
+	if enabled {
+		return manager.Enable()
+	}
+	return manager.Disable()
+}
+
 func runOnMain(fn func()) {
 	if fn == nil {
 		return
@@ -4,9 +4,11 @@ import (
 	"testing"
 
 	"fyne.io/fyne/v2"
+	fynecontainer "fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/test"
 	"fyne.io/fyne/v2/widget"
 
+	"openrouter-costs-tray/internal/autostart"
 	"openrouter-costs-tray/internal/config"
 )
 
@@ -36,23 +38,57 @@ func TestNotificationChecksToggle(t *testing.T) {
 	update := checks["On update: spent"]
 	errorCheck := checks["On error"]
 	startSummary := checks["On start: spends summary"]
-	if enable == nil || update == nil || errorCheck == nil || startSummary == nil {
+	keyChange := checks["On key added/removed/disabled"]
+	if enable == nil || update == nil || errorCheck == nil || startSummary == nil || keyChange == nil {
 		t.Fatalf("expected notification checks to exist")
 	}
 
 	if enable.Checked {
 		t.Fatalf("expected notifications disabled by default")
 	}
-	if !update.Disabled() || !errorCheck.Disabled() || !startSummary.Disabled() {
+	if !update.Disabled() || !errorCheck.Disabled() || !startSummary.Disabled() || !keyChange.Disabled() {
 		t.Fatalf("expected notification options disabled when notifications off")
 	}
 
 	enable.SetChecked(true)
-	if update.Disabled() || errorCheck.Disabled() || startSummary.Disabled() {
+	if update.Disabled() || errorCheck.Disabled() || startSummary.Disabled() || keyChange.Disabled() {
 		t.Fatalf("expected notification options enabled after toggle")
 	}
 }
 
+func TestStartAtLoginReflectsAutostart(t *testing.T) {
+	app := test.NewApp()
+	window = nil
+	defer func() {
+		if window != nil {
+			window.Close()
+			window = nil
+		}
+	}()
+
+	manager := autostart.XDG{Exe: "/usr/bin/openrouter-costs-tray", Dir: t.TempDir()}
+	if err := manager.Enable(); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	Show(app, Deps{ConfigStore: config.NewStore("unused", config.DefaultConfig()), Autostart: manager})
+
+	checks := map[string]*widget.Check{}
+	collectChecks(window.Content(), checks)
+	check := checks["Start at login"]
+	if check == nil || !check.Checked || check.Disabled() {
+		t.Fatalf("expected enabled start at login check, got %+v", check)
+	}
+	window.Close()
+	window = nil
+
+	Show(app, Deps{ConfigStore: config.NewStore("unused", config.DefaultConfig())})
+	checks = map[string]*widget.Check{}
+	collectChecks(window.Content(), checks)
+	if check := checks["Start at login"]; check == nil || !check.Disabled() {
+		t.Fatalf("expected start at login disabled without a manager")
+	}
+}
+
 func collectChecks(obj fyne.CanvasObject, out map[string]*widget.Check) {
 	if obj == nil {
 		return
@@ -60,9 +96,46 @@ func collectChecks(obj fyne.CanvasObject, out map[string]*widget.Check) {
 	if check, ok := obj.(*widget.Check); ok {
 		out[check.Text] = check
 	}
+	if scroll, ok := obj.(*fynecontainer.Scroll); ok {
+		collectChecks(scroll.Content, out)
+	}
 	if container, ok := obj.(*fyne.Container); ok {
 		for _, child := range container.Objects {
 			collectChecks(child, out)
 		}
 	}
 }
+
+func TestComponentLevelsText(t *testing.T) {
+	levels := parseComponentLevels(" client = debug\nmqtt=warn, refresher=error\n=info\nbogus\n")
+	if len(levels) != 3 || levels["client"] != "debug" || levels["mqtt"] != "warn" || levels["refresher"] != "error" {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+	if got := formatComponentLevels(levels); got != "client=debug\nmqtt=warn\nrefresher=error" {
+		t.Fatalf("unexpected text %q", got)
+	}
+	if parseComponentLevels("  ") != nil || formatComponentLevels(nil) != "" {
+		t.Fatalf("expected empty levels to round trip")
+	}
+}
+
+func TestConnectionFromForm(t *testing.T) {
+	cur := config.ConnectionConfig{Token: "token", TimeoutSeconds: 15, ConnectTimeoutSeconds: 10}
+	conn, err := connectionFromForm(cur, " https://gateway.local/api/v1/ ", " socks5://proxy:1080 ", " /etc/ca.pem ", "60", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.ConnectionConfig{
+		Token:          "token",
+		BaseURL:        "https://gateway.local/api/v1",
+		Proxy:          "socks5://proxy:1080",
+		CAFile:         "/etc/ca.pem",
+		TimeoutSeconds: 60,
+	}
+	if conn != want {
+		t.Fatalf("unexpected connection %+v", conn)
+	}
+	if _, err := connectionFromForm(cur, "", "", "", "soon", "10"); err == nil {
+		t.Fatalf("expected error for a non-numeric timeout")
+	}
+}
@@ -0,0 +1,119 @@
+package spend
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/report"
+)
+
+const dateLayout = "2006-01-02"
+
+type Deps struct {
+	History *history.Store
+	Logger  *slog.Logger
+}
+
+var window fyne.Window
+
+// Show opens the spend export window.
+func Show(app fyne.App, deps Deps) {
+	if window != nil {
+		window.Show()
+		window.RequestFocus()
+		return
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	window = app.NewWindow("Export spend")
+	window.Resize(fyne.NewSize(420, 300))
+
+	now := time.Now()
+	fromEntry := widget.NewEntry()
+	fromEntry.SetText(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format(dateLayout))
+	toEntry := widget.NewEntry()
+	toEntry.SetText(now.Format(dateLayout))
+	formatSelect := widget.NewSelect(report.Formats, nil)
+	formatSelect.SetSelected("csv")
+	bySelect := widget.NewSelect(report.Periods, nil)
+	bySelect.SetSelected(string(report.None))
+	utcCheck := widget.NewCheck("Use UTC", nil)
+	statusLabel := widget.NewLabel("")
+
+	exportButton := widget.NewButton("Export...", func() {
+		opts, err := options(fromEntry.Text, toEntry.Text, formatSelect.Selected, bySelect.Selected, utcCheck.Checked, time.Now())
+		if err != nil {
+			statusLabel.SetText(err.Error())
+			return
+		}
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				statusLabel.SetText("Export failed: " + err.Error())
+				return
+			}
+			if writer == nil {
+				return
+			}
+			err = write(writer, deps.History, opts)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				logger.Warn("spend export failed", "error", err)
+				statusLabel.SetText("Export failed: " + err.Error())
+				return
+			}
+			logger.Info("spend exported", "path", writer.URI().Path())
+			statusLabel.SetText("Exported to " + writer.URI().Path())
+		}, window)
+		save.SetFileName("openrouter-spend-" + opts.From.Format(dateLayout) + "." + opts.Format)
+		save.SetFilter(storage.NewExtensionFileFilter([]string{"." + opts.Format}))
+		save.Show()
+	})
+
+	form := container.NewVBox(
+		container.NewGridWithColumns(2, widget.NewLabel("From (YYYY-MM-DD)"), fromEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("To (YYYY-MM-DD)"), toEntry),
+		container.NewGridWithColumns(2, widget.NewLabel("Format"), formatSelect),
+		container.NewGridWithColumns(2, widget.NewLabel("Aggregate by"), bySelect),
+		utcCheck,
+		container.NewHBox(exportButton),
+		statusLabel,
+	)
+	window.SetContent(container.NewPadded(form))
+	window.SetOnClosed(func() {
+		window = nil
+	})
+	window.Show()
+}
+
+func options(from, to, format, by string, utc bool, now time.Time) (report.Options, error) {
+	loc := time.Local
+	if utc {
+		loc = time.UTC
+	}
+	start, end, err := report.Range(from, to, loc, now)
+	if err != nil {
+		return report.Options{}, err
+	}
+	return report.Options{From: start, To: end, Location: loc, Aggregate: report.Period(by), Format: format}, nil
+}
+
+func write(w io.Writer, store *history.Store, opts report.Options) error {
+	samples, err := store.SamplesWithBaseline(opts.From, opts.To)
+	if err != nil {
+		return err
+	}
+	return report.Write(w, samples, opts)
+}
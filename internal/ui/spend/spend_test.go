@@ -0,0 +1,55 @@
+package spend
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/report"
+)
+
+func TestOptions(t *testing.T) {
+	now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	opts, err := options("2024-05-01", "2024-05-02", "json", "day", true, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Location != time.UTC || opts.Aggregate != report.Day || opts.Format != "json" || !opts.To.Equal(time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if _, err := options("bad", "", "csv", "none", false, now); err == nil {
+		t.Fatalf("expected invalid date error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.AppendSamples([]history.SpendSample{{At: at, KeyID: "k1", Total: 1}}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	opts, _ := options("2024-05-01", "2024-05-01", "csv", "none", true, at)
+	var buf bytes.Buffer
+	if err := write(&buf, store, opts); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if buf.String() != "at,key_id,key_label,total,daily,weekly,monthly\n2024-05-01T12:00:00Z,k1,,1,,,\n" {
+		t.Fatalf("unexpected export: %q", buf.String())
+	}
+}
+
+func TestShowReusesWindow(t *testing.T) {
+	app := test.NewApp()
+	window = nil
+	Show(app, Deps{History: history.NewStore(t.TempDir())})
+	first := window
+	Show(app, Deps{})
+	if window != first {
+		t.Fatalf("expected the open window to be reused")
+	}
+	window.Close()
+	window = nil
+}
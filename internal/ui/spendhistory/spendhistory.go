@@ -0,0 +1,254 @@
+package spendhistory
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/chart"
+	"openrouter-costs-tray/internal/history"
+	"openrouter-costs-tray/internal/report"
+	"openrouter-costs-tray/internal/util"
+)
+
+const (
+	dailyDays = 30
+	allKeys   = "All keys"
+)
+
+var weekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+type Deps struct {
+	History *history.Store
+	Logger  *slog.Logger
+}
+
+var window fyne.Window
+
+// series is what the history charts show for one key selection.
+type series struct {
+	dailyFrom time.Time
+	daily     []float64
+	monthFrom time.Time
+	month     []float64
+	hourly    [7][24]float64
+}
+
+// buildSeries prepares the charts from samples: daily spend of the last 30
+// days, the running total of the current month and spend by weekday and hour
+// over the same 30 days.
+func buildSeries(samples []history.SpendSample, key string, now time.Time, loc *time.Location) series {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	monthFrom := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	increases := report.Increases(samples)
+	s := series{
+		dailyFrom: today.AddDate(0, 0, -(dailyDays - 1)),
+		monthFrom: monthFrom,
+	}
+	s.daily = report.DailySpend(increases, key, s.dailyFrom, dailyDays, loc)
+	s.month = report.Cumulative(report.DailySpend(increases, key, monthFrom, now.Day(), loc))
+	s.hourly = report.HourlySpend(increases, key, s.dailyFrom, today.AddDate(0, 0, 1), loc)
+	return s
+}
+
+func dailyText(from time.Time, i int, v float64) string {
+	return from.AddDate(0, 0, i).Format("Mon 2006-01-02") + ": " + util.FormatUSD(v)
+}
+
+func monthText(from time.Time, i int, v float64) string {
+	return from.AddDate(0, 0, i).Format("2006-01-02") + ": " + util.FormatUSD(v) + " this month"
+}
+
+func hourText(row, col int, v float64) string {
+	return fmt.Sprintf("%s %02d:00-%02d:00: %s", weekdays[row], col, col+1, util.FormatUSD(v))
+}
+
+// Show opens the spend history window.
+func Show(app fyne.App, deps Deps) {
+	if window != nil {
+		window.Show()
+		window.RequestFocus()
+		return
+	}
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	window = app.NewWindow("Spend history")
+	window.Resize(fyne.NewSize(640, 420))
+
+	var (
+		samples []history.SpendSample
+		keys    []report.Key
+		current series
+	)
+	hoverLabel := widget.NewLabel("")
+	onHover := func(text string) {
+		hoverLabel.SetText(text)
+	}
+	dailyScale := widget.NewLabel("")
+	monthScale := widget.NewLabel("")
+
+	dailyView := newChartView(func(size chart.Size) []fyne.CanvasObject {
+		return drawBars(current.daily, size)
+	}, func(size chart.Size, p chart.Point) string {
+		i := chart.Column(len(current.daily), size.W, p.X)
+		if i < 0 {
+			return ""
+		}
+		return dailyText(current.dailyFrom, i, current.daily[i])
+	}, onHover)
+	monthView := newChartView(func(size chart.Size) []fyne.CanvasObject {
+		return drawLine(current.month, size)
+	}, func(size chart.Size, p chart.Point) string {
+		i := chart.Column(len(current.month), size.W, p.X)
+		if i < 0 {
+			return ""
+		}
+		return monthText(current.monthFrom, i, current.month[i])
+	}, onHover)
+	heatView := newChartView(func(size chart.Size) []fyne.CanvasObject {
+		return drawHeatmap(current.hourly, size)
+	}, func(size chart.Size, p chart.Point) string {
+		row, col, ok := chart.Cell(7, 24, size, p)
+		if !ok {
+			return ""
+		}
+		return hourText(row, col, current.hourly[row][col])
+	}, onHover)
+
+	keySelect := widget.NewSelect([]string{allKeys}, nil)
+	update := func() {
+		key := ""
+		for _, k := range keys {
+			if k.Name() == keySelect.Selected {
+				key = k.ID
+				break
+			}
+		}
+		current = buildSeries(samples, key, time.Now(), time.Local)
+		dailyScale.SetText("Scale: " + util.FormatUSD(chart.NiceCeil(chart.Max(current.daily))) + " per day")
+		monthScale.SetText("Scale: " + util.FormatUSD(chart.NiceCeil(chart.Max(current.month))))
+		dailyView.Refresh()
+		monthView.Refresh()
+		heatView.Refresh()
+	}
+	reload := func() {
+		var err error
+		samples, err = deps.History.Samples(time.Now().AddDate(0, 0, -(dailyDays+31)), time.Time{})
+		if err != nil {
+			logger.Warn("spend samples load failed", "error", err)
+			hoverLabel.SetText("Failed to load history: " + err.Error())
+		}
+		keys = report.Keys(samples)
+		options := []string{allKeys}
+		for _, k := range keys {
+			options = append(options, k.Name())
+		}
+		keySelect.Options = options
+		if !slices.Contains(options, keySelect.Selected) {
+			keySelect.Selected = allKeys
+		}
+		keySelect.Refresh()
+		update()
+	}
+	keySelect.OnChanged = func(string) {
+		update()
+	}
+	reloadButton := widget.NewButton("Reload", reload)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Last 30 days", container.NewBorder(dailyScale, axis("30 days ago", "today"), nil, nil, dailyView)),
+		container.NewTabItem("This month", container.NewBorder(monthScale, axis("1st", "today"), nil, nil, monthView)),
+		container.NewTabItem("By hour", container.NewBorder(
+			widget.NewLabel("Last 30 days, rows Monday to Sunday"), axis("00:00", "23:00"), nil, nil, heatView)),
+	)
+	top := container.NewBorder(nil, nil, widget.NewLabel("Key"), reloadButton, keySelect)
+	window.SetContent(container.NewPadded(container.NewBorder(top, hoverLabel, nil, nil, tabs)))
+	window.SetOnClosed(func() {
+		window = nil
+	})
+	reload()
+	window.Show()
+}
+
+func axis(start, end string) fyne.CanvasObject {
+	return container.NewHBox(widget.NewLabel(start), layout.NewSpacer(), widget.NewLabel(end))
+}
+
+func chartColor() color.NRGBA {
+	return color.NRGBAModel.Convert(theme.PrimaryColor()).(color.NRGBA)
+}
+
+func drawBars(values []float64, size chart.Size) []fyne.CanvasObject {
+	fill := chartColor()
+	objects := []fyne.CanvasObject{baseline(size)}
+	for _, r := range chart.Bars(values, chart.NiceCeil(chart.Max(values)), size) {
+		if r.H <= 0 {
+			continue
+		}
+		rect := canvas.NewRectangle(fill)
+		rect.Move(fyne.NewPos(r.X, r.Y))
+		rect.Resize(fyne.NewSize(r.W, r.H))
+		objects = append(objects, rect)
+	}
+	return objects
+}
+
+func drawLine(values []float64, size chart.Size) []fyne.CanvasObject {
+	stroke := chartColor()
+	objects := []fyne.CanvasObject{baseline(size)}
+	points := chart.Polyline(values, chart.NiceCeil(chart.Max(values)), size)
+	for i, p := range points {
+		if i > 0 {
+			line := canvas.NewLine(stroke)
+			line.StrokeWidth = 2
+			line.Position1 = fyne.NewPos(points[i-1].X, points[i-1].Y)
+			line.Position2 = fyne.NewPos(p.X, p.Y)
+			objects = append(objects, line)
+		}
+		dot := canvas.NewCircle(stroke)
+		dot.Move(fyne.NewPos(p.X-2.5, p.Y-2.5))
+		dot.Resize(fyne.NewSize(5, 5))
+		objects = append(objects, dot)
+	}
+	return objects
+}
+
+func drawHeatmap(values [7][24]float64, size chart.Size) []fyne.CanvasObject {
+	var top float64
+	for _, row := range values {
+		top = max(top, chart.Max(row[:]))
+	}
+	fill := chartColor()
+	cells := chart.Grid(7, 24, size)
+	objects := make([]fyne.CanvasObject, 0, len(cells))
+	for i, r := range cells {
+		rect := canvas.NewRectangle(chart.Shade(fill, values[i/24][i%24], top))
+		rect.StrokeColor = theme.SeparatorColor()
+		rect.StrokeWidth = 1
+		rect.Move(fyne.NewPos(r.X, r.Y))
+		rect.Resize(fyne.NewSize(r.W, r.H))
+		objects = append(objects, rect)
+	}
+	return objects
+}
+
+func baseline(size chart.Size) fyne.CanvasObject {
+	line := canvas.NewLine(theme.ForegroundColor())
+	line.StrokeWidth = 1
+	line.Position1 = fyne.NewPos(0, size.H)
+	line.Position2 = fyne.NewPos(size.W, size.H)
+	return line
+}
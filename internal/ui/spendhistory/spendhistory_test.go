@@ -0,0 +1,101 @@
+package spendhistory
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	fynecontainer "fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/history"
+)
+
+func TestBuildSeries(t *testing.T) {
+	now := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+	samples := []history.SpendSample{
+		{At: time.Date(2024, 4, 20, 9, 0, 0, 0, time.UTC), KeyID: "k1", Total: 1},
+		{At: time.Date(2024, 4, 30, 9, 30, 0, 0, time.UTC), KeyID: "k1", Total: 2},
+		{At: time.Date(2024, 5, 2, 14, 10, 0, 0, time.UTC), KeyID: "k1", Total: 2.5},
+		{At: time.Date(2024, 5, 3, 14, 20, 0, 0, time.UTC), KeyID: "k1", Total: 3},
+	}
+	s := buildSeries(samples, "", now, time.UTC)
+
+	if len(s.daily) != dailyDays || !s.dailyFrom.Equal(time.Date(2024, 4, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected daily range %v %d", s.dailyFrom, len(s.daily))
+	}
+	if s.daily[dailyDays-1] != 0.5 || s.daily[dailyDays-4] != 1 {
+		t.Fatalf("unexpected daily values %v", s.daily)
+	}
+	if len(s.month) != 3 || s.month[0] != 0 || s.month[1] != 0.5 || s.month[2] != 1 {
+		t.Fatalf("expected cumulative spend of May 1-3, got %v", s.month)
+	}
+	// April 30 was a Tuesday, May 2 a Thursday and May 3 a Friday.
+	if s.hourly[1][9] != 1 || s.hourly[3][14] != 0.5 || s.hourly[4][14] != 0.5 {
+		t.Fatalf("unexpected hourly values %v", s.hourly)
+	}
+
+	if got := buildSeries(samples, "other", now, time.UTC); got.month[2] != 0 {
+		t.Fatalf("expected no spend for an unknown key, got %v", got.month)
+	}
+}
+
+func TestHoverTexts(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if got := dailyText(from, 2, 1.5); got != "Fri 2024-05-03: $1.500" {
+		t.Fatalf("unexpected daily text %q", got)
+	}
+	if got := monthText(from, 0, 1.25); got != "2024-05-01: $1.250 this month" {
+		t.Fatalf("unexpected month text %q", got)
+	}
+	if got := hourText(6, 23, 2); got != "Sun 23:00-24:00: $2.000" {
+		t.Fatalf("unexpected hour text %q", got)
+	}
+}
+
+func TestShowListsKeys(t *testing.T) {
+	app := test.NewApp()
+	window = nil
+	defer func() {
+		if window != nil {
+			window.Close()
+			window = nil
+		}
+	}()
+
+	store := history.NewStore(t.TempDir())
+	now := time.Now()
+	if err := store.AppendSamples([]history.SpendSample{
+		{At: now.Add(-2 * time.Hour), KeyID: "k1", KeyLabel: "dev", Total: 1},
+		{At: now.Add(-time.Hour), KeyID: "k1", KeyLabel: "dev", Total: 2},
+	}); err != nil {
+		t.Fatalf("append samples: %v", err)
+	}
+	Show(app, Deps{History: store})
+
+	sel := findSelect(window.Content())
+	if sel == nil {
+		t.Fatalf("expected key select")
+	}
+	if len(sel.Options) != 2 || sel.Options[0] != allKeys || sel.Options[1] != "dev" || sel.Selected != allKeys {
+		t.Fatalf("unexpected key options %v selected %q", sel.Options, sel.Selected)
+	}
+	sel.SetSelected("dev")
+}
+
+func findSelect(obj fyne.CanvasObject) *widget.Select {
+	switch o := obj.(type) {
+	case *widget.Select:
+		return o
+	case *fynecontainer.AppTabs:
+		return nil
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			if sel := findSelect(child); sel != nil {
+				return sel
+			}
+		}
+	}
+	return nil
+}
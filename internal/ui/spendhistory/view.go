@@ -0,0 +1,76 @@
+package spendhistory
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	"openrouter-costs-tray/internal/chart"
+)
+
+// chartView draws a chart laid out by the chart package and reports the value
+// under the mouse.
+type chartView struct {
+	widget.BaseWidget
+	draw    func(size chart.Size) []fyne.CanvasObject
+	hit     func(size chart.Size, p chart.Point) string
+	onHover func(text string)
+}
+
+var _ desktop.Hoverable = (*chartView)(nil)
+
+func newChartView(draw func(chart.Size) []fyne.CanvasObject, hit func(chart.Size, chart.Point) string, onHover func(string)) *chartView {
+	v := &chartView{draw: draw, hit: hit, onHover: onHover}
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *chartView) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{view: v}
+}
+
+func (v *chartView) MouseIn(ev *desktop.MouseEvent) {
+	v.MouseMoved(ev)
+}
+
+func (v *chartView) MouseMoved(ev *desktop.MouseEvent) {
+	if v.onHover == nil {
+		return
+	}
+	size := v.Size()
+	v.onHover(v.hit(chart.Size{W: size.Width, H: size.Height}, chart.Point{X: ev.Position.X, Y: ev.Position.Y}))
+}
+
+func (v *chartView) MouseOut() {
+	if v.onHover != nil {
+		v.onHover("")
+	}
+}
+
+type chartRenderer struct {
+	view    *chartView
+	size    fyne.Size
+	objects []fyne.CanvasObject
+}
+
+func (r *chartRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.objects = r.view.draw(chart.Size{W: size.Width, H: size.Height})
+}
+
+func (r *chartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(320, 160)
+}
+
+func (r *chartRenderer) Refresh() {
+	r.Layout(r.size)
+	for _, obj := range r.objects {
+		obj.Refresh()
+	}
+}
+
+func (r *chartRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *chartRenderer) Destroy() {}
@@ -2,6 +2,7 @@ package tray
 
 import (
 	"log/slog"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/driver/desktop"
@@ -9,15 +10,27 @@ import (
 	"fyne.io/systray"
 
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
 	"openrouter-costs-tray/internal/state"
 	"openrouter-costs-tray/internal/summary"
+	"openrouter-costs-tray/internal/util"
+)
+
+const (
+	topModelsLimit = 5
+	topKeysLimit   = 5
 )
 
 type Actions struct {
-	Refresh      func()
-	OpenSettings func()
-	OpenWeb      func()
-	Exit         func()
+	Refresh          func()
+	OpenSettings     func()
+	OpenWeb          func()
+	LookupGeneration func()
+	ShowHistory      func()
+	ExportSpend      func()
+	ViewLogs         func()
+	Exit             func()
 }
 
 type Tray struct {
@@ -27,6 +40,12 @@ type Tray struct {
 	cfgStore   *config.Store
 	logger     *slog.Logger
 	menu       *fyne.Menu
+	weekItem   *fyne.MenuItem
+	keysItem   *fyne.MenuItem
+	pricesItem *fyne.MenuItem
+	projItem   *fyne.MenuItem
+	headItems  []*fyne.MenuItem
+	tailItems  []*fyne.MenuItem
 	actions    Actions
 }
 
@@ -43,6 +62,18 @@ func New(app fyne.App, stateStore *state.State, cfgStore *config.Store, logger *
 	if actions.OpenWeb == nil {
 		actions.OpenWeb = func() {}
 	}
+	if actions.LookupGeneration == nil {
+		actions.LookupGeneration = func() {}
+	}
+	if actions.ShowHistory == nil {
+		actions.ShowHistory = func() {}
+	}
+	if actions.ExportSpend == nil {
+		actions.ExportSpend = func() {}
+	}
+	if actions.ViewLogs == nil {
+		actions.ViewLogs = func() {}
+	}
 	if actions.Exit == nil {
 		actions.Exit = func() {}
 	}
@@ -77,6 +108,11 @@ func (t *Tray) Update() {
 	label := summary.Tooltip(cfg, snap)
 
 	t.menu.Label = label
+	t.updateModelMenu(snap, time.Now())
+	t.updateKeysMenu(snap)
+	t.updatePricesMenu(snap, cfg)
+	t.updateProjectsMenu(snap)
+	t.menu.Items = t.composeItems(t.sections(snap, cfg)...)
 	t.setIcon(snap, cfg)
 	t.menu.Refresh()
 	systray.SetTooltip(label)
@@ -92,21 +128,133 @@ func (t *Tray) buildMenu() {
 	openWebItem := fyne.NewMenuItem("Open in web", func() {
 		t.actions.OpenWeb()
 	})
+	lookupItem := fyne.NewMenuItem("Look up generation...", func() {
+		t.actions.LookupGeneration()
+	})
+	historyItem := fyne.NewMenuItem("History...", func() {
+		t.actions.ShowHistory()
+	})
+	exportItem := fyne.NewMenuItem("Export...", func() {
+		t.actions.ExportSpend()
+	})
+	logsItem := fyne.NewMenuItem("View logs...", func() {
+		t.actions.ViewLogs()
+	})
 	exitItem := fyne.NewMenuItem("Exit", func() {
 		t.actions.Exit()
 	})
+	t.weekItem = fyne.NewMenuItem("Top models (last 7 days)", nil)
+	t.weekItem.ChildMenu = fyne.NewMenu("", modelItems(nil)...)
+	t.keysItem = fyne.NewMenuItem("Top keys (total spend)", nil)
+	t.keysItem.ChildMenu = fyne.NewMenu("")
+	t.pricesItem = fyne.NewMenuItem("Watched model prices", nil)
+	t.pricesItem.ChildMenu = fyne.NewMenu("")
+	t.projItem = fyne.NewMenuItem("Projects today (UTC)", nil)
+	t.projItem.ChildMenu = fyne.NewMenu("")
 
-	t.menu = fyne.NewMenu("OpenRouter Costs", refreshItem, openWebItem, settingsItem, exitItem)
+	t.headItems = []*fyne.MenuItem{refreshItem, openWebItem}
+	t.tailItems = []*fyne.MenuItem{lookupItem, historyItem, exportItem, logsItem, settingsItem, exitItem}
+	t.menu = fyne.NewMenu("OpenRouter Costs", t.composeItems()...)
 	if t.desktopApp != nil {
 		t.desktopApp.SetSystemTrayMenu(t.menu)
 	}
 }
 
+// sections returns the optional submenus that have something to show.
+func (t *Tray) sections(snap state.Snapshot, cfg config.Config) []*fyne.MenuItem {
+	var sections []*fyne.MenuItem
+	if cfg.Updates.FetchActivity {
+		sections = append(sections, t.weekItem)
+	}
+	if len(snap.Keys) > 0 {
+		sections = append(sections, t.keysItem)
+	}
+	if len(snap.Projects) > 0 {
+		sections = append(sections, t.projItem)
+	}
+	if len(cfg.Models.Watch) > 0 {
+		sections = append(sections, t.pricesItem)
+	}
+	return sections
+}
+
+// composeItems lays out the menu with the optional sections that have content.
+func (t *Tray) composeItems(sections ...*fyne.MenuItem) []*fyne.MenuItem {
+	items := make([]*fyne.MenuItem, 0, len(t.headItems)+len(sections)+len(t.tailItems))
+	items = append(items, t.headItems...)
+	items = append(items, sections...)
+	return append(items, t.tailItems...)
+}
+
+// updateModelMenu fills the top models of the last seven completed UTC days.
+func (t *Tray) updateModelMenu(snap state.Snapshot, now time.Time) {
+	t.weekItem.ChildMenu.Items = modelItems(summary.TopModels(snap.Activity, now, 7, topModelsLimit))
+}
+
+// updateKeysMenu fills the top spending keys and the account total listed in
+// provisioning mode.
+func (t *Tray) updateKeysMenu(snap state.Snapshot) {
+	top := summary.TopKeys(snap.Keys, topKeysLimit)
+	items := make([]*fyne.MenuItem, 0, len(top)+2)
+	for _, key := range top {
+		label := summary.KeyName(key) + ": " + util.FormatUSD(summary.KeySpend(key))
+		if key.Disabled {
+			label += " (disabled)"
+		}
+		items = append(items, fyne.NewMenuItem(label, nil))
+	}
+	items = append(items, fyne.NewMenuItemSeparator())
+	account := openrouter.AccountUsage(snap.Keys)
+	items = append(items, fyne.NewMenuItem("Account total: "+util.FormatUSD(account.Total), nil))
+	t.keysItem.ChildMenu.Items = items
+}
+
+func (t *Tray) updatePricesMenu(snap state.Snapshot, cfg config.Config) {
+	items := make([]*fyne.MenuItem, 0, len(cfg.Models.Watch))
+	for _, id := range cfg.Models.Watch {
+		label := id + ": unavailable"
+		if price, ok := snap.Prices[id]; ok {
+			label = id + ": " + price.String()
+		}
+		items = append(items, fyne.NewMenuItem(label, nil))
+		if price, ok := snap.Prices[id+pricing.FreeSuffix]; ok {
+			items = append(items, fyne.NewMenuItem(id+pricing.FreeSuffix+": "+price.String(), nil))
+		}
+	}
+	t.pricesItem.ChildMenu.Items = items
+}
+
+func (t *Tray) updateProjectsMenu(snap state.Snapshot) {
+	projects := summary.RankProjects(snap.Projects)
+	if len(projects) == 0 {
+		t.projItem.ChildMenu.Items = modelItems(nil)
+		return
+	}
+	items := make([]*fyne.MenuItem, 0, len(projects))
+	for _, project := range projects {
+		items = append(items, fyne.NewMenuItem(project.Project+": "+util.FormatUSD(project.Spend), nil))
+	}
+	t.projItem.ChildMenu.Items = items
+}
+
+func modelItems(top []summary.ModelCost) []*fyne.MenuItem {
+	if len(top) == 0 {
+		empty := fyne.NewMenuItem("No data", nil)
+		empty.Disabled = true
+		return []*fyne.MenuItem{empty}
+	}
+	items := make([]*fyne.MenuItem, 0, len(top))
+	for _, model := range top {
+		items = append(items, fyne.NewMenuItem(model.Model+": "+util.FormatUSD(model.Usage), nil))
+	}
+	return items
+}
+
 func (t *Tray) setIcon(snap state.Snapshot, cfg config.Config) {
 	if t.desktopApp == nil {
 		return
 	}
-	if snap.NotConfigured || cfg.Connection.Token == "" {
+	if snap.NotConfigured || !cfg.Connection.Configured() {
 		t.desktopApp.SetSystemTrayIcon(IconResource())
 		return
 	}
@@ -114,5 +262,9 @@ func (t *Tray) setIcon(snap state.Snapshot, cfg config.Config) {
 		t.desktopApp.SetSystemTrayIcon(theme.ErrorIcon())
 		return
 	}
+	if snap.Staleness == state.Stale {
+		t.desktopApp.SetSystemTrayIcon(theme.WarningIcon())
+		return
+	}
 	t.desktopApp.SetSystemTrayIcon(IconResource())
 }
@@ -2,11 +2,14 @@ package tray
 
 import (
 	"testing"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/theme"
 
 	"openrouter-costs-tray/internal/config"
+	"openrouter-costs-tray/internal/openrouter"
+	"openrouter-costs-tray/internal/pricing"
 	"openrouter-costs-tray/internal/state"
 )
 
@@ -32,10 +35,10 @@ func TestBuildMenu(t *testing.T) {
 	if tr.menu.Label != "OpenRouter Costs" {
 		t.Fatalf("unexpected menu label: %s", tr.menu.Label)
 	}
-	if len(tr.menu.Items) != 4 {
-		t.Fatalf("expected 4 menu items, got %d", len(tr.menu.Items))
+	if len(tr.menu.Items) != 8 {
+		t.Fatalf("expected 8 menu items, got %d", len(tr.menu.Items))
 	}
-	labels := []string{"Refresh", "Open in web", "Settings", "Exit"}
+	labels := []string{"Refresh", "Open in web", "Look up generation...", "History...", "Export...", "View logs...", "Settings", "Exit"}
 	for i, label := range labels {
 		if tr.menu.Items[i].Label != label {
 			t.Fatalf("expected item %d label %q, got %q", i, label, tr.menu.Items[i].Label)
@@ -60,8 +63,109 @@ func TestSetIcon(t *testing.T) {
 		t.Fatalf("expected error icon")
 	}
 
+	tr.setIcon(state.Snapshot{Staleness: state.Stale}, cfg)
+	if stub.lastIcon == nil || stub.lastIcon.Name() != theme.WarningIcon().Name() {
+		t.Fatalf("expected warning icon for stale data")
+	}
+
+	tr.setIcon(state.Snapshot{Offline: true}, cfg)
+	if stub.lastIcon == nil || stub.lastIcon.Name() != IconResource().Name() {
+		t.Fatalf("expected app icon while offline")
+	}
+
+	tr.setIcon(state.Snapshot{Staleness: state.Late}, cfg)
+	if stub.lastIcon == nil || stub.lastIcon.Name() != IconResource().Name() {
+		t.Fatalf("expected app icon for late data")
+	}
+
 	tr.setIcon(state.Snapshot{}, cfg)
 	if stub.lastIcon == nil || stub.lastIcon.Name() != IconResource().Name() {
 		t.Fatalf("expected tray icon for success")
 	}
 }
+
+func TestUpdateModelMenu(t *testing.T) {
+	tr := &Tray{}
+	tr.buildMenu()
+	if items := tr.weekItem.ChildMenu.Items; len(items) != 1 || !items[0].Disabled {
+		t.Fatalf("expected placeholder item before data")
+	}
+
+	now := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	tr.updateModelMenu(state.Snapshot{Activity: []openrouter.ActivityItem{
+		{Date: "2025-02-09", Model: "a", Usage: 0.5},
+		{Date: "2025-02-08", Model: "b", Usage: 2},
+	}}, now)
+
+	week := tr.weekItem.ChildMenu.Items
+	if len(week) != 2 || week[0].Label != "b: $2.000" || week[1].Label != "a: $0.5000" {
+		t.Fatalf("unexpected week items: %+v", week)
+	}
+}
+
+func TestUpdateKeysMenu(t *testing.T) {
+	tr := &Tray{}
+	tr.buildMenu()
+	cfg := config.DefaultConfig()
+	if sections := tr.sections(state.Snapshot{}, cfg); len(sections) != 0 {
+		t.Fatalf("expected keys menu hidden without keys, got %d sections", len(sections))
+	}
+
+	monthly := 5.0
+	snap := state.Snapshot{Keys: []openrouter.KeyInfo{
+		{Name: "ci", Usage: openrouter.Usage{Total: 2}},
+		{Name: "dev", Disabled: true, Usage: openrouter.Usage{Total: 1, Monthly: &monthly}},
+	}}
+	if sections := tr.sections(snap, cfg); len(sections) != 1 || sections[0] != tr.keysItem {
+		t.Fatalf("expected keys menu shown with keys")
+	}
+	tr.updateKeysMenu(snap)
+	items := tr.keysItem.ChildMenu.Items
+	if len(items) != 4 {
+		t.Fatalf("expected two keys, separator and total, got %d", len(items))
+	}
+	if items[0].Label != "ci: $2.000" || items[1].Label != "dev: $1.000 (disabled)" || items[3].Label != "Account total: $3.000" {
+		t.Fatalf("unexpected keys menu: %q, %q", items[1].Label, items[3].Label)
+	}
+}
+
+func TestComposeItems(t *testing.T) {
+	tr := &Tray{}
+	tr.buildMenu()
+	if items := tr.composeItems(); len(items) != 8 {
+		t.Fatalf("expected optional sections hidden, got %d items", len(items))
+	}
+	items := tr.composeItems(tr.weekItem, tr.keysItem, tr.pricesItem)
+	if len(items) != 11 || items[2].Label != "Top models (last 7 days)" || items[3].Label != "Top keys (total spend)" || items[4].Label != "Watched model prices" {
+		t.Fatalf("expected optional sections after the head items")
+	}
+}
+
+func TestUpdatePricesMenu(t *testing.T) {
+	tr := &Tray{}
+	tr.buildMenu()
+	cfg := config.DefaultConfig()
+	cfg.Models.Watch = []string{"a", "b"}
+	tr.updatePricesMenu(state.Snapshot{Prices: map[string]pricing.Price{
+		"a":      {Prompt: 1e-6, Completion: 2e-6},
+		"a:free": {},
+	}}, cfg)
+
+	items := tr.pricesItem.ChildMenu.Items
+	if len(items) != 3 {
+		t.Fatalf("expected three price items, got %d", len(items))
+	}
+	if items[0].Label != "a: in $1.000/M, out $2.000/M" || items[1].Label != "a:free: in $0.0000/M, out $0.0000/M" || items[2].Label != "b: unavailable" {
+		t.Fatalf("unexpected price items: %q, %q, %q", items[0].Label, items[1].Label, items[2].Label)
+	}
+}
+
+func TestUpdateProjectsMenu(t *testing.T) {
+	tr := &Tray{}
+	tr.buildMenu()
+	tr.updateProjectsMenu(state.Snapshot{Projects: map[string]float64{"api": 0.5, "agent": 2}})
+	items := tr.projItem.ChildMenu.Items
+	if len(items) != 2 || items[0].Label != "agent: $2.000" || items[1].Label != "api: $0.5000" {
+		t.Fatalf("unexpected project items: %+v", items)
+	}
+}
@@ -28,6 +28,20 @@ func FormatTime(t time.Time) string {
 	return t.Local().Format("2006-01-02 15:04")
 }
 
+// FormatAge renders how long ago something happened, e.g. "3h ago".
+func FormatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(age/(24*time.Hour)))
+	}
+}
+
 func TokenHash(token string) string {
 	if token == "" {
 		return ""
@@ -49,3 +49,22 @@ func TestTokenHash(t *testing.T) {
 		t.Fatalf("expected %q, got %q", want, got)
 	}
 }
+
+func TestFormatAge(t *testing.T) {
+	cases := []struct {
+		age  time.Duration
+		want string
+	}{
+		{-time.Hour, "just now"},
+		{30 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3*time.Hour + 59*time.Minute, "3h ago"},
+		{47 * time.Hour, "47h ago"},
+		{72 * time.Hour, "3d ago"},
+	}
+	for _, tc := range cases {
+		if got := FormatAge(tc.age); got != tc.want {
+			t.Fatalf("FormatAge(%v): expected %q, got %q", tc.age, tc.want, got)
+		}
+	}
+}